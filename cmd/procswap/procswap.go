@@ -41,8 +41,15 @@ func main() {
 	// Create a new app. This is a urfave/cli app making it easier to setup.
 	app := procswap.NewApp()
 	app.Version = version
+	// Print the banner before running anything, unless scanning since its
+	// output might be parsed.
+	app.Before = func(c *cli.Context) error {
+		if c.Args().First() != "scan" {
+			printBanner()
+		}
 
-	printBanner()
+		return nil
+	}
 
 	err := app.Run(os.Args)
 	if err != nil {
@@ -54,6 +61,8 @@ func versionPrinter(c *cli.Context) {
 	log.Printf("version=%s revision=%s", c.App.Version, revision)
 }
 
+// printBanner prints the banner followed by an empty line. Println would do
+// the same, but vet flags it as the banner already ends with a newline.
 func printBanner() {
-	log.Println(banner)
+	log.Print(banner + "\n")
 }
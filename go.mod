@@ -3,14 +3,18 @@ module github.com/billiford/procswap
 go 1.15
 
 require (
+	github.com/BurntSushi/toml v0.4.1
 	github.com/billiford/go-ps v1.0.3
 	github.com/eiannone/keyboard v0.0.0-20200508000154-caf4b762e807
+	github.com/fsnotify/fsnotify v1.4.7
 	github.com/google/uuid v1.2.0
-	github.com/karrick/godirwalk v1.16.1 // indirect
-	github.com/logrusorgru/aurora v2.0.3+incompatible // indirect
+	github.com/karrick/godirwalk v1.16.1
+	github.com/logrusorgru/aurora v2.0.3+incompatible
 	github.com/mattn/go-colorable v0.1.8
 	github.com/onsi/ginkgo v1.12.1
 	github.com/onsi/gomega v1.10.3
 	github.com/shiena/ansicolor v0.0.0-20200904210342-c7312218db18
 	github.com/urfave/cli/v2 v2.3.0
+	gopkg.in/natefinch/lumberjack.v2 v2.2.1
+	gopkg.in/yaml.v2 v2.3.0
 )
@@ -0,0 +1,242 @@
+package procswap
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/logrusorgru/aurora"
+)
+
+// swapRecord is a running swap as kept in the state file.
+type swapRecord struct {
+	// Group is the name of the group the swap is part of.
+	Group string `json:"group"`
+	Name  string `json:"name"`
+	Path  string `json:"path"`
+	PID   int    `json:"pid"`
+	// StartTime is when the process started, so a process that was given
+	// the swap's PID after it exited isn't mistaken for the swap.
+	StartTime uint64 `json:"start_time"`
+	// Paused is true if the swap was paused.
+	Paused bool `json:"paused,omitempty"`
+}
+
+// savedState is the content of the state file.
+type savedState struct {
+	Swaps []swapRecord `json:"swaps"`
+}
+
+// Adopt makes the swap track a running process it started before procswap
+// was restarted, instead of starting a new one. The output of an adopted
+// process is read from its spool files, if it has them, and its exit code is
+// unknown.
+func (s *swap) Adopt(pid int) error {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(s.path)
+	cmd.Process = p
+
+	s.cmd = cmd
+	s.paused = false
+	s.throttled = nil
+	s.processState = nil
+	s.setState(SwapStateRunning)
+	// An adopted swap was ready long ago.
+	ready := make(chan struct{})
+	close(ready)
+
+	s.outputMu.Lock()
+	s.ready = ready
+	s.markReady = func() {}
+	s.outputMu.Unlock()
+
+	s.logEvent(fmt.Sprintf("adopted %s (pid %d)", s.path, pid))
+
+	exited := make(chan struct{})
+	s.exited = exited
+
+	go func() {
+		waitForProcess(p)
+		s.logEvent(fmt.Sprintf("pid %d exited", pid))
+		s.exit(false)
+		close(exited)
+	}()
+
+	if s.spool != "" {
+		s.followSpools(exited, true)
+	}
+
+	return nil
+}
+
+// WithStateFile sets the file the loop keeps its running swaps in, so they
+// are adopted instead of started again when procswap restarts.
+func (l *loop) WithStateFile(path string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.stateFile = path
+}
+
+// adoptSwaps adopts the swaps in the state file whose processes are still
+// running, then starts the other swaps of the groups they are part of. It
+// runs before the first poll, which stops them all again if priorities are
+// running.
+func (l *loop) adoptSwaps() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.stateFile == "" || l.dryRun {
+		return
+	}
+
+	b, err := ioutil.ReadFile(l.stateFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logWarn(fmt.Sprintf("error reading state file %s: %s", l.stateFile, err.Error()))
+		}
+
+		return
+	}
+
+	var state savedState
+	if err := json.Unmarshal(b, &state); err != nil {
+		logWarn(fmt.Sprintf("error reading state file %s: %s", l.stateFile, err.Error()))
+
+		return
+	}
+
+	for _, r := range state.Swaps {
+		if !l.adoptSwap(r) {
+			logWarn(fmt.Sprintf("%s %s (pid %d) is no longer a swap, leaving it alone", aurora.Cyan("adopt"), aurora.Bold(r.Path), r.PID))
+		}
+	}
+
+	for _, g := range l.groups {
+		if len(g.runningSwaps) > 0 {
+			g.start()
+			g.launch(g.notRunning())
+		}
+	}
+}
+
+// adoptSwap finds the swap a record of the state file is for and adopts its
+// process. It returns false if no group has the swap anymore.
+func (l *loop) adoptSwap(r swapRecord) bool {
+	for _, g := range l.groups {
+		if g.Name != r.Group {
+			continue
+		}
+
+		for _, s := range g.Swaps {
+			if s.Name() == r.Name && s.Path() == r.Path && !containsSwap(g.runningSwaps, s) {
+				g.adoptSwap(s, r)
+
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// saveState writes the running and suspended swaps of every group to the
+// state file, if they changed since it was last written.
+func (l *loop) saveState() {
+	if l.stateFile == "" || l.dryRun {
+		return
+	}
+
+	state := savedState{Swaps: []swapRecord{}}
+
+	for _, g := range l.groups {
+		for _, s := range append(append([]Swap{}, g.runningSwaps...), g.suspendedSwaps...) {
+			if s.State().done() {
+				continue
+			}
+
+			startTime, err := processStartTime(s.PID())
+			if err != nil {
+				continue
+			}
+
+			state.Swaps = append(state.Swaps, swapRecord{
+				Group:     g.Name,
+				Name:      s.Name(),
+				Path:      s.Path(),
+				PID:       s.PID(),
+				StartTime: startTime,
+				Paused:    s.Paused(),
+			})
+		}
+	}
+
+	b, err := json.MarshalIndent(state, "", "  ")
+	if err != nil || bytes.Equal(b, l.savedState) {
+		return
+	}
+
+	if err := writeStateFile(l.stateFile, b); err != nil {
+		logWarn(fmt.Sprintf("error writing state file %s: %s", l.stateFile, err.Error()))
+
+		return
+	}
+
+	l.savedState = b
+}
+
+// writeStateFile replaces the state file in one go, so a crash while it is
+// written doesn't leave half of it behind.
+func writeStateFile(path string, b []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// adoptSwap adopts the process of a swap started before procswap restarted,
+// as long as it is still the same process.
+func (g *group) adoptSwap(s Swap, r swapRecord) {
+	startTime, err := processStartTime(r.PID)
+	if err != nil || startTime != r.StartTime {
+		logInfo(fmt.Sprintf("%s %s%s (pid %d) is no longer running", aurora.Cyan("adopt"), g.prefix(), aurora.Bold(s.Path()), r.PID))
+
+		return
+	}
+
+	logInfo(fmt.Sprintf("%s %s%s (pid %d)...", aurora.Cyan("adopt"), g.prefix(), aurora.Bold(s.Path()), r.PID), false)
+
+	if err := s.Adopt(r.PID); err != nil {
+		logFailed()
+		logError(fmt.Sprintf("error adopting swap process %s: %s", s.Path(), err.Error()))
+
+		return
+	}
+	// A swap paused when procswap went away would otherwise stay frozen.
+	if r.Paused {
+		if err := s.Resume(); err != nil {
+			logFailed()
+			logError(fmt.Sprintf("error resuming swap process %s: %s", s.Path(), err.Error()))
+			g.track(s)
+
+			return
+		}
+	}
+
+	logOK()
+	g.track(s)
+}
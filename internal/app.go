@@ -1,6 +1,7 @@
 package procswap
 
 import (
+	"errors"
 	"fmt"
 	"path/filepath"
 	"strconv"
@@ -12,32 +13,110 @@ import (
 )
 
 const (
-	appName                   = "procswap"
-	appUsage                  = "run processes when any prioritized process is not running"
-	appUsageText              = "procswap.exe -p <PATH_TO_DIR_FOR_PRIORITIES> -s <PATH_TO_EXECUTABLE>"
-	authorName                = "billiford"
-	flagDiableActionsName     = "disable-actions"
-	flagDiableActionsUsage    = "disable actions (keyboard inputs)"
-	flagIgnoreAliases         = "i"
-	flagIgnoreName            = "ignore"
-	flagIgnoreUsage           = "ignore a priority (case insensitive)"
-	flagLimitAliases          = "l"
-	flagLimitName             = "limit"
-	flagLimitUsage            = "a limit to a number of times the loop runs (0 = infinite)"
-	flagLimitValue            = 0
-	flagPollIntervalAliases   = "pi"
-	flagPollIntervalName      = "poll-interval"
-	flagPollIntervalUsage     = "time in seconds to wait to poll for running processes"
-	flagPollIntervalValue     = 10
-	flagPriorityAliases       = "p"
-	flagPriorityName          = "priority"
-	flagPriorityUsage         = "a path to a file or directory to scan for executables"
-	flagPriorityScriptAliases = "ps"
-	flagPriorityScriptName    = "priority-script"
-	flagPriorityScriptUsage   = "a path to a script that will run once when any priority starts"
-	flagSwapAliases           = "s"
-	flagSwapName              = "swap"
-	flagSwapUsage             = "a process that will run when any priority executable is not running"
+	appName                     = "procswap"
+	appUsage                    = "run processes when any prioritized process is not running"
+	appUsageText                = "procswap.exe -p <PATH_TO_DIR_FOR_PRIORITIES> -s <PATH_TO_EXECUTABLE> | procswap.exe -c <PATH_TO_CONFIG_FILE>"
+	authorName                  = "billiford"
+	commandScanName             = "scan"
+	commandScanUsage            = "list the executables found in the priority paths"
+	commandScanUsageText        = "procswap.exe scan -p <PATH_TO_DIR_FOR_PRIORITIES> [-o table|json|csv]"
+	commandValidateName         = "validate"
+	commandValidateUsage        = "check the config for problems without running any processes"
+	commandValidateUsageText    = "procswap.exe validate -c <PATH_TO_CONFIG_FILE>"
+	flagCgroupSliceAliases      = "cg"
+	flagCgroupSliceName         = "cgroup-slice"
+	flagCgroupSliceUsage        = "a cgroup v2, relative to the root of the hierarchy, each swap gets a cgroup of its own in (Linux only; before Linux 5.7 a swap joins it just after starting)"
+	flagConfigAliases           = "c"
+	flagConfigName              = "config"
+	flagConfigUsage             = "a path to a YAML or TOML config file; flags override its values"
+	flagDiableActionsName       = "disable-actions"
+	flagDiscoveryAliases        = "d"
+	flagDiscoveryName           = "discovery"
+	flagDiscoveryUsage          = "how to find executables in the priority paths, one of exe, glob, regex, executable or header"
+	flagDiscoveryPatternAliases = "dp"
+	flagDiscoveryPatternName    = "discovery-pattern"
+	flagDiscoveryPatternUsage   = "a glob pattern or regular expression file names must match for the glob and regex discovery modes"
+	flagDiableActionsUsage      = "disable actions (keyboard inputs)"
+	flagDryRunName              = "dry-run"
+	flagDryRunUsage             = "log the swaps that would be started or stopped without doing it"
+	flagIgnoreAliases           = "i"
+	flagIgnoreName              = "ignore"
+	flagIgnoreUsage             = "ignore a priority by name or full path (case insensitive)"
+	flagLeaveSwapsRunningName   = "leave-swaps-running"
+	flagLeaveSwapsRunningUsage  = "leave the swaps running when procswap is stopped with Ctrl+C or SIGTERM"
+	flagLimitAliases            = "l"
+	flagLimitName               = "limit"
+	flagLimitUsage              = "a limit to a number of times the loop runs (0 = infinite)"
+	flagLimitValue              = 0
+	flagMatchAliases            = "m"
+	flagMatchName               = "match"
+	flagMatchUsage              = "how running processes are matched against priority executables, one of name, path or cmdline"
+	flagOutputAliases           = "o"
+	flagOutputName              = "output"
+	flagOutputUsage             = "output format, one of table, json or csv"
+	flagOutputValue             = outputTable
+	flagOutputHistoryAliases    = "oh"
+	flagOutputHistoryName       = "output-history"
+	flagOutputHistoryUsage      = "the number of lines of output kept for each swap, shown before its live output"
+	flagOutputHistoryValue      = defaultHistorySize
+	flagPollIntervalAliases     = "pi"
+	flagPollIntervalName        = "poll-interval"
+	flagPollIntervalUsage       = "time in seconds to wait to poll for running processes"
+	flagPollIntervalValue       = 10
+	flagPriorityAliases         = "p"
+	flagPriorityName            = "priority"
+	flagPriorityUsage           = "a path to a file or directory to scan for executables"
+	flagPriorityGlobAliases     = "pg"
+	flagPriorityGlobName        = "priority-glob"
+	flagPriorityGlobUsage       = "a glob pattern matched against the names of running processes (case insensitive)"
+	flagPriorityRegexAliases    = "pr"
+	flagPriorityRegexName       = "priority-regex"
+	flagPriorityRegexUsage      = "a regular expression matched against the names of running processes (case insensitive)"
+	flagPriorityScriptAliases   = "ps"
+	flagPriorityScriptName      = "priority-script"
+	flagPriorityScriptUsage     = "a path to a script that will run once when any priority starts"
+	flagStateFileAliases        = "sf"
+	flagStateFileName           = "state-file"
+	flagStateFileUsage          = "a file the running swaps are kept in, so they are adopted instead of started again when procswap restarts"
+	flagSwapAliases             = "s"
+	flagSwapName                = "swap"
+	flagSwapUsage               = "a process that will run when any priority executable is not running"
+	flagSwapAfterAliases        = "saf"
+	flagSwapAfterName           = "swap-after"
+	flagSwapAfterUsage          = "a swap that has to be ready before another starts as <SWAP>=<AFTER>"
+	flagSwapArgAliases          = "sa"
+	flagSwapArgName             = "swap-arg"
+	flagSwapArgUsage            = "an argument for a swap as <SWAP>=<ARG>, where <SWAP> is the swap's name, path or file name"
+	flagSwapDelayAliases        = "sdl"
+	flagSwapDelayName           = "swap-delay"
+	flagSwapDelayUsage          = "time in seconds to wait before starting a swap as <SWAP>=<SECONDS>"
+	flagSwapDirAliases          = "sd"
+	flagSwapDirName             = "swap-dir"
+	flagSwapDirUsage            = "the working directory for a swap as <SWAP>=<DIR> (default: the swap's directory)"
+	flagSwapEnvAliases          = "se"
+	flagSwapEnvName             = "swap-env"
+	flagSwapEnvUsage            = "an environment variable for a swap as <SWAP>=<KEY>=<VALUE>"
+	flagSwapHealthAliases       = "sh"
+	flagSwapHealthName          = "swap-health"
+	flagSwapHealthUsage         = "a health probe for a swap as <SWAP>=<PROBE>, where <PROBE> is an http:// or https:// URL, tcp://<HOST>:<PORT> or a command"
+	flagSwapLogDirAliases       = "sld"
+	flagSwapLogDirName          = "swap-log-dir"
+	flagSwapLogDirUsage         = "a directory each swap's output is logged to, in a file named after the swap"
+	flagSwapModeAliases         = "sm"
+	flagSwapModeName            = "swap-mode"
+	flagSwapModeUsage           = "what happens to a swap when a priority starts as <SWAP>=<MODE>, where <MODE> is stop, pause or throttle"
+	flagSwapRestartAliases      = "sr"
+	flagSwapRestartName         = "swap-restart"
+	flagSwapRestartUsage        = "a restart policy for a swap as <SWAP>=<POLICY>[:<MAX_RETRIES>], where <POLICY> is never, on-failure or always"
+	flagSwapStopAliases         = "ss"
+	flagSwapStopName            = "swap-stop"
+	flagSwapStopUsage           = "a signal sent to stop a swap as <SWAP>=<SIGNAL>[:<SECONDS>], sent in order until the swap exits"
+	flagSwapStopCommandAliases  = "ssc"
+	flagSwapStopCommandName     = "swap-stop-command"
+	flagSwapStopCommandUsage    = "a command run to stop a swap as <SWAP>=<COMMAND>"
+	flagSwapUserAliases         = "su"
+	flagSwapUserName            = "swap-user"
+	flagSwapUserUsage           = "a user to run a swap as, given as <SWAP>=<USER>[:<GROUP>]; needs procswap to run as root (Linux and macOS only)"
 )
 
 // NewApp returns a urfave/cli app that runs the loops to
@@ -46,6 +125,7 @@ func NewApp() *cli.App {
 	app := cli.NewApp()
 	app.Action = run
 	app.Authors = authors()
+	app.Commands = commands()
 	app.Flags = flags()
 	app.Name = appName
 	app.Usage = appUsage
@@ -62,22 +142,150 @@ func authors() []*cli.Author {
 	}
 }
 
+func commands() []*cli.Command {
+	return []*cli.Command{
+		{
+			Action:    scan,
+			Flags:     scanFlags(),
+			Name:      commandScanName,
+			Usage:     commandScanUsage,
+			UsageText: commandScanUsageText,
+		},
+		{
+			Action:    validate,
+			Flags:     configFlags(),
+			Name:      commandValidateName,
+			Usage:     commandValidateUsage,
+			UsageText: commandValidateUsageText,
+		},
+	}
+}
+
 func flags() []cli.Flag {
-	return []cli.Flag{
+	return append(configFlags(),
 		&cli.BoolFlag{
 			Name:  flagDiableActionsName,
 			Usage: flagDiableActionsUsage,
 		},
+		&cli.BoolFlag{
+			Name:  flagDryRunName,
+			Usage: flagDryRunUsage,
+		},
+		&cli.BoolFlag{
+			Name:  flagLeaveSwapsRunningName,
+			Usage: flagLeaveSwapsRunningUsage,
+		},
+		&cli.IntFlag{
+			Aliases: strings.Split(flagLimitAliases, ","),
+			Name:    flagLimitName,
+			Usage:   flagLimitUsage,
+			Value:   flagLimitValue,
+		},
+		&cli.IntFlag{
+			Aliases: strings.Split(flagOutputHistoryAliases, ","),
+			Name:    flagOutputHistoryName,
+			Usage:   flagOutputHistoryUsage,
+			Value:   flagOutputHistoryValue,
+		},
+		&cli.IntFlag{
+			Aliases: strings.Split(flagPollIntervalAliases, ","),
+			Name:    flagPollIntervalName,
+			Usage:   flagPollIntervalUsage,
+			Value:   flagPollIntervalValue,
+		},
+		&cli.StringFlag{
+			Aliases: strings.Split(flagStateFileAliases, ","),
+			Name:    flagStateFileName,
+			Usage:   flagStateFileUsage,
+		},
+	)
+}
+
+// scanFlags returns the flags of the scan command.
+func scanFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Aliases: strings.Split(flagConfigAliases, ","),
+			Name:    flagConfigName,
+			Usage:   flagConfigUsage,
+		},
+		&cli.StringFlag{
+			Aliases: strings.Split(flagDiscoveryAliases, ","),
+			Name:    flagDiscoveryName,
+			Usage:   flagDiscoveryUsage,
+		},
+		&cli.StringSliceFlag{
+			Aliases: strings.Split(flagDiscoveryPatternAliases, ","),
+			Name:    flagDiscoveryPatternName,
+			Usage:   flagDiscoveryPatternUsage,
+		},
 		&cli.StringSliceFlag{
 			Aliases: strings.Split(flagIgnoreAliases, ","),
 			Name:    flagIgnoreName,
 			Usage:   flagIgnoreUsage,
 		},
+		&cli.StringFlag{
+			Aliases: strings.Split(flagOutputAliases, ","),
+			Name:    flagOutputName,
+			Usage:   flagOutputUsage,
+			Value:   flagOutputValue,
+		},
 		&cli.StringSliceFlag{
-			Aliases:  strings.Split(flagPriorityAliases, ","),
-			Name:     flagPriorityName,
-			Usage:    flagPriorityUsage,
-			Required: true,
+			Aliases: strings.Split(flagPriorityAliases, ","),
+			Name:    flagPriorityName,
+			Usage:   flagPriorityUsage,
+		},
+	}
+}
+
+// configFlags returns the flags that make up the config of a loop, these are
+// shared by every command that reads the config.
+func configFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Aliases: strings.Split(flagCgroupSliceAliases, ","),
+			Name:    flagCgroupSliceName,
+			Usage:   flagCgroupSliceUsage,
+		},
+		&cli.StringFlag{
+			Aliases: strings.Split(flagConfigAliases, ","),
+			Name:    flagConfigName,
+			Usage:   flagConfigUsage,
+		},
+		&cli.StringFlag{
+			Aliases: strings.Split(flagDiscoveryAliases, ","),
+			Name:    flagDiscoveryName,
+			Usage:   flagDiscoveryUsage,
+		},
+		&cli.StringSliceFlag{
+			Aliases: strings.Split(flagDiscoveryPatternAliases, ","),
+			Name:    flagDiscoveryPatternName,
+			Usage:   flagDiscoveryPatternUsage,
+		},
+		&cli.StringSliceFlag{
+			Aliases: strings.Split(flagIgnoreAliases, ","),
+			Name:    flagIgnoreName,
+			Usage:   flagIgnoreUsage,
+		},
+		&cli.StringFlag{
+			Aliases: strings.Split(flagMatchAliases, ","),
+			Name:    flagMatchName,
+			Usage:   flagMatchUsage,
+		},
+		&cli.StringSliceFlag{
+			Aliases: strings.Split(flagPriorityAliases, ","),
+			Name:    flagPriorityName,
+			Usage:   flagPriorityUsage,
+		},
+		&cli.StringSliceFlag{
+			Aliases: strings.Split(flagPriorityGlobAliases, ","),
+			Name:    flagPriorityGlobName,
+			Usage:   flagPriorityGlobUsage,
+		},
+		&cli.StringSliceFlag{
+			Aliases: strings.Split(flagPriorityRegexAliases, ","),
+			Name:    flagPriorityRegexName,
+			Usage:   flagPriorityRegexUsage,
 		},
 		&cli.StringFlag{
 			Aliases: strings.Split(flagPriorityScriptAliases, ","),
@@ -85,105 +293,484 @@ func flags() []cli.Flag {
 			Usage:   flagPriorityScriptUsage,
 		},
 		&cli.StringSliceFlag{
-			Aliases:  strings.Split(flagSwapAliases, ","),
-			Name:     flagSwapName,
-			Usage:    flagSwapUsage,
-			Required: true,
+			Aliases: strings.Split(flagSwapAliases, ","),
+			Name:    flagSwapName,
+			Usage:   flagSwapUsage,
 		},
-		&cli.IntFlag{
-			Aliases: strings.Split(flagLimitAliases, ","),
-			Name:    flagLimitName,
-			Usage:   flagLimitUsage,
-			Value:   flagLimitValue,
+		&cli.StringSliceFlag{
+			Aliases: strings.Split(flagSwapAfterAliases, ","),
+			Name:    flagSwapAfterName,
+			Usage:   flagSwapAfterUsage,
 		},
-		&cli.IntFlag{
-			Aliases: strings.Split(flagPollIntervalAliases, ","),
-			Name:    flagPollIntervalName,
-			Usage:   flagPollIntervalUsage,
-			Value:   flagPollIntervalValue,
+		&cli.StringSliceFlag{
+			Aliases: strings.Split(flagSwapArgAliases, ","),
+			Name:    flagSwapArgName,
+			Usage:   flagSwapArgUsage,
+		},
+		&cli.StringSliceFlag{
+			Aliases: strings.Split(flagSwapDelayAliases, ","),
+			Name:    flagSwapDelayName,
+			Usage:   flagSwapDelayUsage,
+		},
+		&cli.StringSliceFlag{
+			Aliases: strings.Split(flagSwapDirAliases, ","),
+			Name:    flagSwapDirName,
+			Usage:   flagSwapDirUsage,
+		},
+		&cli.StringSliceFlag{
+			Aliases: strings.Split(flagSwapEnvAliases, ","),
+			Name:    flagSwapEnvName,
+			Usage:   flagSwapEnvUsage,
+		},
+		&cli.StringSliceFlag{
+			Aliases: strings.Split(flagSwapHealthAliases, ","),
+			Name:    flagSwapHealthName,
+			Usage:   flagSwapHealthUsage,
+		},
+		&cli.StringFlag{
+			Aliases: strings.Split(flagSwapLogDirAliases, ","),
+			Name:    flagSwapLogDirName,
+			Usage:   flagSwapLogDirUsage,
+		},
+		&cli.StringSliceFlag{
+			Aliases: strings.Split(flagSwapModeAliases, ","),
+			Name:    flagSwapModeName,
+			Usage:   flagSwapModeUsage,
+		},
+		&cli.StringSliceFlag{
+			Aliases: strings.Split(flagSwapRestartAliases, ","),
+			Name:    flagSwapRestartName,
+			Usage:   flagSwapRestartUsage,
+		},
+		&cli.StringSliceFlag{
+			Aliases: strings.Split(flagSwapStopAliases, ","),
+			Name:    flagSwapStopName,
+			Usage:   flagSwapStopUsage,
+		},
+		&cli.StringSliceFlag{
+			Aliases: strings.Split(flagSwapStopCommandAliases, ","),
+			Name:    flagSwapStopCommandName,
+			Usage:   flagSwapStopCommandUsage,
+		},
+		&cli.StringSliceFlag{
+			Aliases: strings.Split(flagSwapUserAliases, ","),
+			Name:    flagSwapUserName,
+			Usage:   flagSwapUserUsage,
 		},
 	}
 }
 
 func run(c *cli.Context) error {
+	cfg, err := configFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	if path := c.String(flagConfigName); path != "" {
+		logInfo(fmt.Sprintf("%s loaded config file %s", aurora.Cyan("setup"), aurora.Bold(path)))
+	}
+
 	loop := NewLoop()
-	// Setup priority executables.
-	pe := listExecutables(c.StringSlice(flagPriorityName), c.StringSlice(flagIgnoreName))
-	if len(pe) == 0 {
-		logWarn(fmt.Sprintf("%s found no priority executables - swap processes will run indefinitely", aurora.Cyan("setup")))
-	} else {
-		execs := strconv.Itoa(len(pe))
-		logInfo(fmt.Sprintf("%s found %s priority executables", aurora.Cyan("setup"), aurora.Bold(execs)))
-	}
-
-	loop.WithPriorities(pe)
-
-	// Setup swap scripts.
-	// -s is a required flag, so there's no need to check if no swap processes
-	// were passed in.
-	sp := c.StringSlice(flagSwapName)
-	s := swaps(pe, sp)
-	loop.WithSwaps(s)
-
-	swapCount := strconv.Itoa(len(sp))
-	logInfo(fmt.Sprintf("%s registered %s swap processes", aurora.Cyan("setup"), aurora.Bold(swapCount)))
-	// Setup priority script.
-	// This will run once any priority starts. We wait for completion of the script.
-	ps := c.String(flagPriorityScriptName)
-	if ps != "" {
-		loop.WithPriorityScript(ps)
-		logInfo(fmt.Sprintf("%s registered priority script %s", aurora.Cyan("setup"), aurora.Bold(ps)))
+	// Set dry run before adding any groups so they all inherit it.
+	if cfg.DryRun {
+		loop.WithDryRun(true)
+		logWarn(fmt.Sprintf("%s dry run, no swaps will be started or stopped", aurora.Cyan("setup")))
+	}
+
+	reloader := newReloader(c, loop)
+	// Setup the groups of priority executables and swap scripts.
+	// Make sure there's no intersection here, that would be a nightmare.
+	groups, err := reloader.groupsFor(cfg, "setup")
+	if err != nil {
+		logFatal(err.Error() + "; exiting")
 	}
+
+	loop.WithGroups(groups)
 	// Set limit for loop to run.
-	if limit := c.Int(flagLimitName); limit > 0 {
-		loop.WithLimit(limit)
+	if cfg.Limit > 0 {
+		loop.WithLimit(cfg.Limit)
 	}
 	// Set the poll interval.
-	pollInterval := c.Int(flagPollIntervalName)
-	if pollInterval > 0 {
-		loop.WithPollInterval(pollInterval)
+	if cfg.PollInterval > 0 {
+		loop.WithPollInterval(cfg.PollInterval)
 	}
+	// Keep the running swaps in the state file, adopting any a previous
+	// procswap left running.
+	if cfg.StateFile != "" {
+		loop.WithStateFile(cfg.StateFile)
+	}
+	loop.WithLeaveSwapsRunning(cfg.LeaveSwapsRunning)
 	// By default, enable all actions (keyboard inputs).
-	if !c.Bool(flagDiableActionsName) {
+	if !cfg.DisableActions {
 		loop.WithActionsEnabled(true)
 	}
+	// Reload the loop on SIGHUP or when the config file changes.
+	reloader.start()
+	defer reloader.stop()
 	// This will run indefinitely unless limit is set to more than 0, or until the user exits.
-	loop.Run()
+	if code := loop.Run(); code != 0 {
+		return cli.Exit("", code)
+	}
 
 	return nil
 }
 
-func listExecutables(paths, ignored []string) []*godirwalk.Dirent {
-	// These are our "priority executables".
-	pe := []*godirwalk.Dirent{}
+// configFromContext reads the config and makes sure it is valid.
+func configFromContext(c *cli.Context) (*config, error) {
+	cfg, err := readConfig(c)
+	if err != nil {
+		return nil, err
+	}
 
-	// Priority and swap process setup.
-	for _, pd := range paths {
-		e, err := ProcessList(pd, ignored)
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// readConfig loads the config file if one was passed in, then overrides
+// its values with any flags that were explicitly set.
+func readConfig(c *cli.Context) (*config, error) {
+	cfg := &config{
+		PollInterval: flagPollIntervalValue,
+	}
+
+	if path := c.String(flagConfigName); path != "" {
+		var err error
+
+		cfg, err = loadConfig(path)
 		if err != nil {
-			logError(fmt.Sprintf("%s error searching %s for executables: %s", aurora.Cyan("setup"), pd, err.Error()))
+			return nil, err
+		}
+	}
 
-			continue
+	if c.IsSet(flagCgroupSliceName) {
+		cfg.CgroupSlice = c.String(flagCgroupSliceName)
+	}
+
+	if c.IsSet(flagDiableActionsName) {
+		cfg.DisableActions = c.Bool(flagDiableActionsName)
+	}
+
+	if c.IsSet(flagDryRunName) {
+		cfg.DryRun = c.Bool(flagDryRunName)
+	}
+
+	if c.IsSet(flagIgnoreName) {
+		cfg.Ignore = c.StringSlice(flagIgnoreName)
+	}
+
+	if c.IsSet(flagLeaveSwapsRunningName) {
+		cfg.LeaveSwapsRunning = c.Bool(flagLeaveSwapsRunningName)
+	}
+
+	if c.IsSet(flagLimitName) {
+		cfg.Limit = c.Int(flagLimitName)
+	}
+
+	if c.IsSet(flagMatchName) {
+		cfg.Match = c.String(flagMatchName)
+	}
+
+	if c.IsSet(flagOutputHistoryName) {
+		cfg.OutputHistory = c.Int(flagOutputHistoryName)
+	}
+
+	if c.IsSet(flagPollIntervalName) {
+		cfg.PollInterval = c.Int(flagPollIntervalName)
+	}
+
+	if c.IsSet(flagPriorityName) {
+		cfg.Priorities = []priorityConfig{}
+		for _, path := range c.StringSlice(flagPriorityName) {
+			cfg.Priorities = append(cfg.Priorities, priorityConfig{Path: path})
 		}
+	}
+	// Discovery flags apply to every priority of the default group.
+	if c.IsSet(flagDiscoveryName) || c.IsSet(flagDiscoveryPatternName) {
+		for i := range cfg.Priorities {
+			if c.IsSet(flagDiscoveryName) {
+				cfg.Priorities[i].Discovery = c.String(flagDiscoveryName)
+			}
 
-		pe = append(pe, e...)
+			if c.IsSet(flagDiscoveryPatternName) {
+				cfg.Priorities[i].Patterns = c.StringSlice(flagDiscoveryPatternName)
+			}
+		}
 	}
 
-	return pe
+	if c.IsSet(flagPriorityGlobName) {
+		cfg.PriorityGlobs = c.StringSlice(flagPriorityGlobName)
+	}
+
+	if c.IsSet(flagPriorityRegexName) {
+		cfg.PriorityRegexes = c.StringSlice(flagPriorityRegexName)
+	}
+
+	if c.IsSet(flagPriorityScriptName) {
+		cfg.PriorityScript = c.String(flagPriorityScriptName)
+	}
+
+	if c.IsSet(flagStateFileName) {
+		cfg.StateFile = c.String(flagStateFileName)
+	}
+
+	if c.IsSet(flagSwapName) {
+		cfg.Swaps = []swapConfig{}
+		for _, path := range c.StringSlice(flagSwapName) {
+			cfg.Swaps = append(cfg.Swaps, swapConfig{Path: path})
+		}
+	}
+
+	if c.IsSet(flagSwapLogDirName) {
+		cfg.SwapLogs.Dir = c.String(flagSwapLogDirName)
+	}
+
+	if err := applySwapFlags(c, cfg); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
 }
 
-func swaps(pe []*godirwalk.Dirent, sp []string) []Swap {
-	// Make sure there's no intersection here, that would be a nightmare.
-	if err := intersect(pe, sp); err != nil {
-		logFatal(err.Error())
+// applySwapFlags applies the per-swap flags to the swaps they refer to. These
+// flags are passed in as <SWAP>=<VALUE>, where <SWAP> is the name, path or
+// file name of the swap. Arguments, swaps to start after and stop signals
+// passed in for a swap replace any set for it in the config file.
+func applySwapFlags(c *cli.Context, cfg *config) error {
+	argsSet := map[*swapConfig]bool{}
+
+	for _, value := range c.StringSlice(flagSwapArgName) {
+		swaps, arg, err := cfg.swapFlag(flagSwapArgName, value)
+		if err != nil {
+			return err
+		}
+
+		for _, s := range swaps {
+			if !argsSet[s] {
+				s.Args = []string{}
+				argsSet[s] = true
+			}
+
+			s.Args = append(s.Args, arg)
+		}
 	}
 
-	swaps := []Swap{}
-	for _, swap := range sp {
-		swaps = append(swaps, NewSwap(swap))
+	afterSet := map[*swapConfig]bool{}
+
+	for _, value := range c.StringSlice(flagSwapAfterName) {
+		swaps, after, err := cfg.swapFlag(flagSwapAfterName, value)
+		if err != nil {
+			return err
+		}
+
+		for _, s := range swaps {
+			if !afterSet[s] {
+				s.After = []string{}
+				afterSet[s] = true
+			}
+
+			s.After = append(s.After, after)
+		}
 	}
 
-	return swaps
+	for _, value := range c.StringSlice(flagSwapDelayName) {
+		swaps, delay, err := cfg.swapFlag(flagSwapDelayName, value)
+		if err != nil {
+			return err
+		}
+
+		seconds, err := strconv.Atoi(delay)
+		if err != nil {
+			return fmt.Errorf("invalid --%s %s: expected <SECONDS>", flagSwapDelayName, value)
+		}
+
+		for _, s := range swaps {
+			s.Delay = seconds
+		}
+	}
+
+	for _, value := range c.StringSlice(flagSwapDirName) {
+		swaps, dir, err := cfg.swapFlag(flagSwapDirName, value)
+		if err != nil {
+			return err
+		}
+
+		for _, s := range swaps {
+			s.Dir = dir
+		}
+	}
+
+	for _, value := range c.StringSlice(flagSwapEnvName) {
+		swaps, env, err := cfg.swapFlag(flagSwapEnvName, value)
+		if err != nil {
+			return err
+		}
+
+		kv := strings.SplitN(env, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return fmt.Errorf("invalid --%s %s, expected <SWAP>=<KEY>=<VALUE>", flagSwapEnvName, value)
+		}
+
+		for _, s := range swaps {
+			if s.Env == nil {
+				s.Env = map[string]string{}
+			}
+
+			s.Env[kv[0]] = kv[1]
+		}
+	}
+
+	for _, value := range c.StringSlice(flagSwapHealthName) {
+		swaps, probe, err := cfg.swapFlag(flagSwapHealthName, value)
+		if err != nil {
+			return err
+		}
+
+		health := healthConfig{}
+
+		switch {
+		case strings.HasPrefix(probe, "http://"), strings.HasPrefix(probe, "https://"):
+			health.HTTP = probe
+		case strings.HasPrefix(probe, "tcp://"):
+			health.TCP = strings.TrimPrefix(probe, "tcp://")
+		default:
+			health.Command = probe
+		}
+
+		for _, s := range swaps {
+			s.Health = health
+		}
+	}
+
+	for _, value := range c.StringSlice(flagSwapModeName) {
+		swaps, mode, err := cfg.swapFlag(flagSwapModeName, value)
+		if err != nil {
+			return err
+		}
+
+		for _, s := range swaps {
+			s.Mode = mode
+		}
+	}
+
+	for _, value := range c.StringSlice(flagSwapRestartName) {
+		swaps, restart, err := cfg.swapFlag(flagSwapRestartName, value)
+		if err != nil {
+			return err
+		}
+
+		parts := strings.SplitN(restart, ":", 2)
+		maxRetries := 0
+
+		if len(parts) == 2 {
+			maxRetries, err = strconv.Atoi(parts[1])
+			if err != nil {
+				return fmt.Errorf("invalid --%s %s: expected <POLICY>[:<MAX_RETRIES>]", flagSwapRestartName, value)
+			}
+		}
+
+		for _, s := range swaps {
+			s.Restart.Policy = parts[0]
+			s.Restart.MaxRetries = maxRetries
+		}
+	}
+
+	stopSet := map[*swapConfig]bool{}
+
+	for _, value := range c.StringSlice(flagSwapStopName) {
+		swaps, stop, err := cfg.swapFlag(flagSwapStopName, value)
+		if err != nil {
+			return err
+		}
+
+		sig, err := parseStopFlag(stop)
+		if err != nil {
+			return fmt.Errorf("invalid --%s %s: %w", flagSwapStopName, value, err)
+		}
+
+		for _, s := range swaps {
+			if !stopSet[s] {
+				s.Stop.Signals = []stopSignalConfig{}
+				stopSet[s] = true
+			}
+
+			s.Stop.Signals = append(s.Stop.Signals, sig)
+		}
+	}
+
+	for _, value := range c.StringSlice(flagSwapStopCommandName) {
+		swaps, command, err := cfg.swapFlag(flagSwapStopCommandName, value)
+		if err != nil {
+			return err
+		}
+
+		for _, s := range swaps {
+			s.Stop.Command = command
+		}
+	}
+
+	for _, value := range c.StringSlice(flagSwapUserName) {
+		swaps, runAs, err := cfg.swapFlag(flagSwapUserName, value)
+		if err != nil {
+			return err
+		}
+
+		parts := strings.SplitN(runAs, ":", 2)
+		if parts[0] == "" {
+			return fmt.Errorf("invalid --%s %s: expected <USER>[:<GROUP>]", flagSwapUserName, value)
+		}
+
+		for _, s := range swaps {
+			s.User = parts[0]
+			s.Group = ""
+
+			if len(parts) == 2 {
+				s.Group = parts[1]
+			}
+		}
+	}
+
+	return nil
+}
+
+// parseStopFlag parses a stop signal passed in as <SIGNAL>[:<SECONDS>].
+func parseStopFlag(value string) (stopSignalConfig, error) {
+	parts := strings.SplitN(value, ":", 2)
+	sig := stopSignalConfig{Signal: parts[0]}
+
+	if len(parts) == 2 {
+		timeout, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return sig, errors.New("expected <SIGNAL>[:<SECONDS>]")
+		}
+
+		sig.Timeout = timeout
+	}
+
+	return sig, nil
+}
+
+// listExecutables lists the executables in every priority path, logging any
+// errors.
+func listExecutables(priorities []priorityConfig, ignored []string) []executable {
+	// These are our "priority executables".
+	pe := []executable{}
+
+	// Priority and swap process setup.
+	for _, p := range priorities {
+		e, err := processList(p.Path, ignored, p.discovery())
+		if err != nil {
+			logError(fmt.Sprintf("%s error searching %s for executables: %s", aurora.Cyan("setup"), p.Path, err.Error()))
+
+			continue
+		}
+
+		pe = append(pe, e...)
+	}
+
+	return pe
 }
 
 func intersect(files []*godirwalk.Dirent, swaps []string) error {
@@ -193,12 +780,42 @@ func intersect(files []*godirwalk.Dirent, swaps []string) error {
 		filesMap[file.Name()] = true
 	}
 
+	found := []string{}
+
 	for _, swap := range swaps {
 		file := filepath.Base(swap)
 		if filesMap[file] {
-			return fmt.Errorf("%s found in both priorities and swaps, this would be bad; exiting", file)
+			found = append(found, file)
 		}
 	}
 
+	if len(found) > 0 {
+		return fmt.Errorf("%s found in both priorities and swaps, this would be bad", strings.Join(found, ", "))
+	}
+
+	return nil
+}
+
+// intersectRules returns an error if any priority rule matches the file name
+// of a swap.
+func intersectRules(rules []Rule, swaps []string) error {
+	found := []string{}
+
+	for _, swap := range swaps {
+		file := filepath.Base(swap)
+
+		for _, rule := range rules {
+			if rule.Match(file) {
+				found = append(found, fmt.Sprintf("%s (%s)", file, rule))
+
+				break
+			}
+		}
+	}
+
+	if len(found) > 0 {
+		return fmt.Errorf("%s matched by priority rules, this would be bad", strings.Join(found, ", "))
+	}
+
 	return nil
 }
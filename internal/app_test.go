@@ -1,13 +1,22 @@
 package procswap_test
 
 import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"runtime"
+	"strconv"
+	"strings"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	. "github.com/onsi/gomega/gbytes"
 
+	"github.com/google/uuid"
 	"github.com/urfave/cli/v2"
 
 	. "github.com/billiford/procswap/internal"
@@ -38,6 +47,105 @@ var _ = Describe("App", func() {
 		})
 	})
 
+	Describe("swaps outliving procswap", func() {
+		var (
+			helper  *exec.Cmd
+			dir     string
+			pidFile string
+			args    []string
+			pid     int
+		)
+
+		BeforeEach(func() {
+			if runtime.GOOS != "linux" {
+				Skip("the swap's process is looked up in /proc")
+			}
+
+			dir = filepath.Join(os.TempDir(), uuid.New().String())
+			Expect(os.MkdirAll(dir, 0o755)).To(Succeed())
+			pidFile = filepath.Join(dir, "ticker.pid")
+			pid = 0
+
+			args = []string{procswapFilename(),
+				"--priority-glob", "nothing-runs-this",
+				"-s", tickerScriptPath(),
+				"--swap-arg", "ticker=" + pidFile,
+				"--poll-interval", "1",
+				"--disable-actions",
+			}
+		})
+
+		JustBeforeEach(func() {
+			// Run procswap in a process of its own, so it can exit.
+			helper = exec.Command(os.Args[0], "-test.run=TestHelperProcess")
+			helper.Env = append(os.Environ(), "TMPDIR="+dir, "PROCSWAP_HELPER_ARGS="+strings.Join(args, "\n"))
+			Expect(helper.Start()).To(Succeed())
+
+			Eventually(func() int {
+				b, _ := ioutil.ReadFile(pidFile)
+				pid, _ = strconv.Atoi(strings.TrimSpace(string(b)))
+				return pid
+			}, 10*time.Second).ShouldNot(BeZero())
+		})
+
+		AfterEach(func() {
+			if helper != nil && helper.ProcessState == nil {
+				_ = helper.Process.Kill()
+				_ = helper.Wait()
+			}
+
+			if p, err := os.FindProcess(pid); err == nil && pid > 0 {
+				_ = p.Kill()
+			}
+
+			os.RemoveAll(dir)
+		})
+
+		When("procswap is killed while keeping a state file", func() {
+			var stateFile string
+
+			BeforeEach(func() {
+				stateFile = filepath.Join(dir, "state.json")
+				args = append(args, "--state-file", stateFile)
+			})
+
+			It("leaves the swap it recorded running", func() {
+				Eventually(func() int {
+					return recordedPID(stateFile)
+				}, 10*time.Second).Should(Equal(pid))
+
+				Expect(helper.Process.Kill()).To(Succeed())
+				_ = helper.Wait()
+
+				Consistently(func() bool {
+					return processRunning(pid)
+				}, time.Second).Should(BeTrue())
+			})
+		})
+
+		When("procswap is stopped and told to leave swaps running", func() {
+			BeforeEach(func() {
+				args = append(args, "--leave-swaps-running")
+			})
+
+			It("leaves the swap running after procswap exits", func() {
+				Expect(helper.Process.Signal(os.Interrupt)).To(Succeed())
+				_ = helper.Wait()
+				Expect(helper.ProcessState.Exited()).To(BeTrue())
+
+				Consistently(func() bool {
+					return processRunning(pid)
+				}, time.Second).Should(BeTrue())
+			})
+
+			It("leaves no output file behind to grow", func() {
+				Expect(helper.Process.Signal(os.Interrupt)).To(Succeed())
+				_ = helper.Wait()
+				Expect(filepath.Glob(filepath.Join(dir, "procswap-*"))).To(BeEmpty())
+			})
+		})
+	})
+
 	Describe("#Run", func() {
 		BeforeEach(func() {
 			app = NewApp()
@@ -67,6 +175,178 @@ var _ = Describe("App", func() {
 			err = app.Run(args)
 		})
 
+		When("no swaps are set", func() {
+			BeforeEach(func() {
+				args = []string{procswapFilename(),
+					"-p", priorityFileDir(),
+					"--limit", "1",
+					"--disable-actions",
+				}
+			})
+
+			It("returns an error", func() {
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(Equal("no swaps set, pass --swap or set swaps in the config file"))
+			})
+		})
+
+		When("a per-swap flag does not refer to a swap", func() {
+			BeforeEach(func() {
+				args = append(args, "--swap-arg", "miner.bat=-pool")
+			})
+
+			It("returns an error", func() {
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(Equal("--swap-arg miner.bat=-pool does not refer to any swap"))
+			})
+		})
+
+		When("a swap environment variable is invalid", func() {
+			BeforeEach(func() {
+				args = append(args, "--swap-env", filepath.Base(swapFilePath())+"=GPU_MAX_HEAP_SIZE")
+			})
+
+			It("returns an error", func() {
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(HavePrefix("invalid --swap-env " + filepath.Base(swapFilePath()) + "=GPU_MAX_HEAP_SIZE"))
+			})
+		})
+
+		When("a swap restart policy is invalid", func() {
+			BeforeEach(func() {
+				args = append(args, "--swap-restart", filepath.Base(swapFilePath())+"=on-failure:many")
+			})
+
+			It("returns an error", func() {
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(Equal("invalid --swap-restart " + filepath.Base(swapFilePath()) + "=on-failure:many: expected <POLICY>[:<MAX_RETRIES>]"))
+			})
+		})
+
+		When("a swap restart policy is unknown", func() {
+			BeforeEach(func() {
+				args = append(args, "--swap-restart", filepath.Base(swapFilePath())+"=sometimes")
+			})
+
+			It("returns an error", func() {
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(ContainSubstring("unknown restart policy sometimes"))
+			})
+		})
+
+		When("a swap stop signal is invalid", func() {
+			BeforeEach(func() {
+				args = append(args, "--swap-stop", filepath.Base(swapFilePath())+"=SIGINT:soon")
+			})
+
+			It("returns an error", func() {
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(Equal("invalid --swap-stop " + filepath.Base(swapFilePath()) + "=SIGINT:soon: expected <SIGNAL>[:<SECONDS>]"))
+			})
+		})
+
+		When("a swap stop signal is unknown", func() {
+			BeforeEach(func() {
+				args = append(args, "--swap-stop", filepath.Base(swapFilePath())+"=SIGNOPE")
+			})
+
+			It("returns an error", func() {
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(ContainSubstring("unknown stop signal SIGNOPE"))
+			})
+		})
+
+		When("a cgroup slice is passed in", func() {
+			BeforeEach(func() {
+				args = append(args, "--cgroup-slice", "procswap.slice", "-s", swapFilePath())
+			})
+
+			It("applies it to the swaps", func() {
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(HavePrefix("swaps " + swapFilePath() + " and " + swapFilePath() + " use the same cgroup procswap.slice"))
+			})
+		})
+
+		When("the config file does not exist", func() {
+			BeforeEach(func() {
+				args = []string{procswapFilename(), "--config", "does-not-exist.yml"}
+			})
+
+			It("returns an error", func() {
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(HavePrefix("error reading config file does-not-exist.yml"))
+			})
+		})
+
+		Context("when a config file is passed in", func() {
+			var configPath string
+
+			BeforeEach(func() {
+				configPath = writeConfigFile(fmt.Sprintf(`priorities: [%q]
+ignore: [ignore_me.exe]
+swaps:
+  - path: %q
+priority_script: %q
+poll_interval: 1
+limit: 1
+disable_actions: true
+`, priorityFileDir(), swapFilePath(), priorityScriptPath()))
+				args = []string{procswapFilename(), "--config", configPath}
+			})
+
+			AfterEach(func() {
+				os.Remove(configPath)
+			})
+
+			It("sets up the loop from the config file", func() {
+				Expect(err).To(BeNil())
+				Eventually(buffer).Should(Say(fmtInfoLog + `.*setup.* loaded config file .*` + configPath + `.*`))
+				Eventually(buffer).Should(Say(fmtInfoLog + `.*setup.* searching ` + priorityFileDir() + ` for executables`))
+				Eventually(buffer).Should(Say(fmtInfoLog + `.*setup.* ignoring priority .*ignore_me.exe.*`))
+				Eventually(buffer).Should(Say(fmtInfoLog + `.*setup.* registered .*1.* swap processes`))
+				Eventually(buffer).Should(Say(fmtInfoLog + `.*start.* .*` + swapFilePath() + `.*\.\.\. .*OK.*`))
+			})
+
+			When("the config file changes while running", func() {
+				BeforeEach(func() {
+					args = append(args, "--limit", "3")
+
+					go func() {
+						time.Sleep(500 * time.Millisecond)
+						err := ioutil.WriteFile(configPath, []byte(fmt.Sprintf(`priorities: [%q]
+swaps:
+  - path: %q
+  - path: %q
+poll_interval: 1
+disable_actions: true
+`, priorityFileDir(), swapFilePath(), priorityScriptPath())), 0600)
+						Expect(err).To(BeNil())
+					}()
+				})
+
+				It("reloads the config and starts only the new swap", func() {
+					Expect(err).To(BeNil())
+					Eventually(buffer).Should(Say(fmtInfoLog + `.*start.* .*` + swapFilePath() + `.*\.\.\. .*OK.*`))
+					Eventually(buffer).Should(Say(fmtInfoLog + `.*reload.* config file .*` + configPath + `.* changed`))
+					Eventually(buffer).Should(Say(fmtInfoLog + `.*reload.* registered .*2.* swap processes`))
+					Eventually(buffer).Should(Say(fmtInfoLog + `.*start.* .*` + priorityScriptPath() + `.*\.\.\. .*OK.*`))
+					Expect(buffer).ToNot(Say(`stop`))
+				})
+			})
+
+			When("flags are also passed in", func() {
+				BeforeEach(func() {
+					args = append(args, "--ignore", "test1.exe", "-s", swapFilePath(), "-s", swapFilePath())
+				})
+
+				It("overrides the config file values", func() {
+					Expect(err).To(BeNil())
+					Eventually(buffer).Should(Say(fmtInfoLog + `.*setup.* ignoring priority .*test1.exe.*`))
+					Eventually(buffer).Should(Say(fmtInfoLog + `.*setup.* registered .*2.* swap processes`))
+				})
+			})
+		})
+
 		When("it succeeds", func() {
 			It("succeeds", func() {
 				Expect(err).To(BeNil())
@@ -81,6 +361,27 @@ var _ = Describe("App", func() {
 	})
 })
 
+// recordedPID returns the PID of the first swap in a state file, or 0 if
+// there is none yet.
+func recordedPID(stateFile string) int {
+	b, err := ioutil.ReadFile(stateFile)
+	if err != nil {
+		return 0
+	}
+
+	var state struct {
+		Swaps []struct {
+			PID int `json:"pid"`
+		} `json:"swaps"`
+	}
+
+	if err := json.Unmarshal(b, &state); err != nil || len(state.Swaps) == 0 {
+		return 0
+	}
+
+	return state.Swaps[0].PID
+}
+
 func procswapFilename() string {
 	if runtime.GOOS == "windows" {
 		return "procswap.exe"
@@ -88,3 +389,15 @@ func procswapFilename() string {
 
 	return "procswap"
 }
+
+func writeConfigFile(contents string) string {
+	f, err := ioutil.TempFile("", "procswap-*.yml")
+	Expect(err).To(BeNil())
+
+	defer f.Close()
+
+	_, err = f.WriteString(contents)
+	Expect(err).To(BeNil())
+
+	return f.Name()
+}
@@ -0,0 +1,234 @@
+package procswap
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// nameUnsafe matches the characters that can't be part of the name of a
+// swap's cgroup or of the files its output is spooled to.
+var nameUnsafe = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// Cgroup is the cgroup v2 a swap runs in on Linux. The zero value runs the
+// swap in procswap's own cgroup.
+type Cgroup struct {
+	// Path is the swap's own cgroup, relative to the root of the cgroup v2
+	// hierarchy. It is made when the swap starts, along with its parents.
+	Path string
+	// MemoryMax is the most memory, in bytes, the swap's processes can use
+	// together (0 = unlimited).
+	MemoryMax int64
+	// CPUMax is the most CPU time the swap's processes can use together, in
+	// percent of one CPU (0 = unlimited).
+	CPUMax int
+}
+
+// SwapStats is the resource usage of a swap's cgroup.
+type SwapStats struct {
+	// CPU is the CPU time used by every process that ran in the cgroup.
+	CPU time.Duration
+	// Memory is the memory, in bytes, the cgroup uses now. It is -1 when the
+	// memory controller is not enabled for the cgroup.
+	Memory int64
+}
+
+// String describes the usage for logging.
+func (s SwapStats) String() string {
+	if s.Memory < 0 {
+		return fmt.Sprintf("used %s of cpu", s.CPU.Round(time.Millisecond))
+	}
+
+	return fmt.Sprintf("using %s of memory, used %s of cpu", formatBytes(s.Memory), s.CPU.Round(time.Millisecond))
+}
+
+// validate returns an error if a limit is out of range or set without a
+// cgroup to apply it to.
+func (c Cgroup) validate() error {
+	if c.MemoryMax < 0 {
+		return errors.New("cgroup memory max can't be negative")
+	}
+
+	if c.CPUMax < 0 {
+		return errors.New("cgroup cpu max can't be negative")
+	}
+
+	if c.Path == "" && (c.MemoryMax != 0 || c.CPUMax != 0) {
+		return errors.New("cgroup limits need a cgroup slice to make the swap's cgroup in")
+	}
+
+	return nil
+}
+
+// cgroupPathFor returns the path of a swap's cgroup in slice. Every group
+// has a cgroup of its own in the slice with the cgroups of its swaps inside.
+func cgroupPathFor(slice, group, name string) string {
+	if slice == "" {
+		return ""
+	}
+
+	return filepath.ToSlash(filepath.Join(strings.TrimPrefix(slice, "/"),
+		nameUnsafe.ReplaceAllString(group, "_"), nameUnsafe.ReplaceAllString(name, "_")))
+}
+
+// memoryUnits are the suffixes a memory size can have.
+var memoryUnits = map[string]int64{
+	"":  1,
+	"K": 1 << 10,
+	"M": 1 << 20,
+	"G": 1 << 30,
+	"T": 1 << 40,
+}
+
+// parseMemory parses a memory size like 512M or 2G. An empty size or max
+// means unlimited and parses to 0.
+func parseMemory(size string) (int64, error) {
+	size = strings.ToUpper(strings.TrimSpace(size))
+	if size == "" || size == "MAX" {
+		return 0, nil
+	}
+
+	number := strings.TrimRight(size, "KMGTB")
+	unit := strings.TrimSuffix(strings.TrimPrefix(size, number), "B")
+
+	multiplier, ok := memoryUnits[unit]
+	if !ok {
+		return 0, fmt.Errorf("invalid memory size %s, use a number of bytes or one ending in K, M, G or T", size)
+	}
+
+	n, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid memory size %s, use a number of bytes or one ending in K, M, G or T", size)
+	}
+
+	return n * multiplier, nil
+}
+
+// formatBytes formats a number of bytes for logging.
+func formatBytes(n int64) string {
+	const unit = 1 << 10
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGT"[exp])
+}
+
+// WithCgroup sets the cgroup the swap runs in.
+func (s *swap) WithCgroup(cgroup Cgroup) {
+	s.cgroup = cgroup
+}
+
+// Stats returns the resource usage of the swap's cgroup.
+func (s *swap) Stats() (SwapStats, error) {
+	if s.cgroup.Path == "" {
+		return SwapStats{}, errors.New("swap does not run in a cgroup of its own")
+	}
+
+	return readCgroupStats(s.cgroup.Path)
+}
+
+// startCgroup makes the swap's cgroup and sets its limits, so the swap can
+// start inside it.
+func (s *swap) startCgroup() error {
+	if s.cgroup.Path == "" {
+		return nil
+	}
+
+	if err := makeCgroup(s.cgroup); err != nil {
+		return fmt.Errorf("error making cgroup %s: %w", s.cgroup.Path, err)
+	}
+
+	return nil
+}
+
+// openCgroupFor has the command start inside the swap's cgroup, so nothing
+// the swap forks escapes its limits. It returns the cgroup's directory for
+// the caller to close once the command has started, or nil when the swap has
+// to join its cgroup once started instead.
+func (s *swap) openCgroupFor(cmd *exec.Cmd) *os.File {
+	if s.cgroup.Path == "" {
+		return nil
+	}
+
+	dir, err := openCgroup(s.cgroup.Path)
+	if err != nil {
+		return nil
+	}
+
+	if !startInCgroup(cmd, dir) {
+		dir.Close()
+
+		return nil
+	}
+
+	return dir
+}
+
+// joinCgroup moves the swap's process into its cgroup, everything it starts
+// from then on is part of the cgroup too. It is only needed when the kernel
+// can't start the process inside its cgroup, and leaves a window in which
+// what the process forks escapes the cgroup. A swap that can't join its
+// cgroup is killed, as its limits wouldn't apply.
+func (s *swap) joinCgroup() error {
+	if s.cgroup.Path == "" {
+		return nil
+	}
+
+	if err := moveToCgroup(s.cgroup.Path, s.cmd.Process.Pid); err != nil {
+		_ = s.cmd.Process.Kill()
+		_, _ = s.cmd.Process.Wait()
+
+		return fmt.Errorf("error moving %s to cgroup %s: %w", s.path, s.cgroup.Path, err)
+	}
+
+	return nil
+}
+
+// cgroupPIDs returns the PIDs of every process in the swap's cgroup, or nil
+// if it has no cgroup of its own.
+func (s *swap) cgroupPIDs() []int {
+	if s.cgroup.Path == "" {
+		return nil
+	}
+
+	pids, err := cgroupProcs(s.cgroup.Path)
+	if err != nil {
+		return nil
+	}
+
+	return pids
+}
+
+// killCgroup kills every process in the swap's cgroup at once, if it has
+// one. Kernels before 5.14 can't kill a cgroup, which leaves killing its
+// processes one by one.
+func (s *swap) killCgroup() {
+	if s.cgroup.Path == "" {
+		return
+	}
+
+	_ = killCgroup(s.cgroup.Path)
+}
+
+// removeCgroup removes the swap's cgroup once every process in it has
+// exited. A cgroup that can't be removed is reused when the swap starts again.
+func (s *swap) removeCgroup() {
+	if s.cgroup.Path == "" {
+		return
+	}
+
+	_ = removeCgroup(s.cgroup.Path)
+}
@@ -0,0 +1,85 @@
+//go:build go1.20
+// +build go1.20
+
+package procswap
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+var (
+	// cgroupFDOnce guards checking if the kernel can start a process inside
+	// a cgroup.
+	cgroupFDOnce sync.Once
+	// cgroupFDKernel is true if the kernel can start a process inside a
+	// cgroup, which needs Linux 5.7 or later.
+	cgroupFDKernel bool
+)
+
+// startInCgroup has the command's process start inside the cgroup at dir,
+// before it can run. It returns false when the kernel is too old to do that.
+func startInCgroup(cmd *exec.Cmd, dir *os.File) bool {
+	cgroupFDOnce.Do(func() {
+		cgroupFDKernel = kernelAtLeast(5, 7)
+	})
+
+	if !cgroupFDKernel {
+		return false
+	}
+
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = int(dir.Fd())
+
+	return true
+}
+
+// cgroupFDFailed returns true if a command that was to start inside a cgroup
+// failed because clone3 isn't available, rather than for a reason of its
+// own. Containers often turn down clone3 with ENOSYS.
+func cgroupFDFailed(err error) bool {
+	return errors.Is(err, syscall.ENOSYS)
+}
+
+// kernelAtLeast returns true if the running kernel is at least version
+// major.minor, or if its version can't be read.
+func kernelAtLeast(major, minor int) bool {
+	b, err := ioutil.ReadFile("/proc/sys/kernel/osrelease")
+	if err != nil {
+		return true
+	}
+
+	parts := strings.SplitN(strings.TrimSpace(string(b)), ".", 3)
+	if len(parts) < 2 {
+		return true
+	}
+
+	gotMajor, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return true
+	}
+
+	// The minor version can be followed by more than a patch version, like
+	// in 5.7-rc1.
+	digits := parts[1]
+	if i := strings.IndexFunc(digits, func(r rune) bool { return r < '0' || r > '9' }); i >= 0 {
+		digits = digits[:i]
+	}
+
+	gotMinor, err := strconv.Atoi(digits)
+	if err != nil {
+		return true
+	}
+
+	return gotMajor > major || gotMajor == major && gotMinor >= minor
+}
@@ -0,0 +1,296 @@
+package procswap
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultCgroupRoot is where the cgroup v2 hierarchy is usually mounted.
+	defaultCgroupRoot = "/sys/fs/cgroup"
+	// cgroupCPUPeriod is the period, in microseconds, a cgroup's CPU max is
+	// measured over.
+	cgroupCPUPeriod = 100000
+	// cgroupLeaf is the cgroup procswap moves itself to when the cgroups of
+	// its swaps are made inside its own cgroup, as only cgroups without
+	// processes can hand controllers down.
+	cgroupLeaf = "procswap"
+)
+
+// cgroupControllers are the controllers a swap's cgroup is given, if its
+// parent has them.
+var cgroupControllers = []string{"cpu", "memory"}
+
+// cgroupRoot returns where the cgroup v2 hierarchy is mounted, which is
+// /sys/fs/cgroup/unified on systems that mount cgroup v1 as well.
+func cgroupRoot() string {
+	b, err := ioutil.ReadFile("/proc/self/mounts")
+	if err != nil {
+		return defaultCgroupRoot
+	}
+
+	for _, line := range strings.Split(string(b), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 2 && fields[2] == "cgroup2" {
+			return fields[1]
+		}
+	}
+
+	return defaultCgroupRoot
+}
+
+// makeCgroup makes a cgroup and its parents, handing the CPU and memory
+// controllers down to it, and sets its limits.
+func makeCgroup(c Cgroup) error {
+	root := cgroupRoot()
+	dir := filepath.Join(root, c.Path)
+
+	if err := makeCgroupDir(root, dir); err != nil {
+		return err
+	}
+
+	memory := "max"
+	if c.MemoryMax > 0 {
+		memory = strconv.FormatInt(c.MemoryMax, 10)
+	}
+
+	if err := writeCgroupFile(dir, "memory.max", memory, c.MemoryMax > 0); err != nil {
+		return fmt.Errorf("error setting memory max: %w", err)
+	}
+
+	cpu := "max"
+	if c.CPUMax > 0 {
+		cpu = strconv.Itoa(c.CPUMax * cgroupCPUPeriod / 100)
+	}
+
+	if err := writeCgroupFile(dir, "cpu.max", fmt.Sprintf("%s %d", cpu, cgroupCPUPeriod), c.CPUMax > 0); err != nil {
+		return fmt.Errorf("error setting cpu max: %w", err)
+	}
+
+	return nil
+}
+
+// makeCgroupDir makes the cgroup at dir, making its parents first and
+// handing the controllers down from each parent.
+func makeCgroupDir(root, dir string) error {
+	if dir == root {
+		return nil
+	}
+
+	if _, err := os.Stat(dir); err == nil {
+		return nil
+	}
+
+	parent := filepath.Dir(dir)
+	if err := makeCgroupDir(root, parent); err != nil {
+		return err
+	}
+
+	if err := enableControllers(root, parent); err != nil {
+		return err
+	}
+
+	return os.Mkdir(dir, 0o755)
+}
+
+// enableControllers hands the CPU and memory controllers of the cgroup at
+// dir down to its children. When procswap runs in that cgroup, say as a
+// service it was delegated to, procswap moves to a leaf cgroup first.
+func enableControllers(root, dir string) error {
+	available, err := readCgroupList(filepath.Join(dir, "cgroup.controllers"))
+	if err != nil {
+		return err
+	}
+
+	enabled, err := readCgroupList(filepath.Join(dir, "cgroup.subtree_control"))
+	if err != nil {
+		return err
+	}
+
+	changes := []string{}
+
+	for _, controller := range cgroupControllers {
+		if contains(available, controller) && !contains(enabled, controller) {
+			changes = append(changes, "+"+controller)
+		}
+	}
+
+	if len(changes) == 0 {
+		return nil
+	}
+
+	if dir != root {
+		if err := vacateCgroup(dir); err != nil {
+			return err
+		}
+	}
+
+	return ioutil.WriteFile(filepath.Join(dir, "cgroup.subtree_control"), []byte(strings.Join(changes, " ")), 0o644)
+}
+
+// vacateCgroup moves procswap out of the cgroup at dir to a leaf cgroup
+// inside it. Other processes in the cgroup, like other services sharing it,
+// aren't procswap's to move, so they are an error.
+func vacateCgroup(dir string) error {
+	pids, err := readCgroupPIDs(dir)
+	if err != nil || len(pids) == 0 {
+		return err
+	}
+
+	others := []string{}
+
+	for _, pid := range pids {
+		if pid != os.Getpid() {
+			others = append(others, strconv.Itoa(pid))
+		}
+	}
+
+	if len(others) > 0 {
+		return fmt.Errorf("cgroup %s has processes other than procswap in it (%s), use a cgroup slice procswap has to itself",
+			dir, strings.Join(others, ", "))
+	}
+
+	leaf := filepath.Join(dir, cgroupLeaf)
+	if err := os.Mkdir(leaf, 0o755); err != nil && !os.IsExist(err) {
+		return err
+	}
+
+	if err := writePID(leaf, os.Getpid()); err != nil {
+		return fmt.Errorf("error moving procswap out of cgroup %s: %w", dir, err)
+	}
+
+	return nil
+}
+
+// moveToCgroup moves a process into the cgroup at path.
+func moveToCgroup(path string, pid int) error {
+	return writePID(filepath.Join(cgroupRoot(), path), pid)
+}
+
+// cgroupProcs returns the PIDs of every process in the cgroup at path.
+func cgroupProcs(path string) ([]int, error) {
+	return readCgroupPIDs(filepath.Join(cgroupRoot(), path))
+}
+
+// killCgroup kills every process in the cgroup at path and its
+// descendants. It needs Linux 5.14 or later.
+func killCgroup(path string) error {
+	return ioutil.WriteFile(filepath.Join(cgroupRoot(), path, "cgroup.kill"), []byte("1"), 0o644)
+}
+
+// removeCgroup removes the cgroup at path, which fails while any process
+// is still in it.
+func removeCgroup(path string) error {
+	return os.Remove(filepath.Join(cgroupRoot(), path))
+}
+
+// readCgroupStats reads the CPU time used by the cgroup at path and the
+// memory it uses now.
+func readCgroupStats(path string) (SwapStats, error) {
+	dir := filepath.Join(cgroupRoot(), path)
+	stats := SwapStats{Memory: -1}
+
+	f, err := os.Open(filepath.Join(dir, "cpu.stat"))
+	if err != nil {
+		return stats, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "usage_usec" {
+			usec, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return stats, fmt.Errorf("invalid cpu usage in %s: %w", f.Name(), err)
+			}
+
+			stats.CPU = time.Duration(usec) * time.Microsecond
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return stats, err
+	}
+	// The memory controller may not be enabled for the cgroup.
+	b, err := ioutil.ReadFile(filepath.Join(dir, "memory.current"))
+	if err == nil {
+		if memory, err := strconv.ParseInt(strings.TrimSpace(string(b)), 10, 64); err == nil {
+			stats.Memory = memory
+		}
+	}
+
+	return stats, nil
+}
+
+// writeCgroupFile writes value to a file of the cgroup at dir. A file that
+// doesn't exist because its controller isn't enabled is only an error when
+// required.
+func writeCgroupFile(dir, name, value string, required bool) error {
+	// Files can't be made in a cgroup, so don't try to.
+	f, err := os.OpenFile(filepath.Join(dir, name), os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		if !required && os.IsNotExist(err) {
+			return nil
+		}
+
+		return err
+	}
+
+	if _, err := f.WriteString(value); err != nil {
+		f.Close()
+
+		return err
+	}
+
+	return f.Close()
+}
+
+// writePID moves a process into the cgroup at dir.
+func writePID(dir string, pid int) error {
+	return ioutil.WriteFile(filepath.Join(dir, "cgroup.procs"), []byte(strconv.Itoa(pid)), 0o644)
+}
+
+// readCgroupPIDs reads the PIDs of the processes in the cgroup at dir.
+func readCgroupPIDs(dir string) ([]int, error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, "cgroup.procs"))
+	if err != nil {
+		return nil, err
+	}
+
+	pids := []int{}
+
+	for _, field := range bytes.Fields(b) {
+		pid, err := strconv.Atoi(string(field))
+		if err != nil {
+			return nil, fmt.Errorf("invalid pid %s in %s", field, dir)
+		}
+
+		pids = append(pids, pid)
+	}
+
+	return pids, nil
+}
+
+// readCgroupList reads a space separated list of controllers.
+func readCgroupList(file string) ([]string, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return strings.Fields(string(b)), nil
+}
+
+// openCgroup opens the directory of the cgroup at path, for a process to be
+// started inside it.
+func openCgroup(path string) (*os.File, error) {
+	return os.Open(filepath.Join(cgroupRoot(), path))
+}
@@ -0,0 +1,19 @@
+//go:build !go1.20
+// +build !go1.20
+
+package procswap
+
+import (
+	"os"
+	"os/exec"
+)
+
+// startInCgroup returns false, as starting a process inside a cgroup needs
+// Go 1.20 or later. The process is moved into its cgroup once started.
+func startInCgroup(cmd *exec.Cmd, dir *os.File) bool {
+	return false
+}
+
+func cgroupFDFailed(err error) bool {
+	return false
+}
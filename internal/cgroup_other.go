@@ -0,0 +1,50 @@
+//go:build !linux
+// +build !linux
+
+package procswap
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+// errCgroupsNotSupported is returned by every cgroup function outside of Linux.
+var errCgroupsNotSupported = errors.New("cgroups are not supported on " + runtime.GOOS)
+
+func makeCgroup(c Cgroup) error {
+	return errCgroupsNotSupported
+}
+
+func openCgroup(path string) (*os.File, error) {
+	return nil, errCgroupsNotSupported
+}
+
+func startInCgroup(cmd *exec.Cmd, dir *os.File) bool {
+	return false
+}
+
+func cgroupFDFailed(err error) bool {
+	return false
+}
+
+func moveToCgroup(path string, pid int) error {
+	return errCgroupsNotSupported
+}
+
+func cgroupProcs(path string) ([]int, error) {
+	return nil, errCgroupsNotSupported
+}
+
+func killCgroup(path string) error {
+	return errCgroupsNotSupported
+}
+
+func removeCgroup(path string) error {
+	return errCgroupsNotSupported
+}
+
+func readCgroupStats(path string) (SwapStats, error) {
+	return SwapStats{}, errCgroupsNotSupported
+}
@@ -0,0 +1,975 @@
+package procswap
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/logrusorgru/aurora"
+	"gopkg.in/yaml.v2"
+)
+
+// config is the declarative setup of a loop. It can be loaded from a YAML or
+// TOML file and any value can be overridden by its CLI flag.
+//
+// The priorities, ignores, priority script and swaps at the top level make up
+// the default group, named groups are listed under groups.
+type config struct {
+	// CgroupSlice is the cgroup v2, relative to the root of the hierarchy,
+	// each swap gets a cgroup of its own in. Swaps start inside their cgroup,
+	// except before Linux 5.7 or where clone3 is blocked, where they join it
+	// just after starting and anything forked before that escapes it.
+	CgroupSlice string `yaml:"cgroup_slice" toml:"cgroup_slice"`
+	// DisableActions disables actions (keyboard inputs).
+	DisableActions bool `yaml:"disable_actions" toml:"disable_actions"`
+	// DryRun logs what the loop would do without starting or stopping anything.
+	DryRun bool `yaml:"dry_run" toml:"dry_run"`
+	// Groups is a list of named groups, each with their own priorities and swaps.
+	Groups []groupConfig `yaml:"groups" toml:"groups"`
+	// Ignore is a list of priorities to ignore by name or full path (case insensitive).
+	Ignore []string `yaml:"ignore" toml:"ignore"`
+	// LeaveSwapsRunning leaves the swaps running when procswap is stopped.
+	LeaveSwapsRunning bool `yaml:"leave_swaps_running" toml:"leave_swaps_running"`
+	// Limit is the number of times the loop runs (0 = infinite).
+	Limit int `yaml:"limit" toml:"limit"`
+	// Match is how running processes are matched against priority executables.
+	Match string `yaml:"match" toml:"match"`
+	// OutputHistory is the number of lines of output kept for each swap.
+	OutputHistory int `yaml:"output_history" toml:"output_history"`
+	// PollInterval is the time in seconds to wait to poll for running processes.
+	PollInterval int `yaml:"poll_interval" toml:"poll_interval"`
+	// Priorities is a list of files or directories to scan for executables.
+	Priorities []priorityConfig `yaml:"priorities" toml:"priorities"`
+	// PriorityGlobs is a list of glob patterns matched against running process names.
+	PriorityGlobs []string `yaml:"priority_globs" toml:"priority_globs"`
+	// PriorityRegexes is a list of regular expressions matched against running process names.
+	PriorityRegexes []string `yaml:"priority_regexes" toml:"priority_regexes"`
+	// PriorityScript is a path to a script that runs once when any priority starts.
+	PriorityScript string `yaml:"priority_script" toml:"priority_script"`
+	// StateFile is where the running swaps are kept, so they are adopted
+	// instead of started again when procswap restarts.
+	StateFile string `yaml:"state_file" toml:"state_file"`
+	// SwapLogs is where and how the output of swaps is logged.
+	SwapLogs swapLogsConfig `yaml:"swap_logs" toml:"swap_logs"`
+	// Swaps is a list of processes that run when no priority is running.
+	Swaps []swapConfig `yaml:"swaps" toml:"swaps"`
+}
+
+// swapLogsConfig is the declarative setup of the files the output of swaps
+// is written to.
+type swapLogsConfig struct {
+	// Dir is the directory each swap's output is logged to, in a file named
+	// after the swap. Nothing is logged when it is empty.
+	Dir string `yaml:"dir" toml:"dir"`
+	// MaxSize is the size in megabytes a log file can grow to before it is rotated.
+	MaxSize int `yaml:"max_size" toml:"max_size"`
+	// MaxAge is the number of days rotated log files are kept (0 = forever).
+	MaxAge int `yaml:"max_age" toml:"max_age"`
+	// MaxBackups is the number of rotated log files kept (0 = all).
+	MaxBackups int `yaml:"max_backups" toml:"max_backups"`
+	// Compress gzips rotated log files.
+	Compress bool `yaml:"compress" toml:"compress"`
+}
+
+// groupConfig is the declarative setup of a named group. Its swaps are only
+// stopped by its own priorities.
+type groupConfig struct {
+	// Name identifies the group.
+	Name string `yaml:"name" toml:"name"`
+	// Ignore is a list of priorities to ignore by name or full path (case insensitive).
+	Ignore []string `yaml:"ignore" toml:"ignore"`
+	// Match is how running processes are matched against priority executables.
+	Match string `yaml:"match" toml:"match"`
+	// Priorities is a list of files or directories to scan for executables.
+	Priorities []priorityConfig `yaml:"priorities" toml:"priorities"`
+	// PriorityGlobs is a list of glob patterns matched against running process names.
+	PriorityGlobs []string `yaml:"priority_globs" toml:"priority_globs"`
+	// PriorityRegexes is a list of regular expressions matched against running process names.
+	PriorityRegexes []string `yaml:"priority_regexes" toml:"priority_regexes"`
+	// PriorityScript is a path to a script that runs once when any priority of the group starts.
+	PriorityScript string `yaml:"priority_script" toml:"priority_script"`
+	// Swaps is a list of processes that run when no priority of the group is running.
+	Swaps []swapConfig `yaml:"swaps" toml:"swaps"`
+}
+
+// priorityConfig is a file or directory to scan for priority executables
+// along with how to find them. In a config file it can be written as just
+// the path.
+type priorityConfig struct {
+	// Path is the path to a file or directory to scan for executables.
+	Path string `yaml:"path" toml:"path"`
+	// Discovery is the discovery mode, it defaults to finding ".exe" files.
+	Discovery string `yaml:"discovery" toml:"discovery"`
+	// Patterns are the glob patterns or regular expressions for the glob and
+	// regex discovery modes.
+	Patterns []string `yaml:"patterns" toml:"patterns"`
+}
+
+// swapConfig is the declarative setup of a single swap process.
+type swapConfig struct {
+	// After are the names, paths or file names of the swaps of the same
+	// group that have to be ready before the swap starts.
+	After []string `yaml:"after" toml:"after"`
+	// Args are the arguments passed to the swap.
+	Args []string `yaml:"args" toml:"args"`
+	// Cgroup holds the limits of the swap's cgroup.
+	Cgroup cgroupConfig `yaml:"cgroup" toml:"cgroup"`
+	// Delay is the time in seconds to wait before starting the swap.
+	Delay int `yaml:"delay" toml:"delay"`
+	// Dir is the working directory of the swap, it defaults to the directory
+	// the swap lives in.
+	Dir string `yaml:"dir" toml:"dir"`
+	// Env holds extra environment variables for the swap.
+	Env map[string]string `yaml:"env" toml:"env"`
+	// Group is the group the swap runs as, it defaults to the user's primary
+	// group. It needs a user.
+	Group string `yaml:"group" toml:"group"`
+	// Health checks that the running swap is healthy.
+	Health healthConfig `yaml:"health" toml:"health"`
+	// Log is the file the swap's output is logged to, it defaults to a file
+	// named after the swap in the swap logs directory.
+	Log string `yaml:"log" toml:"log"`
+	// Mode is stop, pause or throttle, it decides what happens to the swap
+	// when a priority starts.
+	Mode string `yaml:"mode" toml:"mode"`
+	// Name identifies the swap, it defaults to the swap's path.
+	Name string `yaml:"name" toml:"name"`
+	// Path is the path to the swap script or executable.
+	Path string `yaml:"path" toml:"path"`
+	// Ready is when the swap is ready for the swaps that start after it.
+	Ready readyConfig `yaml:"ready" toml:"ready"`
+	// Restart decides if the swap is restarted when it exits on its own.
+	Restart restartConfig `yaml:"restart" toml:"restart"`
+	// Stop is how the swap is stopped, by default it is killed.
+	Stop stopConfig `yaml:"stop" toml:"stop"`
+	// Throttle is how the swap is slowed down in throttle mode.
+	Throttle throttleConfig `yaml:"throttle" toml:"throttle"`
+	// User is the user the swap runs as, it defaults to the user procswap
+	// runs as.
+	User string `yaml:"user" toml:"user"`
+	// logs are the swap logs settings of the config the swap is part of.
+	logs swapLogsConfig
+	// history is the number of lines of output kept for the swap.
+	history int
+	// cgroupSlice is the cgroup slice of the config the swap is part of.
+	cgroupSlice string
+	// groupName is the name of the group the swap is part of.
+	groupName string
+	// spool is where the swap's output is spooled to, if anywhere.
+	spool string
+}
+
+// healthConfig is the declarative setup of a swap's health probe.
+type healthConfig struct {
+	// HTTP is a URL that has to answer a GET with a 2xx or 3xx status.
+	HTTP string `yaml:"http" toml:"http"`
+	// TCP is an address that has to accept a connection.
+	TCP string `yaml:"tcp" toml:"tcp"`
+	// Command is a command that has to exit with code 0.
+	Command string `yaml:"command" toml:"command"`
+	// Args are the arguments passed to the command.
+	Args []string `yaml:"args" toml:"args"`
+	// InitialDelay is the time in seconds to wait after the swap starts before the first probe.
+	InitialDelay int `yaml:"initial_delay" toml:"initial_delay"`
+	// Interval is the time in seconds between probes.
+	Interval int `yaml:"interval" toml:"interval"`
+	// Timeout is the time in seconds a probe can take.
+	Timeout int `yaml:"timeout" toml:"timeout"`
+	// FailureThreshold is how many probes in a row have to fail before the swap is restarted.
+	FailureThreshold int `yaml:"failure_threshold" toml:"failure_threshold"`
+}
+
+// readyConfig is the declarative setup of when a swap is ready.
+type readyConfig struct {
+	// TCP is an address that has to accept a connection.
+	TCP string `yaml:"tcp" toml:"tcp"`
+	// Output is a regular expression a line of the swap's output has to match.
+	Output string `yaml:"output" toml:"output"`
+	// Timeout is the time in seconds swaps wait for the swap to be ready.
+	Timeout int `yaml:"timeout" toml:"timeout"`
+}
+
+// restartConfig is the declarative setup of a swap's restart policy.
+type restartConfig struct {
+	// Policy is one of never, on-failure or always.
+	Policy string `yaml:"policy" toml:"policy"`
+	// MaxRetries is the number of restarts in a row before giving up (0 = infinite).
+	MaxRetries int `yaml:"max_retries" toml:"max_retries"`
+	// Backoff is the time in seconds to wait before the first restart.
+	Backoff int `yaml:"backoff" toml:"backoff"`
+	// MaxBackoff is the longest time in seconds to wait between restarts.
+	MaxBackoff int `yaml:"max_backoff" toml:"max_backoff"`
+}
+
+// stopConfig is the declarative setup of how a swap is stopped.
+type stopConfig struct {
+	// Command is a command run to stop the swap.
+	Command string `yaml:"command" toml:"command"`
+	// Args are the arguments passed to the command.
+	Args []string `yaml:"args" toml:"args"`
+	// Timeout is the time in seconds to wait for the swap to exit after the command runs.
+	Timeout int `yaml:"timeout" toml:"timeout"`
+	// Signals are sent one after the other until the swap exits.
+	Signals []stopSignalConfig `yaml:"signals" toml:"signals"`
+}
+
+// cgroupConfig is the declarative setup of the limits of a swap's cgroup.
+type cgroupConfig struct {
+	// MemoryMax is the most memory the swap can use, like 512M or 2G.
+	MemoryMax string `yaml:"memory_max" toml:"memory_max"`
+	// CPUMax is the most CPU time the swap can use, in percent of one CPU.
+	CPUMax int `yaml:"cpu_max" toml:"cpu_max"`
+}
+
+// throttleConfig is the declarative setup of how a swap is throttled.
+type throttleConfig struct {
+	// Nice is the niceness, from 1 to 19, the swap runs at.
+	Nice int `yaml:"nice" toml:"nice"`
+	// CPUs are the CPUs the swap is restricted to.
+	CPUs []int `yaml:"cpus" toml:"cpus"`
+	// CPUWeight is the cgroup v2 CPU weight, from 1 to 10000, of the swap's cgroup.
+	CPUWeight int `yaml:"cpu_weight" toml:"cpu_weight"`
+}
+
+// stopSignalConfig is a signal sent to stop a swap.
+type stopSignalConfig struct {
+	// Signal is the name of the signal, like SIGINT.
+	Signal string `yaml:"signal" toml:"signal"`
+	// Timeout is the time in seconds to wait for the swap to exit.
+	Timeout int `yaml:"timeout" toml:"timeout"`
+}
+
+// loadConfig reads the config file at path. The format is chosen by the
+// file's extension. Relative paths inside the file are resolved against the
+// directory the file lives in.
+func loadConfig(path string) (*config, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config file %s: %w", path, err)
+	}
+
+	cfg := &config{}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yml", ".yaml":
+		err = yaml.UnmarshalStrict(b, cfg)
+	case ".toml":
+		err = decodeTOML(b, cfg)
+	default:
+		return nil, fmt.Errorf("unsupported config file format %s, use .yml, .yaml or .toml", path)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error parsing config file %s: %w", path, err)
+	}
+
+	cfg.resolvePaths(filepath.Dir(path))
+
+	return cfg, nil
+}
+
+// decodeTOML decodes b into cfg, returning an error for any keys that are
+// not part of the config so typos don't silently go unnoticed.
+func decodeTOML(b []byte, cfg *config) error {
+	md, err := toml.Decode(string(b), cfg)
+	if err != nil {
+		return err
+	}
+
+	keys := []string{}
+
+	for _, key := range md.Undecoded() {
+		// Priorities decode themselves and check their own keys.
+		if len(key) > 1 && key[len(key)-2] == "priorities" {
+			continue
+		}
+
+		keys = append(keys, key.String())
+	}
+
+	if len(keys) > 0 {
+		return fmt.Errorf("unknown keys %s", strings.Join(keys, ", "))
+	}
+
+	return nil
+}
+
+// resolvePaths makes all relative paths in the config relative to dir.
+func (c *config) resolvePaths(dir string) {
+	for i := range c.Priorities {
+		c.Priorities[i].Path = resolvePath(dir, c.Priorities[i].Path)
+	}
+
+	for i := range c.Swaps {
+		c.Swaps[i].resolvePaths(dir)
+	}
+
+	c.PriorityScript = resolvePath(dir, c.PriorityScript)
+	c.StateFile = resolvePath(dir, c.StateFile)
+	c.SwapLogs.Dir = resolvePath(dir, c.SwapLogs.Dir)
+
+	for i := range c.Groups {
+		c.Groups[i].resolvePaths(dir)
+	}
+}
+
+// resolvePaths makes all relative paths in the group relative to dir.
+func (g *groupConfig) resolvePaths(dir string) {
+	for i := range g.Priorities {
+		g.Priorities[i].Path = resolvePath(dir, g.Priorities[i].Path)
+	}
+
+	for i := range g.Swaps {
+		g.Swaps[i].resolvePaths(dir)
+	}
+
+	g.PriorityScript = resolvePath(dir, g.PriorityScript)
+}
+
+// resolvePaths makes all relative paths of the swap relative to dir.
+func (s *swapConfig) resolvePaths(dir string) {
+	s.Path = resolvePath(dir, s.Path)
+	s.Dir = resolvePath(dir, s.Dir)
+	s.Log = resolvePath(dir, s.Log)
+	// A stop or health command that is just a name is looked up in the PATH.
+	if filepath.Base(s.Stop.Command) != s.Stop.Command {
+		s.Stop.Command = resolvePath(dir, s.Stop.Command)
+	}
+
+	if filepath.Base(s.Health.Command) != s.Health.Command {
+		s.Health.Command = resolvePath(dir, s.Health.Command)
+	}
+}
+
+func resolvePath(dir, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+
+	return filepath.Join(dir, filepath.FromSlash(path))
+}
+
+// defaultGroup returns the group made up of the top level priorities,
+// ignores, priority script and swaps.
+func (c *config) defaultGroup() groupConfig {
+	return groupConfig{
+		Name:            defaultGroupName,
+		Ignore:          c.Ignore,
+		Match:           c.Match,
+		Priorities:      c.Priorities,
+		PriorityGlobs:   c.PriorityGlobs,
+		PriorityRegexes: c.PriorityRegexes,
+		PriorityScript:  c.PriorityScript,
+		Swaps:           c.Swaps,
+	}
+}
+
+// groups returns every group in the config. The default group is only
+// included if it has swaps or there are no named groups.
+func (c *config) groups() []groupConfig {
+	groups := []groupConfig{}
+	if len(c.Swaps) > 0 || len(c.Groups) == 0 {
+		groups = append(groups, c.defaultGroup())
+	}
+
+	groups = append(groups, c.Groups...)
+	// Swaps log and keep their output using the settings at the top of the
+	// config, and refer to the swaps they start after by name.
+	for i, g := range groups {
+		swaps := make([]swapConfig, len(g.Swaps))
+		keys := swapKeys(g.Name, g.Swaps)
+
+		for j, s := range g.Swaps {
+			s.logs = c.SwapLogs
+			s.history = c.OutputHistory
+			s.cgroupSlice = c.CgroupSlice
+			s.groupName = g.Name
+			s.spool = c.spoolPath(keys[j])
+			s.After = g.swapNames(s.After)
+			swaps[j] = s
+		}
+
+		groups[i].Swaps = swaps
+	}
+
+	return groups
+}
+
+// spoolPath returns where the swap with key writes its output, or nothing if
+// it writes to pipes. Swaps that are adopted when procswap restarts have to
+// outlive it, so they spool their output next to the state file. Swaps left
+// running without a state file write to the null device, as a file nothing
+// reads once procswap is gone would grow without bound.
+func (c *config) spoolPath(key string) string {
+	switch {
+	case c.StateFile != "":
+		return spoolPathFor(c.StateFile+".output", key)
+	case c.LeaveSwapsRunning:
+		return os.DevNull
+	default:
+		return ""
+	}
+}
+
+// validate makes sure every group has priorities and swaps, that group
+// names are unique and that every priority's discovery, rules, match mode and
+// swap mode, stop strategy, restart policy, health probe, startup, log and
+// cgroup are valid. Every mistake found is returned together in one error.
+func (c *config) validate() error {
+	errs := []error{}
+	// Priorities and swaps used to be required flags, keep them required
+	// now that they can come from either place.
+	if len(c.Groups) == 0 || c.defaultGroup().hasPriorities() || len(c.Swaps) > 0 {
+		if !c.defaultGroup().hasPriorities() {
+			errs = append(errs, fmt.Errorf("no priorities set, pass --%s, --%s or --%s or set priorities in the config file",
+				flagPriorityName, flagPriorityGlobName, flagPriorityRegexName))
+		}
+
+		if len(c.Swaps) == 0 {
+			errs = append(errs, fmt.Errorf("no swaps set, pass --%s or set swaps in the config file", flagSwapName))
+		}
+	}
+
+	names := map[string]bool{}
+
+	for _, g := range c.Groups {
+		switch {
+		case g.Name == "":
+			errs = append(errs, errors.New("every group must have a name"))
+		case g.Name == defaultGroupName:
+			errs = append(errs, fmt.Errorf("group name %s is reserved", defaultGroupName))
+		case names[g.Name]:
+			errs = append(errs, fmt.Errorf("group %s is defined more than once", g.Name))
+		case !g.hasPriorities():
+			errs = append(errs, fmt.Errorf("group %s has no priorities", g.Name))
+		case len(g.Swaps) == 0:
+			errs = append(errs, fmt.Errorf("group %s has no swaps", g.Name))
+		}
+
+		names[g.Name] = true
+	}
+
+	if c.OutputHistory < 0 {
+		errs = append(errs, fmt.Errorf("invalid output history %d, it can't be negative", c.OutputHistory))
+	}
+
+	if c.StateFile != "" && !inspectorSupported {
+		errs = append(errs, fmt.Errorf("a state file is not supported on %s", runtime.GOOS))
+	}
+
+	for _, g := range c.groups() {
+		if _, err := g.startOrder(); err != nil {
+			errs = append(errs, fmt.Errorf("%w%s", err, g.logSuffix()))
+		}
+	}
+
+	// logs maps each swap log file to the swap writing to it.
+	logs := map[string]string{}
+	// cgroups maps each swap cgroup to the swap running in it.
+	cgroups := map[string]string{}
+
+	for _, g := range c.groups() {
+		for _, p := range g.Priorities {
+			if err := p.discovery().validate(); err != nil {
+				errs = append(errs, fmt.Errorf("priority %s%s: %w", p.Path, g.logSuffix(), err))
+			}
+		}
+
+		if _, err := g.rules(); err != nil {
+			errs = append(errs, fmt.Errorf("%w%s", err, g.logSuffix()))
+		}
+
+		if g.Match != "" && !contains(matchModes, g.Match) {
+			errs = append(errs, fmt.Errorf("unknown match mode %s%s, use one of %s", g.Match, g.logSuffix(), strings.Join(matchModes, ", ")))
+		}
+
+		switch {
+		case (g.Match == MatchPath || g.Match == MatchCmdline) && !inspectorSupported:
+			errs = append(errs, fmt.Errorf("match mode %s%s is not supported on %s, use %s", g.Match, g.logSuffix(), runtime.GOOS, MatchName))
+		case g.Match == MatchCmdline && !cmdlineSupported:
+			errs = append(errs, fmt.Errorf("match mode %s%s is not supported on %s, use %s or %s",
+				g.Match, g.logSuffix(), runtime.GOOS, MatchName, MatchPath))
+		}
+
+		for _, sc := range g.Swaps {
+			if err := sc.Stop.strategy().validate(); err != nil {
+				errs = append(errs, fmt.Errorf("swap %s%s: %w", sc.key(), g.logSuffix(), err))
+			}
+
+			if err := sc.Restart.policy().validate(); err != nil {
+				errs = append(errs, fmt.Errorf("swap %s%s: %w", sc.key(), g.logSuffix(), err))
+			}
+
+			if err := sc.Health.probe().validate(); err != nil {
+				errs = append(errs, fmt.Errorf("swap %s%s: %w", sc.key(), g.logSuffix(), err))
+			}
+
+			if err := validateSwapMode(sc.Mode); err != nil {
+				errs = append(errs, fmt.Errorf("swap %s%s: %w", sc.key(), g.logSuffix(), err))
+			}
+
+			if err := sc.Throttle.throttle().validate(); err != nil {
+				errs = append(errs, fmt.Errorf("swap %s%s: %w", sc.key(), g.logSuffix(), err))
+			}
+
+			cgroup, err := sc.cgroup()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("swap %s%s: %w", sc.key(), g.logSuffix(), err))
+			}
+
+			if err := cgroup.validate(); err != nil {
+				errs = append(errs, fmt.Errorf("swap %s%s: %w", sc.key(), g.logSuffix(), err))
+			}
+
+			if cgroup.Path != "" {
+				if other, ok := cgroups[cgroup.Path]; ok {
+					errs = append(errs, fmt.Errorf("swaps %s and %s%s use the same cgroup %s, set a name for one of them",
+						other, sc.key(), g.logSuffix(), cgroup.Path))
+				} else {
+					cgroups[cgroup.Path] = sc.key()
+				}
+			}
+
+			if err := sc.startup().validate(); err != nil {
+				errs = append(errs, fmt.Errorf("swap %s%s: %w", sc.key(), g.logSuffix(), err))
+			}
+
+			if sc.Ready.Output != "" && sc.spool == os.DevNull {
+				errs = append(errs, fmt.Errorf("swap %s%s: ready output can't be matched when swaps are left running without a state file, as their output is discarded",
+					sc.key(), g.logSuffix()))
+			}
+
+			for _, ref := range sc.After {
+				switch len(g.swapsMatching(ref)) {
+				case 0:
+					errs = append(errs, fmt.Errorf("swap %s%s starts after %s, which is not a swap of the group", sc.key(), g.logSuffix(), ref))
+				case 1:
+				default:
+					errs = append(errs, fmt.Errorf("swap %s%s starts after %s, which matches more than one swap of the group", sc.key(), g.logSuffix(), ref))
+				}
+			}
+
+			if err := sc.outputLog().validate(); err != nil {
+				errs = append(errs, fmt.Errorf("swap %s%s: %w", sc.key(), g.logSuffix(), err))
+			}
+
+			if err := sc.runAs().validate(); err != nil {
+				errs = append(errs, fmt.Errorf("swap %s%s: %w", sc.key(), g.logSuffix(), err))
+			}
+
+			if path := pathKey(sc.outputLog().Path); path != "" {
+				if other, ok := logs[path]; ok {
+					errs = append(errs, fmt.Errorf("swaps %s and %s%s log to the same file %s, set a name or log for one of them",
+						other, sc.key(), g.logSuffix(), sc.outputLog().Path))
+				} else {
+					logs[path] = sc.key()
+				}
+			}
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	// Report every mistake at once, so they can all be fixed in one go.
+	messages := make([]string, 0, len(errs))
+	for _, err := range errs {
+		messages = append(messages, err.Error())
+	}
+
+	return errors.New(strings.Join(messages, "; "))
+}
+
+// swapFlag splits the value of a per-swap flag, <SWAP>=<VALUE>, and returns
+// every swap in the config that <SWAP> refers to along with the value.
+func (c *config) swapFlag(flag, value string) ([]*swapConfig, string, error) {
+	kv := strings.SplitN(value, "=", 2)
+	if len(kv) != 2 || kv[0] == "" {
+		return nil, "", fmt.Errorf("invalid --%s %s, expected <SWAP>=<VALUE>", flag, value)
+	}
+
+	swaps := []*swapConfig{}
+
+	for i := range c.Swaps {
+		if c.Swaps[i].matches(kv[0]) {
+			swaps = append(swaps, &c.Swaps[i])
+		}
+	}
+
+	for i := range c.Groups {
+		for j := range c.Groups[i].Swaps {
+			if c.Groups[i].Swaps[j].matches(kv[0]) {
+				swaps = append(swaps, &c.Groups[i].Swaps[j])
+			}
+		}
+	}
+
+	if len(swaps) == 0 {
+		return nil, "", fmt.Errorf("--%s %s does not refer to any swap", flag, value)
+	}
+
+	return swaps, kv[1], nil
+}
+
+// swapPaths returns the path of each swap in the group.
+func (g groupConfig) swapPaths() []string {
+	paths := make([]string, 0, len(g.Swaps))
+	for _, s := range g.Swaps {
+		paths = append(paths, s.Path)
+	}
+
+	return paths
+}
+
+// hasPriorities returns true if the group has any priority paths or rules.
+func (g groupConfig) hasPriorities() bool {
+	return len(g.Priorities) > 0 || len(g.PriorityGlobs) > 0 || len(g.PriorityRegexes) > 0
+}
+
+// rules returns the group's priority globs and regexes as rules.
+func (g groupConfig) rules() ([]Rule, error) {
+	rules := make([]Rule, 0, len(g.PriorityGlobs)+len(g.PriorityRegexes))
+
+	for _, pattern := range g.PriorityGlobs {
+		rule, err := NewGlobRule(pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		rules = append(rules, rule)
+	}
+
+	for _, pattern := range g.PriorityRegexes {
+		rule, err := NewRegexRule(pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// swapsMatching returns the swaps of the group that ref refers to, by their
+// name, path or file name.
+func (g groupConfig) swapsMatching(ref string) []swapConfig {
+	matching := []swapConfig{}
+
+	for _, s := range g.Swaps {
+		if s.matches(ref) {
+			matching = append(matching, s)
+		}
+	}
+
+	return matching
+}
+
+// swapNames replaces each reference to a swap of the group with the swap's
+// name. References to no swap, or to more than one, are kept as they are.
+func (g groupConfig) swapNames(refs []string) []string {
+	if refs == nil {
+		return nil
+	}
+
+	names := make([]string, 0, len(refs))
+
+	for _, ref := range refs {
+		if matching := g.swapsMatching(ref); len(matching) == 1 {
+			ref = matching[0].name()
+		}
+
+		names = append(names, ref)
+	}
+
+	return names
+}
+
+// startOrder returns the indexes of the group's swaps in the order they
+// start in, or an error if swaps start after each other.
+func (g groupConfig) startOrder() ([]int, error) {
+	names := make([]string, len(g.Swaps))
+	after := make([][]string, len(g.Swaps))
+
+	for i, s := range g.Swaps {
+		names[i] = s.name()
+		after[i] = g.swapNames(s.After)
+	}
+
+	return startOrder(names, after)
+}
+
+// logSuffix returns the text that goes at the end of setup log messages
+// about the group. Nothing is added for the default group.
+func (g groupConfig) logSuffix() string {
+	if g.Name == defaultGroupName {
+		return ""
+	}
+
+	return fmt.Sprintf(" for group %s", aurora.Bold(g.Name))
+}
+
+// UnmarshalYAML lets a priority be written as just its path.
+func (p *priorityConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if err := unmarshal(&p.Path); err == nil {
+		return nil
+	}
+	// Use a type without this method so it doesn't recurse.
+	type plain priorityConfig
+
+	return unmarshal((*plain)(p))
+}
+
+// UnmarshalTOML lets a priority be written as just its path. Keys are
+// checked here since the decoder does not report undecoded keys of a type
+// that decodes itself.
+func (p *priorityConfig) UnmarshalTOML(data interface{}) error {
+	switch v := data.(type) {
+	case string:
+		p.Path = v
+
+		return nil
+	case map[string]interface{}:
+		return p.decodeTOMLTable(v)
+	default:
+		return fmt.Errorf("a priority must be a path or a table, got %v", data)
+	}
+}
+
+func (p *priorityConfig) decodeTOMLTable(table map[string]interface{}) error {
+	keys := make([]string, 0, len(table))
+	for key := range table {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		var ok bool
+
+		switch key {
+		case "path":
+			p.Path, ok = table[key].(string)
+		case "discovery":
+			p.Discovery, ok = table[key].(string)
+		case "patterns":
+			p.Patterns, ok = toStrings(table[key])
+		default:
+			return fmt.Errorf("unknown key %s in priority", key)
+		}
+
+		if !ok {
+			return fmt.Errorf("invalid value for key %s in priority: %v", key, table[key])
+		}
+	}
+
+	return nil
+}
+
+// toStrings converts a decoded TOML array to a slice of strings.
+func toStrings(data interface{}) ([]string, bool) {
+	values, ok := data.([]interface{})
+	if !ok {
+		return nil, false
+	}
+
+	s := make([]string, 0, len(values))
+
+	for _, value := range values {
+		str, ok := value.(string)
+		if !ok {
+			return nil, false
+		}
+
+		s = append(s, str)
+	}
+
+	return s, true
+}
+
+// discovery returns how executables are found in the priority path.
+func (p priorityConfig) discovery() Discovery {
+	return Discovery{
+		Mode:     p.Discovery,
+		Patterns: p.Patterns,
+	}
+}
+
+// key returns what identifies the swap, its name if it has one or its path.
+func (s swapConfig) key() string {
+	if s.Name != "" {
+		return s.Name
+	}
+
+	return s.Path
+}
+
+// matches returns true if ref refers to the swap by its name, path or the
+// base name of its path, ignoring case.
+func (s swapConfig) matches(ref string) bool {
+	return contains([]string{s.Name, s.Path, filepath.Base(s.Path)}, ref)
+}
+
+// environ returns the swap's extra environment variables in the form
+// "KEY=value", sorted by key.
+func (s swapConfig) environ() []string {
+	env := make([]string, 0, len(s.Env))
+	for k, v := range s.Env {
+		env = append(env, k+"="+v)
+	}
+
+	sort.Strings(env)
+
+	return env
+}
+
+// newSwap creates the swap described by the config.
+func (s swapConfig) newSwap() Swap {
+	swap := NewSwap(s.Path)
+	swap.WithArgs(s.Args)
+	swap.WithDir(s.Dir)
+	swap.WithEnv(s.environ())
+	swap.WithStop(s.Stop.strategy())
+	swap.WithRestart(s.Restart.policy())
+	swap.WithHealth(s.Health.probe())
+	swap.WithOutputLog(s.outputLog())
+	swap.WithHistorySize(s.history)
+	swap.WithStartup(s.startup())
+	swap.WithName(s.name())
+	swap.WithMode(s.Mode)
+	swap.WithThrottle(s.Throttle.throttle())
+	// The cgroup was already checked when the config was validated.
+	cgroup, _ := s.cgroup()
+	swap.WithCgroup(cgroup)
+	swap.WithRunAs(s.runAs())
+	swap.WithSpool(s.spool)
+
+	return swap
+}
+
+// name returns the name the swap's output is labelled with and other swaps
+// refer to it by when they start after it.
+func (s swapConfig) name() string {
+	if s.Name != "" {
+		return s.Name
+	}
+
+	return filepath.Base(s.Path)
+}
+
+// startup returns when the swap starts relative to the other swaps of its group.
+func (s swapConfig) startup() Startup {
+	return Startup{
+		Delay: time.Duration(s.Delay) * time.Second,
+		After: s.After,
+		Ready: ReadyCondition{
+			TCP:     s.Ready.TCP,
+			Output:  s.Ready.Output,
+			Timeout: time.Duration(s.Ready.Timeout) * time.Second,
+		},
+	}
+}
+
+// outputLog returns the log file the swap's output is written to. Unless a
+// log is set for the swap it is named after the swap and lives in the swap
+// logs directory, if there is one.
+func (s swapConfig) outputLog() OutputLog {
+	path := s.Log
+	if path == "" && s.logs.Dir != "" {
+		name := s.Name
+		if name == "" {
+			name = strings.TrimSuffix(filepath.Base(s.Path), filepath.Ext(s.Path))
+		}
+
+		path = filepath.Join(s.logs.Dir, name+".log")
+	}
+
+	return OutputLog{
+		Path:       path,
+		MaxSize:    s.logs.MaxSize,
+		MaxAge:     s.logs.MaxAge,
+		MaxBackups: s.logs.MaxBackups,
+		Compress:   s.logs.Compress,
+	}
+}
+
+// probe returns the health probe described by the config.
+func (h healthConfig) probe() HealthProbe {
+	return HealthProbe{
+		HTTP:             h.HTTP,
+		TCP:              h.TCP,
+		Command:          h.Command,
+		Args:             h.Args,
+		InitialDelay:     time.Duration(h.InitialDelay) * time.Second,
+		Interval:         time.Duration(h.Interval) * time.Second,
+		Timeout:          time.Duration(h.Timeout) * time.Second,
+		FailureThreshold: h.FailureThreshold,
+	}
+}
+
+// policy returns the restart policy described by the config.
+func (r restartConfig) policy() RestartPolicy {
+	return RestartPolicy{
+		Policy:     r.Policy,
+		MaxRetries: r.MaxRetries,
+		Backoff:    time.Duration(r.Backoff) * time.Second,
+		MaxBackoff: time.Duration(r.MaxBackoff) * time.Second,
+	}
+}
+
+// cgroup returns the cgroup the swap runs in, made in the config's cgroup
+// slice if it has one.
+func (s swapConfig) cgroup() (Cgroup, error) {
+	memory, err := parseMemory(s.Cgroup.MemoryMax)
+	if err != nil {
+		return Cgroup{}, fmt.Errorf("cgroup memory max: %w", err)
+	}
+
+	return Cgroup{
+		Path:      cgroupPathFor(s.cgroupSlice, s.groupName, s.name()),
+		MemoryMax: memory,
+		CPUMax:    s.Cgroup.CPUMax,
+	}, nil
+}
+
+// runAs returns the user and group the swap runs as.
+func (s swapConfig) runAs() RunAs {
+	return RunAs{
+		User:  s.User,
+		Group: s.Group,
+	}
+}
+
+// throttle returns the throttle described by the config.
+func (t throttleConfig) throttle() Throttle {
+	return Throttle{
+		Nice:      t.Nice,
+		CPUs:      t.CPUs,
+		CPUWeight: t.CPUWeight,
+	}
+}
+
+// strategy returns the stop strategy described by the config.
+func (s stopConfig) strategy() StopStrategy {
+	strategy := StopStrategy{
+		Command: s.Command,
+		Args:    s.Args,
+		Timeout: time.Duration(s.Timeout) * time.Second,
+	}
+
+	for _, sig := range s.Signals {
+		strategy.Signals = append(strategy.Signals, StopSignal{
+			Signal:  sig.Signal,
+			Timeout: time.Duration(sig.Timeout) * time.Second,
+		})
+	}
+
+	return strategy
+}
@@ -0,0 +1,595 @@
+package procswap
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Config", func() {
+	var (
+		cfg  *config
+		dir  string
+		path string
+		err  error
+	)
+
+	BeforeEach(func() {
+		wd, _ := os.Getwd()
+		dir = filepath.FromSlash(wd + "/test/config")
+	})
+
+	Describe("#loadConfig", func() {
+		JustBeforeEach(func() {
+			cfg, err = loadConfig(path)
+		})
+
+		When("the file does not exist", func() {
+			BeforeEach(func() {
+				path = filepath.Join(dir, uuid.New().String()+".yml")
+			})
+
+			It("returns an error", func() {
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(HavePrefix("error reading config file"))
+			})
+		})
+
+		When("the file format is not supported", func() {
+			BeforeEach(func() {
+				path = filepath.Join(dir, "procswap.json")
+			})
+
+			It("returns an error", func() {
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(HavePrefix("unsupported config file format"))
+			})
+		})
+
+		When("a YAML file has unknown keys", func() {
+			BeforeEach(func() {
+				path = filepath.Join(dir, "unknown.yml")
+			})
+
+			It("returns an error", func() {
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(ContainSubstring("field swap not found"))
+			})
+		})
+
+		When("a TOML file has unknown keys", func() {
+			BeforeEach(func() {
+				path = filepath.Join(dir, "unknown.toml")
+			})
+
+			It("returns an error", func() {
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(ContainSubstring("unknown keys swap"))
+			})
+		})
+
+		When("it is a YAML file", func() {
+			BeforeEach(func() {
+				path = filepath.Join(dir, "procswap.yml")
+			})
+
+			It("succeeds", func() {
+				Expect(err).To(BeNil())
+				Expect(cfg.Priorities).To(Equal([]priorityConfig{{Path: filepath.Join(dir, "..", "priorities")}}))
+				Expect(cfg.Ignore).To(Equal([]string{"ignore_me.exe"}))
+				Expect(cfg.defaultGroup().swapPaths()).To(Equal([]string{filepath.Join(dir, "..", "swaps", "swap")}))
+				Expect(cfg.PriorityScript).To(Equal(filepath.Join(dir, "..", "scripts", "priority-script")))
+				Expect(cfg.PollInterval).To(Equal(1))
+				Expect(cfg.Limit).To(Equal(1))
+				Expect(cfg.DisableActions).To(BeTrue())
+			})
+		})
+
+		When("it has named groups", func() {
+			BeforeEach(func() {
+				path = filepath.Join(dir, "groups.yml")
+			})
+
+			It("succeeds", func() {
+				Expect(err).To(BeNil())
+				Expect(cfg.validate()).To(BeNil())
+				groups := cfg.groups()
+				Expect(groups).To(HaveLen(2))
+				Expect(groups[0].Name).To(Equal("cpu-miner"))
+				Expect(groups[0].Priorities).To(Equal([]priorityConfig{{Path: filepath.Join(dir, "..", "priorities", "test1.exe")}}))
+				Expect(groups[1].Name).To(Equal("gpu-miner"))
+				Expect(groups[1].Ignore).To(Equal([]string{"ignore_me.exe"}))
+				Expect(groups[1].PriorityScript).To(Equal(filepath.Join(dir, "..", "scripts", "priority-script")))
+				Expect(groups[1].swapPaths()).To(Equal([]string{filepath.Join(dir, "..", "swaps", "swap")}))
+			})
+		})
+
+		When("it is a TOML file with named groups", func() {
+			BeforeEach(func() {
+				path = filepath.Join(dir, "groups.toml")
+			})
+
+			It("succeeds", func() {
+				Expect(err).To(BeNil())
+				Expect(cfg.validate()).To(BeNil())
+				groups := cfg.groups()
+				Expect(groups).To(HaveLen(2))
+				Expect(groups[0].Name).To(Equal("cpu-miner"))
+				Expect(groups[1].Name).To(Equal("gpu-miner"))
+				Expect(groups[1].swapPaths()).To(Equal([]string{filepath.Join(dir, "..", "swaps", "swap")}))
+			})
+		})
+
+		When("swaps have arguments, a working directory and environment variables", func() {
+			BeforeEach(func() {
+				path = filepath.Join(dir, "swaps.yml")
+			})
+
+			It("succeeds", func() {
+				Expect(err).To(BeNil())
+				Expect(cfg.Swaps).To(HaveLen(1))
+				s := cfg.Swaps[0]
+				Expect(s.key()).To(Equal("phoenix"))
+				Expect(s.Args).To(Equal([]string{"-pool", "us1.ethermine.org:4444"}))
+				Expect(s.Dir).To(Equal(filepath.Join(dir, "..", "swaps")))
+				Expect(s.environ()).To(Equal([]string{"GPU_FORCE_64BIT_PTR=0", "GPU_MAX_HEAP_SIZE=100"}))
+				Expect(s.matches("PHOENIX")).To(BeTrue())
+				Expect(s.matches("swap")).To(BeTrue())
+				Expect(s.matches("miner")).To(BeFalse())
+				Expect(s.Stop.strategy()).To(Equal(StopStrategy{
+					Command: filepath.Join(dir, "..", "scripts", "stop"),
+					Timeout: 5 * time.Second,
+					Signals: []StopSignal{
+						{Signal: "SIGINT", Timeout: 10 * time.Second},
+						{Signal: "SIGTERM"},
+					},
+				}))
+				Expect(cfg.groups()[0].Swaps[0].outputLog()).To(Equal(OutputLog{
+					Path:       filepath.Join(dir, "..", "logs", "phoenix.log"),
+					MaxSize:    10,
+					MaxBackups: 5,
+					Compress:   true,
+				}))
+				Expect(s.Health.probe()).To(Equal(HealthProbe{
+					HTTP:             "http://127.0.0.1:4067/health",
+					Interval:         15 * time.Second,
+					FailureThreshold: 4,
+				}))
+				Expect(s.Restart.policy()).To(Equal(RestartPolicy{
+					Policy:     RestartOnFailure,
+					MaxRetries: 3,
+					Backoff:    2 * time.Second,
+				}))
+			})
+		})
+
+		When("priorities set how executables are discovered", func() {
+			BeforeEach(func() {
+				path = filepath.Join(dir, "discovery.yml")
+			})
+
+			It("succeeds", func() {
+				Expect(err).To(BeNil())
+				Expect(cfg.validate()).To(BeNil())
+				Expect(cfg.Priorities).To(Equal([]priorityConfig{
+					{Path: filepath.Join(dir, "..", "priorities")},
+					{Path: filepath.Join(dir, "..", "swaps"), Discovery: DiscoveryHeader},
+					{Path: filepath.Join(dir, "..", "scripts"), Discovery: DiscoveryGlob, Patterns: []string{"priority-*"}},
+				}))
+			})
+		})
+
+		When("a TOML file's priorities set how executables are discovered", func() {
+			BeforeEach(func() {
+				path = filepath.Join(dir, "discovery.toml")
+			})
+
+			It("succeeds", func() {
+				Expect(err).To(BeNil())
+				Expect(cfg.Priorities).To(Equal([]priorityConfig{
+					{Path: filepath.Join(dir, "..", "priorities")},
+					{Path: filepath.Join(dir, "..", "swaps"), Discovery: DiscoveryHeader},
+					{Path: filepath.Join(dir, "..", "scripts"), Discovery: DiscoveryGlob, Patterns: []string{"priority-*"}},
+				}))
+			})
+		})
+
+		When("a TOML file's priority has unknown keys", func() {
+			BeforeEach(func() {
+				path = filepath.Join(dir, "unknown_priority.toml")
+			})
+
+			It("returns an error", func() {
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(ContainSubstring("unknown key discover in priority"))
+			})
+		})
+
+		When("it is a TOML file", func() {
+			BeforeEach(func() {
+				path = filepath.Join(dir, "procswap.toml")
+			})
+
+			It("succeeds", func() {
+				Expect(err).To(BeNil())
+				Expect(cfg.Priorities).To(Equal([]priorityConfig{{Path: filepath.Join(dir, "..", "priorities")}}))
+				Expect(cfg.Ignore).To(Equal([]string{"ignore_me.exe"}))
+				Expect(cfg.defaultGroup().swapPaths()).To(Equal([]string{filepath.Join(dir, "..", "swaps", "swap")}))
+				Expect(cfg.PriorityScript).To(Equal(filepath.Join(dir, "..", "scripts", "priority-script")))
+				Expect(cfg.PollInterval).To(Equal(1))
+				Expect(cfg.Limit).To(Equal(1))
+				Expect(cfg.DisableActions).To(BeTrue())
+			})
+		})
+	})
+
+	Describe("#validate", func() {
+		BeforeEach(func() {
+			cfg = &config{
+				Groups: []groupConfig{
+					{
+						Name:       "cpu-miner",
+						Priorities: []priorityConfig{{Path: "priorities"}},
+						Swaps:      []swapConfig{{Path: "swap"}},
+					},
+				},
+			}
+		})
+
+		JustBeforeEach(func() {
+			err = cfg.validate()
+		})
+
+		When("there are no groups and no swaps", func() {
+			BeforeEach(func() {
+				cfg = &config{Priorities: []priorityConfig{{Path: "priorities"}}}
+			})
+
+			It("returns an error", func() {
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(Equal("no swaps set, pass --swap or set swaps in the config file"))
+			})
+		})
+
+		When("the default group has swaps but no priorities", func() {
+			BeforeEach(func() {
+				cfg.Swaps = []swapConfig{{Path: "swap"}}
+			})
+
+			It("returns an error", func() {
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(Equal("no priorities set, pass --priority, --priority-glob or --priority-regex or set priorities in the config file"))
+			})
+		})
+
+		When("a priority has an unknown discovery mode", func() {
+			BeforeEach(func() {
+				cfg.Groups[0].Priorities[0].Discovery = "magic"
+			})
+
+			It("returns an error", func() {
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(HavePrefix("priority priorities for group"))
+				Expect(err.Error()).To(HaveSuffix("unknown discovery mode magic, use one of exe, glob, regex, executable, header"))
+			})
+		})
+
+		When("a group only has priority rules", func() {
+			BeforeEach(func() {
+				cfg.Groups[0].Priorities = nil
+				cfg.Groups[0].PriorityGlobs = []string{"*-Win64-Shipping.exe"}
+				cfg.Groups[0].PriorityRegexes = []string{"^eldenring"}
+			})
+
+			It("succeeds", func() {
+				Expect(err).To(BeNil())
+			})
+		})
+
+		When("a priority rule is invalid", func() {
+			BeforeEach(func() {
+				cfg.Groups[0].PriorityRegexes = []string{"("}
+			})
+
+			It("returns an error", func() {
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(HavePrefix("invalid priority regex ("))
+				Expect(err.Error()).To(ContainSubstring("for group"))
+			})
+		})
+
+		When("a group has an unknown match mode", func() {
+			BeforeEach(func() {
+				cfg.Groups[0].Match = "inode"
+			})
+
+			It("returns an error", func() {
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(HavePrefix("unknown match mode inode for group"))
+				Expect(err.Error()).To(HaveSuffix("use one of name, path, cmdline"))
+			})
+		})
+
+		When("a group matches by path where processes can't be inspected", func() {
+			BeforeEach(func() {
+				if inspectorSupported {
+					Skip("processes can be inspected on " + runtime.GOOS)
+				}
+
+				cfg.Groups[0].Match = MatchPath
+				cfg.StateFile = "procswap.state"
+			})
+
+			It("returns an error", func() {
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(ContainSubstring("a state file is not supported on " + runtime.GOOS))
+				Expect(err.Error()).To(ContainSubstring("match mode path for group"))
+				Expect(err.Error()).To(ContainSubstring("is not supported on " + runtime.GOOS + ", use name"))
+			})
+		})
+
+		When("a swap waits for output that is discarded", func() {
+			BeforeEach(func() {
+				cfg.LeaveSwapsRunning = true
+				cfg.Groups[0].Swaps[0].Ready.Output = "listening"
+			})
+
+			It("returns an error", func() {
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(HavePrefix("swap swap for group"))
+				Expect(err.Error()).To(ContainSubstring("ready output can't be matched when swaps are left running without a state file"))
+			})
+
+			When("a state file is set", func() {
+				BeforeEach(func() {
+					if !inspectorSupported {
+						Skip("a state file is not supported on " + runtime.GOOS)
+					}
+
+					cfg.StateFile = "procswap.state"
+				})
+
+				It("succeeds", func() {
+					Expect(err).To(BeNil())
+				})
+			})
+		})
+
+		When("a group matches by command line where command lines can't be read", func() {
+			BeforeEach(func() {
+				if cmdlineSupported {
+					Skip("command lines can be read on " + runtime.GOOS)
+				}
+
+				cfg.Groups[0].Match = MatchCmdline
+			})
+
+			It("returns an error", func() {
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(ContainSubstring("match mode cmdline for group"))
+				Expect(err.Error()).To(ContainSubstring("is not supported on " + runtime.GOOS))
+			})
+		})
+
+		When("a swap has a group to run as without a user", func() {
+			BeforeEach(func() {
+				cfg.Groups[0].Swaps[0].Group = "video"
+			})
+
+			It("returns an error", func() {
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(HavePrefix("swap swap for group"))
+				Expect(err.Error()).To(ContainSubstring("a group to run as needs a user to run as too"))
+			})
+		})
+
+		When("a swap has an unknown stop signal", func() {
+			BeforeEach(func() {
+				cfg.Groups[0].Swaps[0].Stop.Signals = []stopSignalConfig{{Signal: "SIGNOPE"}}
+			})
+
+			It("returns an error", func() {
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(HavePrefix("swap swap for group"))
+				Expect(err.Error()).To(ContainSubstring("unknown stop signal SIGNOPE, use one of SIGHUP, SIGINT, SIGKILL, SIGQUIT, SIGTERM"))
+			})
+		})
+
+		When("a swap has an unknown restart policy", func() {
+			BeforeEach(func() {
+				cfg.Groups[0].Swaps[0].Restart.Policy = "sometimes"
+			})
+
+			It("returns an error", func() {
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(HavePrefix("swap swap for group"))
+				Expect(err.Error()).To(HaveSuffix("unknown restart policy sometimes, use one of never, on-failure, always"))
+			})
+		})
+
+		When("a swap health probe sets more than one check", func() {
+			BeforeEach(func() {
+				cfg.Groups[0].Swaps[0].Health = healthConfig{HTTP: "http://127.0.0.1:4067", TCP: "127.0.0.1:3333"}
+			})
+
+			It("returns an error", func() {
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(HavePrefix("swap swap for group"))
+				Expect(err.Error()).To(HaveSuffix("health probe can only set one of http, tcp or command"))
+			})
+		})
+
+		When("two swaps log to the same file", func() {
+			BeforeEach(func() {
+				cfg.SwapLogs.Dir = "logs"
+				cfg.Groups[0].Swaps = append(cfg.Groups[0].Swaps, swapConfig{Path: filepath.Join("other", "swap.exe")})
+			})
+
+			It("returns an error", func() {
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(HavePrefix("swaps swap and " + filepath.Join("other", "swap.exe") + " for group"))
+				Expect(err.Error()).To(HaveSuffix("log to the same file " + filepath.Join("logs", "swap.log") + ", set a name or log for one of them"))
+			})
+		})
+
+		When("a swap starts after a swap that is not in its group", func() {
+			BeforeEach(func() {
+				cfg.Groups[0].Swaps[0].After = []string{"proxy"}
+			})
+
+			It("returns an error", func() {
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(HavePrefix("swap swap for group"))
+				Expect(err.Error()).To(HaveSuffix("starts after proxy, which is not a swap of the group"))
+			})
+		})
+
+		When("swaps start after each other", func() {
+			BeforeEach(func() {
+				cfg.Groups[0].Swaps = []swapConfig{
+					{Path: "swap", After: []string{"proxy"}},
+					{Path: "proxy", After: []string{"swap"}},
+				}
+			})
+
+			It("returns an error", func() {
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(HavePrefix("swaps swap, proxy start after each other for group"))
+			})
+		})
+
+		When("a swap has an unknown mode", func() {
+			BeforeEach(func() {
+				cfg.Groups[0].Swaps[0].Mode = "hibernate"
+			})
+
+			It("returns an error", func() {
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(HavePrefix("swap swap for group"))
+				Expect(err.Error()).To(HaveSuffix("unknown swap mode hibernate, use one of stop, pause, throttle"))
+			})
+		})
+
+		When("a swap has a throttle out of range", func() {
+			BeforeEach(func() {
+				cfg.Groups[0].Swaps[0].Throttle.Nice = 20
+			})
+
+			It("returns an error", func() {
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(HavePrefix("swap swap for group"))
+				Expect(err.Error()).To(HaveSuffix("throttle nice must be between 1 and 19"))
+			})
+		})
+
+		When("a swap has an invalid cgroup memory max", func() {
+			BeforeEach(func() {
+				cfg.CgroupSlice = "procswap.slice"
+				cfg.Groups[0].Swaps[0].Cgroup.MemoryMax = "2X"
+			})
+
+			It("returns an error", func() {
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(HavePrefix("swap swap for group"))
+				Expect(err.Error()).To(HaveSuffix("cgroup memory max: invalid memory size 2X, use a number of bytes or one ending in K, M, G or T"))
+			})
+		})
+
+		When("a swap has cgroup limits without a cgroup slice", func() {
+			BeforeEach(func() {
+				cfg.Groups[0].Swaps[0].Cgroup.MemoryMax = "512M"
+			})
+
+			It("returns an error", func() {
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(HaveSuffix("cgroup limits need a cgroup slice to make the swap's cgroup in"))
+			})
+		})
+
+		When("two swaps of a group would use the same cgroup", func() {
+			BeforeEach(func() {
+				cfg.CgroupSlice = "procswap.slice"
+				cfg.Groups[0].Swaps = []swapConfig{
+					{Path: "a/swap"},
+					{Path: "b/swap"},
+				}
+			})
+
+			It("returns an error", func() {
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(HavePrefix("swaps a/swap and b/swap for group"))
+				Expect(err.Error()).To(HaveSuffix("use the same cgroup procswap.slice/cpu-miner/swap, set a name for one of them"))
+			})
+		})
+
+		When("a group has no name", func() {
+			BeforeEach(func() {
+				cfg.Groups[0].Name = ""
+			})
+
+			It("returns an error", func() {
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(Equal("every group must have a name"))
+			})
+		})
+
+		When("a group is named default", func() {
+			BeforeEach(func() {
+				cfg.Groups[0].Name = "default"
+			})
+
+			It("returns an error", func() {
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(Equal("group name default is reserved"))
+			})
+		})
+
+		When("a group is defined more than once", func() {
+			BeforeEach(func() {
+				cfg.Groups = append(cfg.Groups, cfg.Groups[0])
+			})
+
+			It("returns an error", func() {
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(Equal("group cpu-miner is defined more than once"))
+			})
+		})
+
+		When("a group has no swaps", func() {
+			BeforeEach(func() {
+				cfg.Groups[0].Swaps = nil
+			})
+
+			It("returns an error", func() {
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(Equal("group cpu-miner has no swaps"))
+			})
+		})
+
+		When("there is more than one mistake", func() {
+			BeforeEach(func() {
+				cfg.Groups[0].Match = "inode"
+				cfg.Groups[0].Swaps[0].Stop.Signals = []stopSignalConfig{{Signal: "SIGNOPE"}}
+			})
+
+			It("returns every mistake in one error", func() {
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(HavePrefix("unknown match mode inode for group"))
+				Expect(err.Error()).To(ContainSubstring("; swap swap for group"))
+				Expect(err.Error()).To(ContainSubstring("unknown stop signal SIGNOPE"))
+			})
+		})
+
+		When("it succeeds", func() {
+			It("succeeds", func() {
+				Expect(err).To(BeNil())
+				Expect(cfg.groups()).To(HaveLen(1))
+			})
+		})
+	})
+})
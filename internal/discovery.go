@@ -0,0 +1,206 @@
+package procswap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// Discovery modes decide which files in a priority directory are executables.
+const (
+	// DiscoveryExe finds files that end in ".exe". This is the default.
+	DiscoveryExe = "exe"
+	// DiscoveryGlob finds files whose name matches any of the glob patterns.
+	DiscoveryGlob = "glob"
+	// DiscoveryRegex finds files whose name matches any of the regular expressions.
+	DiscoveryRegex = "regex"
+	// DiscoveryExecutable finds files that are executable: files with an
+	// executable bit set, or on Windows files with an extension in PATHEXT.
+	DiscoveryExecutable = "executable"
+	// DiscoveryHeader finds ELF and PE binaries by their header, no matter their name.
+	DiscoveryHeader = "header"
+)
+
+// defaultPathExt is used to find out if a file is executable on Windows when
+// the PATHEXT environment variable is not set.
+const defaultPathExt = ".com;.exe;.bat;.cmd"
+
+var (
+	// discoveryModes is every discovery mode.
+	discoveryModes = []string{DiscoveryExe, DiscoveryGlob, DiscoveryRegex, DiscoveryExecutable, DiscoveryHeader}
+	// exeRegex matches file names that end in ".exe".
+	exeRegex = regexp.MustCompile(`(?i)^.*\.exe$`)
+	// elfMagic is the magic number at the start of an ELF binary.
+	elfMagic = []byte{0x7f, 'E', 'L', 'F'}
+	// peMagic is the magic number at the start of a PE binary (its DOS header).
+	peMagic = []byte{'M', 'Z'}
+	// peSignature is the signature the DOS header of a PE binary points to.
+	peSignature = []byte{'P', 'E', 0, 0}
+)
+
+// Discovery decides which files in a priority directory are executables.
+// The zero value finds files that end in ".exe".
+type Discovery struct {
+	// Mode is one of the discovery modes, it defaults to DiscoveryExe.
+	Mode string
+	// Patterns are the glob patterns or regular expressions file names are
+	// matched against when the mode is DiscoveryGlob or DiscoveryRegex.
+	// Names are matched ignoring case.
+	Patterns []string
+}
+
+// String returns the discovery mode with its patterns.
+func (d Discovery) String() string {
+	mode := d.Mode
+	if mode == "" {
+		mode = DiscoveryExe
+	}
+
+	if len(d.Patterns) == 0 {
+		return mode
+	}
+
+	return fmt.Sprintf("%s %s", mode, strings.Join(d.Patterns, ", "))
+}
+
+// matcher returns a func that reports if the file at path, named name, is
+// an executable.
+func (d Discovery) matcher() (func(path, name string) bool, error) {
+	switch d.Mode {
+	case "", DiscoveryExe:
+		return func(path, name string) bool {
+			return exeRegex.MatchString(name)
+		}, nil
+	case DiscoveryGlob:
+		if len(d.Patterns) == 0 {
+			return nil, fmt.Errorf("discovery mode %s needs at least one pattern", d.Mode)
+		}
+
+		patterns := make([]string, 0, len(d.Patterns))
+
+		for _, p := range d.Patterns {
+			p = strings.ToLower(p)
+			if err := validPattern(p); err != nil {
+				return nil, fmt.Errorf("invalid glob pattern %s: %w", p, err)
+			}
+
+			patterns = append(patterns, p)
+		}
+
+		return func(path, name string) bool {
+			name = strings.ToLower(name)
+			for _, p := range patterns {
+				if ok, _ := filepath.Match(p, name); ok {
+					return true
+				}
+			}
+
+			return false
+		}, nil
+	case DiscoveryRegex:
+		if len(d.Patterns) == 0 {
+			return nil, fmt.Errorf("discovery mode %s needs at least one pattern", d.Mode)
+		}
+
+		regexes := make([]*regexp.Regexp, 0, len(d.Patterns))
+
+		for _, p := range d.Patterns {
+			r, err := regexp.Compile("(?i)" + p)
+			if err != nil {
+				return nil, fmt.Errorf("invalid regular expression %s: %w", p, err)
+			}
+
+			regexes = append(regexes, r)
+		}
+
+		return func(path, name string) bool {
+			for _, r := range regexes {
+				if r.MatchString(name) {
+					return true
+				}
+			}
+
+			return false
+		}, nil
+	case DiscoveryExecutable:
+		return isExecutable, nil
+	case DiscoveryHeader:
+		return func(path, name string) bool {
+			return hasExecutableHeader(path)
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown discovery mode %s, use one of %s", d.Mode, strings.Join(discoveryModes, ", "))
+	}
+}
+
+// validate returns an error if the discovery mode is unknown or any of its
+// patterns are invalid.
+func (d Discovery) validate() error {
+	_, err := d.matcher()
+
+	return err
+}
+
+// isExecutable returns true if the file at path is a regular file with an
+// executable bit set. On Windows it returns true if the file's extension is
+// in PATHEXT instead.
+func isExecutable(path, name string) bool {
+	if runtime.GOOS == "windows" {
+		pathExt := os.Getenv("PATHEXT")
+		if pathExt == "" {
+			pathExt = defaultPathExt
+		}
+
+		return contains(strings.Split(pathExt, ";"), filepath.Ext(name))
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+
+	return info.Mode().IsRegular() && info.Mode()&0111 != 0
+}
+
+// hasExecutableHeader returns true if the file at path starts with an ELF
+// or PE header.
+func hasExecutableHeader(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	header := make([]byte, 64)
+
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return false
+	}
+
+	header = header[:n]
+
+	if bytes.HasPrefix(header, elfMagic) {
+		return true
+	}
+
+	// A DOS header is 64 bytes, the offset of the PE signature is at 0x3c.
+	if !bytes.HasPrefix(header, peMagic) || len(header) < 64 {
+		return false
+	}
+
+	offset := int64(binary.LittleEndian.Uint32(header[0x3c:]))
+	signature := make([]byte, len(peSignature))
+
+	if _, err := f.ReadAt(signature, offset); err != nil {
+		return false
+	}
+
+	return bytes.Equal(signature, peSignature)
+}
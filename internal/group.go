@@ -0,0 +1,966 @@
+package procswap
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/karrick/godirwalk"
+	"github.com/logrusorgru/aurora"
+)
+
+// defaultGroupName is the name of the group that the loop's priorities,
+// priority script and swaps belong to when they are not part of a named group.
+const defaultGroupName = "default"
+
+// Match modes decide how running processes are matched against a group's
+// priority executables.
+const (
+	// MatchName matches processes by the name of their executable. This is the default.
+	MatchName = "name"
+	// MatchPath matches processes by the full path of their executable.
+	MatchPath = "path"
+	// MatchCmdline matches processes by the full path of their executable, or
+	// if their command line contains the path of a priority.
+	MatchCmdline = "cmdline"
+)
+
+// matchModes is every match mode.
+var matchModes = []string{MatchName, MatchPath, MatchCmdline}
+
+// Group is a named set of priorities and the swaps they take priority over.
+// Groups run independently of each other, a running priority only stops the
+// swaps of its own group.
+type Group struct {
+	// Name identifies the group.
+	Name string
+	// Priorities is the list of priority executables of the group.
+	Priorities []*godirwalk.Dirent
+	// PriorityPaths are the full paths of the priority executables, they are
+	// used instead of their names when matching by path or command line.
+	PriorityPaths []string
+	// Match is the match mode, it defaults to MatchName.
+	Match string
+	// Rules are priorities declared as patterns, matched against the names of
+	// running processes.
+	Rules []Rule
+	// PriorityScript is a script that will run when any priority of the group starts.
+	PriorityScript string
+	// Swaps is the list of swap processes of the group.
+	Swaps []Swap
+}
+
+// group holds a Group along with its state in the loop.
+type group struct {
+	Group
+	// dryRun logs what the group would do without starting or stopping anything.
+	dryRun bool
+	// if the swap scripts have been started or not.
+	started bool
+	// list of currently running swaps.
+	runningSwaps []Swap
+	// list of swaps paused or throttled while priorities run, in the order
+	// they started in.
+	suspendedSwaps []Swap
+	// mu is the loop's lock, watchers hold it while they restart swaps.
+	mu *sync.Mutex
+	// generations counts how many times each swap has been started by the
+	// loop, so a watcher knows when its swap has been started again without it.
+	generations map[Swap]int
+	// startups counts how many times the group's swaps have been started in
+	// the background, so a startup knows when it has been cancelled.
+	startups int
+	// restarting holds the swaps a failed health probe is stopping, so
+	// nothing else starts them until they have stopped.
+	restarting map[Swap]bool
+}
+
+func newGroup(g Group, mu *sync.Mutex) *group {
+	return &group{
+		Group:          g,
+		runningSwaps:   []Swap{},
+		suspendedSwaps: []Swap{},
+		mu:             mu,
+		generations:    map[Swap]int{},
+		restarting:     map[Swap]bool{},
+	}
+}
+
+// prefix returns the text that goes in front of log messages about the group.
+// Nothing is added for the default group so a loop without named groups logs
+// as it always has.
+func (g *group) prefix() string {
+	if g.Name == defaultGroupName {
+		return ""
+	}
+
+	return fmt.Sprintf("(%s) ", g.Name)
+}
+
+// run swaps running processes for priority executables or starts the swap
+// processes if no priority process of the group is running and they have not
+// already been started.
+//
+// Stopping swaps and running the priority script block, so when priorities
+// start run only decides what to do and returns the work, which the caller
+// does once it has released the loop's lock. It returns nil otherwise.
+func (g *group) run(table *processTable, first bool) func() {
+	g.reconcile()
+	// List running priorities from the current processes running.
+	runningPriorities := g.listRunningPriorities(table)
+
+	switch {
+	case len(runningPriorities) > 0 && !g.started && first:
+		// It is our first loop and priority processes are already running so log this.
+		logWarn(fmt.Sprintf("%snot starting swap processes, priority processes already running: %s",
+			g.prefix(), aurora.Bold(strings.Join(runningPriorities, ", "))))
+	case len(runningPriorities) > 0 && g.started:
+		// Do this if there are any priorities started and we need to stop all running swap processes.
+		logInfo(fmt.Sprintf("%s %s%s", aurora.Yellow("priority"), g.prefix(), aurora.Bold(strings.Join(runningPriorities, ", "))))
+
+		// It might make sense to set swap scripts to either started or not inside their functions,
+		// but I think ths is more explicit.
+		g.stop()
+		stopping := g.suspendSwaps()
+		detached := g.detach()
+
+		return func() {
+			for _, s := range stopping {
+				detached.stopSwap(s)
+			}
+
+			detached.startPriorityScript()
+		}
+	case len(runningPriorities) == 0 && !g.started:
+		// Do this when there are no priorities started and we need to start all the swap processes.
+		g.start()
+		g.startSwaps()
+	}
+
+	return nil
+}
+
+// listRunningPriorities takes in the table of currently running
+// processes and makes a list of any of the group's priorities that are
+// running.
+func (g *group) listRunningPriorities(table *processTable) []string {
+	prioritiesMap := map[string]bool{}
+	// Check if an executable has started that we want to take priority over
+	// our swap processes.
+	switch g.Match {
+	case MatchPath, MatchCmdline:
+		for _, path := range g.listRunningPriorityPaths(table) {
+			prioritiesMap[path] = true
+		}
+	default:
+		names := table.allNames()
+		for _, priority := range g.Priorities {
+			if names[priority.Name()] {
+				prioritiesMap[priority.Name()] = true
+			}
+		}
+	}
+	// Check if any running process matches a priority rule.
+	if len(g.Rules) > 0 {
+		for name := range table.allNames() {
+			for _, rule := range g.Rules {
+				if rule.Match(name) {
+					prioritiesMap[name] = true
+
+					break
+				}
+			}
+		}
+	}
+
+	// Generate a slice of currently running priorities.
+	priorities := make([]string, 0, len(prioritiesMap))
+	for k := range prioritiesMap {
+		priorities = append(priorities, k)
+	}
+
+	sort.Strings(priorities)
+
+	return priorities
+}
+
+// listRunningPriorityPaths returns the paths of the group's priorities that
+// are running, matching processes by the full path of their executable or,
+// when matching by command line, by the priority paths their command line
+// contains.
+func (g *group) listRunningPriorityPaths(table *processTable) []string {
+	if len(g.PriorityPaths) == 0 {
+		return nil
+	}
+
+	paths := map[string]string{}
+	for _, path := range g.PriorityPaths {
+		paths[pathKey(path)] = path
+	}
+
+	running := []string{}
+
+	for _, process := range table.processes {
+		if path, ok := paths[pathKey(table.path(process))]; ok {
+			running = append(running, path)
+
+			continue
+		}
+
+		if g.Match != MatchCmdline {
+			continue
+		}
+
+		cmdline := foldCase(table.cmdline(process))
+		if cmdline == "" {
+			continue
+		}
+
+		for key, path := range paths {
+			if strings.Contains(cmdline, key) {
+				running = append(running, path)
+			}
+		}
+	}
+
+	return running
+}
+
+// pathKey returns path in the form used to compare paths, which ignores case
+// on Windows.
+func pathKey(path string) string {
+	if path == "" {
+		return ""
+	}
+
+	return foldCase(filepath.Clean(path))
+}
+
+// foldCase lowercases s on Windows, where paths are not case sensitive.
+func foldCase(s string) string {
+	if runtime.GOOS == "windows" {
+		return strings.ToLower(s)
+	}
+
+	return s
+}
+
+// detach returns a copy of the group with what stopping swaps and running
+// the priority script need, which can be used without the loop's lock while
+// a reload changes the group.
+func (g *group) detach() *group {
+	return &group{
+		Group:  Group{Name: g.Name, PriorityScript: g.PriorityScript},
+		dryRun: g.dryRun,
+	}
+}
+
+func (g *group) stop() {
+	g.started = false
+}
+
+func (g *group) start() {
+	g.started = true
+}
+
+// reload replaces the group's definition. Swaps are compared by identity:
+// running and suspended swaps that are no longer passed in are stopped, and if
+// swaps are currently started any new swaps are started. Swaps that are in
+// both the old and new definition are left untouched.
+//
+// Stopping swaps blocks, so reload returns the work of stopping the swaps
+// that were removed for the caller to do once it has released the loop's
+// lock, along with starting the new swaps after them, so a swap and the one
+// replacing it never run at the same time. It returns nil if no swap has to
+// be stopped.
+func (g *group) reload(def Group) func() {
+	runningSwaps := []Swap{}
+	stopping := []Swap{}
+
+	for _, swap := range g.runningSwaps {
+		if containsSwap(def.Swaps, swap) {
+			runningSwaps = append(runningSwaps, swap)
+
+			continue
+		}
+
+		stopping = append(stopping, swap)
+	}
+
+	suspendedSwaps := []Swap{}
+	stoppingSuspended := []Swap{}
+
+	for _, swap := range g.suspendedSwaps {
+		if containsSwap(def.Swaps, swap) {
+			suspendedSwaps = append(suspendedSwaps, swap)
+
+			continue
+		}
+
+		stoppingSuspended = append(stoppingSuspended, swap)
+	}
+
+	g.runningSwaps = runningSwaps
+	g.suspendedSwaps = suspendedSwaps
+	g.Group = def
+
+	if len(stopping) == 0 && len(stoppingSuspended) == 0 {
+		g.launchNew()
+
+		return nil
+	}
+
+	detached := g.detach()
+
+	return func() {
+		for _, swap := range stopping {
+			detached.stopSwap(swap)
+		}
+
+		for _, swap := range stoppingSuspended {
+			detached.stopSuspendedSwap(swap)
+		}
+
+		g.mu.Lock()
+		defer g.mu.Unlock()
+
+		g.launchNew()
+	}
+}
+
+// launchNew starts the swaps that are not running yet. Only the new swaps are
+// started if no priorities were running on the last poll, otherwise they'll
+// be started when all priorities stop. This also starts any swap a startup
+// that is still in progress had yet to start.
+func (g *group) launchNew() {
+	if !g.started {
+		return
+	}
+
+	g.launch(g.notRunning())
+}
+
+// notRunning returns the group's swaps that are not running, leaving out
+// the swaps being restarted.
+func (g *group) notRunning() []Swap {
+	swaps := []Swap{}
+
+	for _, swap := range g.Swaps {
+		if !containsSwap(g.runningSwaps, swap) && !g.restarting[swap] {
+			swaps = append(swaps, swap)
+		}
+	}
+
+	return swaps
+}
+
+// containsSwap returns true if swaps contains swap.
+func containsSwap(swaps []Swap, swap Swap) bool {
+	for _, s := range swaps {
+		if s == swap {
+			return true
+		}
+	}
+
+	return false
+}
+
+// startSwaps resumes the suspended swaps, then starts every other swap.
+func (g *group) startSwaps() {
+	g.resumeSwaps()
+	g.launch(g.notRunning())
+}
+
+// resumeSwaps resumes the paused swaps and unthrottles the throttled swaps,
+// in the order they started in. A swap that can't be resumed or unthrottled
+// is stopped, so it is started from scratch instead.
+func (g *group) resumeSwaps() {
+	for _, s := range g.suspendedSwaps {
+		action, doing, resume := "resume", "resuming", s.Resume
+		if s.Mode() == SwapModeThrottle {
+			action, doing, resume = "unthrottle", "unthrottling", s.Unthrottle
+		}
+
+		if g.dryRun {
+			logInfo(fmt.Sprintf("%s would %s %s%s", aurora.Blue("dry-run"), aurora.Green(action), g.prefix(), aurora.Bold(s.Path())))
+
+			g.runningSwaps = append(g.runningSwaps, s)
+
+			continue
+		}
+
+		logInfo(fmt.Sprintf("%s %s%s...", aurora.Green(action), g.prefix(), aurora.Bold(s.Path())), false)
+
+		if err := resume(); err != nil {
+			logFailed()
+			logError(fmt.Sprintf("error %s swap process %s: %s", doing, s.Path(), err.Error()))
+			g.stopSwap(s)
+
+			continue
+		}
+
+		logOK()
+
+		g.runningSwaps = append(g.runningSwaps, s)
+	}
+
+	g.suspendedSwaps = []Swap{}
+}
+
+// suspendSwaps pauses the running swaps in pause mode and throttles the ones
+// in throttle mode, in reverse of the order they started in. It returns the
+// rest, along with any swap that can't be paused or throttled, in the order
+// they are to be stopped in; stopping them is up to the caller.
+func (g *group) suspendSwaps() []Swap {
+	// Cancel any startup still in progress.
+	g.startups++
+
+	suspendedSwaps := []Swap{}
+	stopping := []Swap{}
+
+	for i := len(g.runningSwaps) - 1; i >= 0; i-- {
+		s := g.runningSwaps[i]
+
+		if (s.Mode() == SwapModePause || s.Mode() == SwapModeThrottle) && g.suspendSwap(s) {
+			suspendedSwaps = append([]Swap{s}, suspendedSwaps...)
+
+			continue
+		}
+
+		stopping = append(stopping, s)
+	}
+
+	g.runningSwaps = []Swap{}
+	g.suspendedSwaps = append(g.suspendedSwaps, suspendedSwaps...)
+
+	return stopping
+}
+
+// suspendSwap pauses or throttles a single swap process, depending on its
+// mode, returning true if it was suspended.
+func (g *group) suspendSwap(s Swap) bool {
+	action, doing, suspend := "pause", "pausing", s.Pause
+	if s.Mode() == SwapModeThrottle {
+		action, doing, suspend = "throttle", "throttling", s.Throttle
+	}
+
+	if g.dryRun {
+		logInfo(fmt.Sprintf("%s would %s %s%s", aurora.Blue("dry-run"), aurora.Yellow(action), g.prefix(), aurora.Bold(s.Path())))
+
+		return true
+	}
+
+	logInfo(fmt.Sprintf("%s %s%s...", aurora.Yellow(action), g.prefix(), aurora.Bold(s.Path())), false)
+
+	if err := suspend(); err != nil {
+		logFailed()
+		logError(fmt.Sprintf("error %s swap process %s: %s, stopping it instead", doing, s.Path(), err.Error()))
+
+		return false
+	}
+
+	logOK()
+
+	return true
+}
+
+// stopSuspendedSwap resumes a paused swap so it can handle its stop
+// strategy, then stops it. A throttled swap is stopped as it is.
+func (g *group) stopSuspendedSwap(s Swap) {
+	if !g.dryRun && s.Mode() == SwapModePause {
+		// If it can't be resumed it is killed all the same.
+		_ = s.Resume()
+	}
+
+	g.stopSwap(s)
+}
+
+// launch starts swaps in dependency order. If any swap is delayed, waits for
+// other swaps or has to be ready before others start, the swaps are started
+// in the background so the loop keeps polling in the meantime.
+func (g *group) launch(swaps []Swap) {
+	sorted, err := sortSwaps(swaps)
+	if err != nil {
+		logError(fmt.Sprintf("%s%s, starting swaps in the order they are listed", g.prefix(), err.Error()))
+
+		sorted = swaps
+	}
+
+	staged := false
+
+	for _, s := range sorted {
+		if !s.Startup().isZero() {
+			staged = true
+		}
+	}
+	// A dry run starts nothing, so there is nothing to wait for.
+	if !staged || g.dryRun {
+		for _, s := range sorted {
+			g.startSwap(s)
+		}
+
+		return
+	}
+
+	g.startups++
+
+	go g.startInOrder(sorted, g.startups)
+}
+
+// startInOrder starts swaps one after the other, waiting for the swaps each
+// starts after to be ready and for its delay. It stops once the startup is
+// cancelled by the group's swaps being stopped or started again.
+func (g *group) startInOrder(swaps []Swap, startup int) {
+	for _, s := range swaps {
+		g.mu.Lock()
+		if !g.starting(startup) {
+			g.mu.Unlock()
+
+			return
+		}
+
+		startupOf := s.Startup()
+		dependencies := g.dependencies(s)
+		g.mu.Unlock()
+
+		for _, dependency := range dependencies {
+			g.waitReady(s, dependency)
+		}
+
+		if startupOf.Delay > 0 {
+			logInfo(fmt.Sprintf("%s %s%s in %s", aurora.Green("start"), g.prefix(), aurora.Bold(s.Path()), startupOf.Delay))
+			time.Sleep(startupOf.Delay)
+		}
+
+		g.mu.Lock()
+		if !g.starting(startup) {
+			g.mu.Unlock()
+
+			return
+		}
+		// The swap may have been started, or removed, by a reload while we waited.
+		if containsSwap(g.Swaps, s) && !containsSwap(g.runningSwaps, s) && !g.restarting[s] {
+			g.startSwap(s)
+		}
+		g.mu.Unlock()
+	}
+}
+
+// starting returns true if the group's swaps are started and startup has not
+// been cancelled.
+func (g *group) starting(startup int) bool {
+	return g.started && g.startups == startup
+}
+
+// dependencies returns the running swaps that s starts after.
+func (g *group) dependencies(s Swap) []Swap {
+	dependencies := []Swap{}
+
+	for _, name := range s.Startup().After {
+		found := false
+
+		for _, running := range g.runningSwaps {
+			if running.Name() == name {
+				dependencies = append(dependencies, running)
+				found = true
+			}
+		}
+
+		if !found {
+			logWarn(fmt.Sprintf("%s%s starts after %s, which is not running", g.prefix(), s.Path(), name))
+		}
+	}
+
+	return dependencies
+}
+
+// waitReady waits for dependency to be ready before s starts, giving up
+// after the dependency's ready timeout or if it exits.
+func (g *group) waitReady(s, dependency Swap) {
+	g.mu.Lock()
+	ready := dependency.Ready()
+	exited := dependency.Exited()
+	timeout := dependency.Startup().Ready.timeout()
+	g.mu.Unlock()
+
+	select {
+	case <-ready:
+	case <-exited:
+		logWarn(fmt.Sprintf("%s%s starts after %s, which exited before it was ready", g.prefix(), s.Path(), dependency.Path()))
+	case <-time.After(timeout):
+		logWarn(fmt.Sprintf("%s%s starts after %s, which is not ready after %s; starting anyway", g.prefix(), s.Path(), dependency.Path(), timeout))
+	}
+}
+
+// startSwap starts a single swap process and adds it to the running swaps.
+func (g *group) startSwap(s Swap) {
+	if g.dryRun {
+		logInfo(fmt.Sprintf("%s would %s %s%s", aurora.Blue("dry-run"), aurora.Green("start"), g.prefix(), aurora.Bold(s.Path())))
+
+		g.runningSwaps = append(g.runningSwaps, s)
+
+		return
+	}
+
+	// Print this without a newline at the end since we'll be printing the status later.
+	logInfo(fmt.Sprintf("%s %s%s...", aurora.Green("start"), g.prefix(), aurora.Bold(s.Path())), false)
+
+	err := s.Start()
+	if err != nil {
+		logFailed()
+		logError(fmt.Sprintf("error starting swap process %s: %s", s.Path(), err.Error()))
+
+		return
+	}
+
+	logOK()
+	g.track(s)
+}
+
+// track adds a swap that has just started to the running swaps, then
+// watches it and probes its health.
+func (g *group) track(s Swap) {
+	g.runningSwaps = append(g.runningSwaps, s)
+	g.generations[s]++
+
+	go g.watch(s, g.generations[s])
+
+	if !s.Health().isZero() {
+		go g.probe(s, g.generations[s])
+	}
+}
+
+// probe checks a running swap's health on the interval of its health probe.
+// When too many probes in a row fail the swap is stopped and started again.
+// It stops probing once the swap is stopped by the loop or started again.
+func (g *group) probe(s Swap, generation int) {
+	g.mu.Lock()
+	health := s.Health()
+	g.mu.Unlock()
+
+	failures := 0
+	threshold := health.failureThreshold()
+
+	time.Sleep(health.InitialDelay)
+
+	for {
+		time.Sleep(health.interval())
+
+		g.mu.Lock()
+		if !g.watching(s, generation) {
+			g.mu.Unlock()
+
+			return
+		}
+		// A paused swap can't answer its probe, and a throttled one may be
+		// too slow to.
+		if containsSwap(g.suspendedSwaps, s) {
+			failures = 0
+
+			g.mu.Unlock()
+
+			continue
+		}
+		g.mu.Unlock()
+
+		err := s.Check()
+
+		g.mu.Lock()
+		// The swap may have been stopped, or started again, while it was checked.
+		if !g.watching(s, generation) {
+			g.mu.Unlock()
+
+			return
+		}
+
+		if err == nil {
+			if failures > 0 {
+				logInfo(fmt.Sprintf("%s %s%s is healthy again", aurora.Cyan("health"), g.prefix(), aurora.Bold(s.Path())))
+			}
+
+			failures = 0
+
+			g.mu.Unlock()
+
+			continue
+		}
+
+		failures++
+
+		logWarn(fmt.Sprintf("%s %s%s failed probe %s (%d/%d): %s", aurora.Cyan("health"), g.prefix(), aurora.Bold(s.Path()), health, failures, threshold, err.Error()))
+
+		if failures >= threshold {
+			logError(fmt.Sprintf("%s %s%s unhealthy after %d failed probes, restarting", aurora.Cyan("health"), g.prefix(), s.Path(), failures))
+			// Stopping the swap can take a while, so it is stopped without the
+			// lock. Once it is no longer running its watcher stops watching it.
+			g.removeRunningSwap(s)
+			g.restarting[s] = true
+			detached := g.detach()
+			g.mu.Unlock()
+
+			detached.stopSwap(s)
+
+			g.mu.Lock()
+			// The group's swaps may have been stopped, or the swap removed,
+			// while it was stopping. Starting the swap again moves it to a new
+			// generation, which stops this probe.
+			if g.restarting[s] && g.started && containsSwap(g.Swaps, s) {
+				g.startSwap(s)
+			}
+
+			delete(g.restarting, s)
+			g.mu.Unlock()
+
+			return
+		}
+
+		g.mu.Unlock()
+	}
+}
+
+// watch waits for a swap to exit and, if it exited on its own, logs its exit
+// code and restarts it according to its restart policy. It stops watching
+// once the swap is stopped by the loop or started again by anything else.
+func (g *group) watch(s Swap, generation int) {
+	retries := 0
+
+	for {
+		g.mu.Lock()
+		exited := s.Exited()
+		g.mu.Unlock()
+
+		if exited == nil {
+			return
+		}
+
+		started := time.Now()
+
+		<-exited
+
+		g.mu.Lock()
+
+		if !g.watching(s, generation) {
+			g.mu.Unlock()
+
+			return
+		}
+
+		code := s.ExitCode()
+		policy := s.Restart()
+		// A swap that exits while paused or throttled is started from scratch
+		// once priorities stop, there's no point restarting it before then.
+		if containsSwap(g.suspendedSwaps, s) {
+			g.removeExited(s)
+			g.mu.Unlock()
+
+			return
+		}
+
+		g.logExit(s)
+
+		if time.Since(started) >= restartResetAfter {
+			retries = 0
+		}
+
+		if !policy.shouldRestart(code, retries) {
+			if retries > 0 && policy.shouldRestart(code, 0) {
+				logError(fmt.Sprintf("%s %s%s gave up after %d retries", aurora.Yellow("restart"), g.prefix(), s.Path(), retries))
+			}
+
+			g.removeRunningSwap(s)
+			g.mu.Unlock()
+
+			return
+		}
+
+		retries++
+		backoff := policy.backoff(retries)
+
+		logInfo(fmt.Sprintf("%s %s%s in %s (retry %d)", aurora.Yellow("restart"), g.prefix(), aurora.Bold(s.Path()), backoff, retries))
+		g.mu.Unlock()
+
+		time.Sleep(backoff)
+
+		g.mu.Lock()
+		// The swap may have been stopped, or started again, while we waited.
+		if !g.watching(s, generation) {
+			g.mu.Unlock()
+
+			return
+		}
+
+		logInfo(fmt.Sprintf("%s %s%s...", aurora.Yellow("restart"), g.prefix(), aurora.Bold(s.Path())), false)
+
+		if err := s.Start(); err != nil {
+			logFailed()
+			logError(fmt.Sprintf("error restarting swap process %s: %s", s.Path(), err.Error()))
+			g.removeRunningSwap(s)
+			g.mu.Unlock()
+
+			return
+		}
+
+		logOK()
+		g.mu.Unlock()
+	}
+}
+
+// watching returns true if the swap is still running, or suspended, as part of
+// the group and has not been started again since generation.
+func (g *group) watching(s Swap, generation int) bool {
+	if g.generations[s] != generation {
+		return false
+	}
+
+	return containsSwap(g.suspendedSwaps, s) || g.started && containsSwap(g.runningSwaps, s)
+}
+
+// reconcile removes the running and suspended swaps whose process is no
+// longer running, so nothing acts on a dead process. Swaps that are about to
+// be restarted are left to their watcher.
+func (g *group) reconcile() {
+	swaps := append(append([]Swap{}, g.runningSwaps...), g.suspendedSwaps...)
+
+	for _, s := range swaps {
+		if !s.State().done() {
+			continue
+		}
+
+		if containsSwap(g.runningSwaps, s) && s.Restart().shouldRestart(s.ExitCode(), 0) {
+			continue
+		}
+
+		g.removeExited(s)
+	}
+}
+
+// logExit logs how a swap that is no longer running ended.
+func (g *group) logExit(s Swap) {
+	suffix := ""
+	if containsSwap(g.suspendedSwaps, s) {
+		suffix = " while priorities run"
+	}
+
+	logWarn(fmt.Sprintf("%s %s%s %s%s", aurora.Red("exit"), g.prefix(), aurora.Bold(s.Path()), describeExit(s), suffix))
+}
+
+// removeExited logs how a swap that is no longer running ended, then
+// removes it from the running and suspended swaps.
+func (g *group) removeExited(s Swap) {
+	g.logExit(s)
+	g.removeRunningSwap(s)
+	g.removeSuspendedSwap(s)
+}
+
+// removeSuspendedSwap removes a swap from the suspended swaps.
+func (g *group) removeSuspendedSwap(s Swap) {
+	suspendedSwaps := []Swap{}
+
+	for _, swap := range g.suspendedSwaps {
+		if swap != s {
+			suspendedSwaps = append(suspendedSwaps, swap)
+		}
+	}
+
+	g.suspendedSwaps = suspendedSwaps
+}
+
+// removeRunningSwap removes a swap from the running swaps.
+func (g *group) removeRunningSwap(s Swap) {
+	runningSwaps := []Swap{}
+
+	for _, swap := range g.runningSwaps {
+		if swap != s {
+			runningSwaps = append(runningSwaps, swap)
+		}
+	}
+
+	g.runningSwaps = runningSwaps
+}
+
+// stopSwaps stops all running and suspended swap processes. Each swap is
+// stopped along with its whole process tree, every process descended from
+// it, so nothing it started is left behind.
+//
+// Stopping swaps blocks, so stopSwaps only takes the swaps out of the group
+// and returns the work of stopping them, for the caller to do once it has
+// released the loop's lock.
+func (g *group) stopSwaps() func() {
+	// Cancel any startup still in progress, and any restart.
+	g.startups++
+	g.restarting = map[Swap]bool{}
+
+	runningSwaps, suspendedSwaps := g.runningSwaps, g.suspendedSwaps
+	// Since we're shutting down everything, reset the currently running commands.
+	g.runningSwaps = []Swap{}
+	g.suspendedSwaps = []Swap{}
+	detached := g.detach()
+
+	return func() {
+		// Loop through and kill the running swaps, in reverse of the order they
+		// started in so swaps stop before the swaps they depend on.
+		for i := len(runningSwaps) - 1; i >= 0; i-- {
+			detached.stopSwap(runningSwaps[i])
+		}
+
+		for i := len(suspendedSwaps) - 1; i >= 0; i-- {
+			detached.stopSuspendedSwap(suspendedSwaps[i])
+		}
+	}
+}
+
+// stopSwap stops a single swap process using its stop strategy, then logs
+// the outcome of each step taken. It is up to the caller to remove it from
+// the running swaps.
+func (g *group) stopSwap(swap Swap) {
+	if g.dryRun {
+		logInfo(fmt.Sprintf("%s would %s %s%s", aurora.Blue("dry-run"), aurora.Red("stop"), g.prefix(), aurora.Bold(swap.Path())))
+
+		return
+	}
+
+	logInfo(fmt.Sprintf("%s %s%s...", aurora.Red("stop"), g.prefix(), aurora.Bold(swap.Path())), false)
+
+	steps, err := swap.Stop()
+	if err != nil {
+		logFailed()
+		logError(err.Error())
+	} else {
+		logOK()
+	}
+
+	for _, step := range steps {
+		logInfo(fmt.Sprintf("%s %s%s %s", aurora.Red("stop"), g.prefix(), aurora.Bold(swap.Path()), step))
+	}
+}
+
+// startPriorityScript starts the group's priority script. It waits for the command to complete, which
+// is different than swaps which are started then stopped if a priority process begins running.
+func (g *group) startPriorityScript() {
+	// If no priority script is set, just return.
+	if g.PriorityScript == "" {
+		return
+	}
+
+	if g.dryRun {
+		logInfo(fmt.Sprintf("%s would run %s %s%s", aurora.Blue("dry-run"), aurora.Magenta("priority script"), g.prefix(), aurora.Bold(g.PriorityScript)))
+
+		return
+	}
+
+	logInfo(fmt.Sprintf("%s %s%s...", aurora.Magenta("priority script"), g.prefix(), aurora.Bold(g.PriorityScript)), false)
+
+	cmd := exec.Command(g.PriorityScript)
+	if err := cmd.Run(); err != nil {
+		// If there is an error running the priority script, just log it and let the loop continue.
+		logFailed()
+		logError(err.Error())
+	} else {
+		logOK()
+	}
+}
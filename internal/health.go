@@ -0,0 +1,188 @@
+package procswap
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultHealthInterval is how often a swap is probed when no interval is set.
+	defaultHealthInterval = 30 * time.Second
+	// defaultHealthTimeout is how long a probe can take when no timeout is set.
+	defaultHealthTimeout = 5 * time.Second
+	// defaultHealthFailureThreshold is how many probes in a row have to fail
+	// before a swap is restarted when no threshold is set.
+	defaultHealthFailureThreshold = 3
+)
+
+// HealthProbe checks that a running swap is healthy, not just alive. Exactly
+// one of HTTP, TCP or Command is set. The zero value never probes the swap.
+type HealthProbe struct {
+	// HTTP is a URL that has to answer a GET with a 2xx or 3xx status.
+	HTTP string
+	// TCP is an address, like 127.0.0.1:3333, that has to accept a connection.
+	TCP string
+	// Command is run to check the swap and has to exit with code 0. It gets
+	// the PID of the swap in the PROCSWAP_SWAP_PID environment variable.
+	Command string
+	// Args are the arguments passed to the command.
+	Args []string
+	// InitialDelay is how long to wait after the swap starts before the first probe.
+	InitialDelay time.Duration
+	// Interval is the time between probes.
+	Interval time.Duration
+	// Timeout is how long a probe can take before it fails.
+	Timeout time.Duration
+	// FailureThreshold is how many probes in a row have to fail before the
+	// swap is restarted.
+	FailureThreshold int
+}
+
+// isZero returns true if the probe checks nothing.
+func (h HealthProbe) isZero() bool {
+	return h.HTTP == "" && h.TCP == "" && h.Command == ""
+}
+
+// validate returns an error if more than one check is set or any value is negative.
+func (h HealthProbe) validate() error {
+	checks := 0
+
+	for _, check := range []string{h.HTTP, h.TCP, h.Command} {
+		if check != "" {
+			checks++
+		}
+	}
+
+	if checks > 1 {
+		return errors.New("health probe can only set one of http, tcp or command")
+	}
+
+	if h.InitialDelay < 0 || h.Interval < 0 || h.Timeout < 0 || h.FailureThreshold < 0 {
+		return errors.New("health probe delay, interval, timeout and failure threshold can't be negative")
+	}
+
+	return nil
+}
+
+// String describes what the probe checks.
+func (h HealthProbe) String() string {
+	switch {
+	case h.HTTP != "":
+		return "http " + h.HTTP
+	case h.TCP != "":
+		return "tcp " + h.TCP
+	default:
+		return strings.Join(append([]string{"command", h.Command}, h.Args...), " ")
+	}
+}
+
+// interval returns the interval, or the default interval if it is not set.
+func (h HealthProbe) interval() time.Duration {
+	if h.Interval == 0 {
+		return defaultHealthInterval
+	}
+
+	return h.Interval
+}
+
+// timeout returns the timeout, or the default timeout if it is not set.
+func (h HealthProbe) timeout() time.Duration {
+	if h.Timeout == 0 {
+		return defaultHealthTimeout
+	}
+
+	return h.Timeout
+}
+
+// failureThreshold returns the failure threshold, or the default threshold
+// if it is not set.
+func (h HealthProbe) failureThreshold() int {
+	if h.FailureThreshold == 0 {
+		return defaultHealthFailureThreshold
+	}
+
+	return h.FailureThreshold
+}
+
+// checkHTTP sends a GET to the probe's URL.
+func (h HealthProbe) checkHTTP() error {
+	client := &http.Client{Timeout: h.timeout()}
+
+	resp, err := client.Get(h.HTTP)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("unhealthy status %s", resp.Status)
+	}
+
+	return nil
+}
+
+// checkTCP opens a connection to the probe's address.
+func (h HealthProbe) checkTCP() error {
+	conn, err := net.DialTimeout("tcp", h.TCP, h.timeout())
+	if err != nil {
+		return err
+	}
+
+	return conn.Close()
+}
+
+// Check probes the swap once, returning an error if it is unhealthy. A
+// swap without a health probe is always healthy.
+func (s *swap) Check() error {
+	switch {
+	case s.health.HTTP != "":
+		return s.health.checkHTTP()
+	case s.health.TCP != "":
+		return s.health.checkTCP()
+	case s.health.Command != "":
+		return s.checkCommand()
+	default:
+		return nil
+	}
+}
+
+// checkCommand runs the probe's command in the same directory as the swap.
+func (s *swap) checkCommand() error {
+	if s.cmd == nil {
+		return errors.New("no command to check")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.health.timeout())
+	defer cancel()
+
+	var out bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, s.health.Command, s.health.Args...)
+	cmd.Dir = s.cmd.Dir
+	cmd.Env = append(append(os.Environ(), s.env...), "PROCSWAP_SWAP_PID="+strconv.Itoa(s.cmd.Process.Pid))
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("timed out after %s", s.health.timeout())
+		}
+
+		if output := strings.TrimSpace(out.String()); output != "" {
+			return fmt.Errorf("%w: %s", err, output)
+		}
+
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,50 @@
+package procswap
+
+import "sync"
+
+// defaultHistorySize is the number of lines of output kept for each swap
+// when no size is set.
+const defaultHistorySize = 100
+
+// history is a ring buffer of the most recent lines of a swap's output.
+type history struct {
+	mu    sync.Mutex
+	lines []string
+	// next is the index the next line is written to.
+	next int
+	// full is true once the buffer has wrapped around.
+	full bool
+}
+
+func newHistory(size int) *history {
+	if size <= 0 {
+		size = defaultHistorySize
+	}
+
+	return &history{lines: make([]string, size)}
+}
+
+// add adds a line, replacing the oldest line once the buffer is full.
+func (h *history) add(line string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lines[h.next] = line
+	h.next = (h.next + 1) % len(h.lines)
+
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// snapshot returns the lines in the buffer, oldest first.
+func (h *history) snapshot() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.full {
+		return append([]string{}, h.lines[:h.next]...)
+	}
+
+	return append(append([]string{}, h.lines[h.next:]...), h.lines[:h.next]...)
+}
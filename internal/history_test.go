@@ -0,0 +1,51 @@
+package procswap
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("history", func() {
+	var h *history
+
+	BeforeEach(func() {
+		h = newHistory(3)
+	})
+
+	Describe("#snapshot", func() {
+		When("the buffer is not full", func() {
+			BeforeEach(func() {
+				h.add("one")
+				h.add("two")
+			})
+
+			It("returns every line", func() {
+				Expect(h.snapshot()).To(Equal([]string{"one", "two"}))
+			})
+		})
+
+		When("the buffer has wrapped around", func() {
+			BeforeEach(func() {
+				for _, line := range []string{"one", "two", "three", "four", "five"} {
+					h.add(line)
+				}
+			})
+
+			It("returns the most recent lines, oldest first", func() {
+				Expect(h.snapshot()).To(Equal([]string{"three", "four", "five"}))
+			})
+		})
+	})
+
+	Describe("#newHistory", func() {
+		When("no size is set", func() {
+			BeforeEach(func() {
+				h = newHistory(0)
+			})
+
+			It("uses the default size", func() {
+				Expect(h.lines).To(HaveLen(defaultHistorySize))
+			})
+		})
+	})
+})
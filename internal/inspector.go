@@ -0,0 +1,28 @@
+package procswap
+
+//go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 . Inspector
+
+// Inspector looks up details of running processes that are not part of the
+// process table, like the full path of their executable.
+type Inspector interface {
+	Cmdline(int) (string, error)
+	Path(int) (string, error)
+}
+
+type inspector struct{}
+
+// NewInspector returns an implementation of Inspector for the current OS.
+func NewInspector() Inspector {
+	return &inspector{}
+}
+
+// Cmdline returns the command line of the process with the given PID, its
+// arguments are joined by spaces.
+func (i *inspector) Cmdline(pid int) (string, error) {
+	return processCmdline(pid)
+}
+
+// Path returns the full path of the executable of the process with the given PID.
+func (i *inspector) Path(pid int) (string, error) {
+	return processPath(pid)
+}
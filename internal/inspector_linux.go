@@ -0,0 +1,78 @@
+package procswap
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// inspectorSupported is true as /proc has the path and start time of every
+// process.
+const inspectorSupported = true
+
+// cmdlineSupported is true as /proc has the command line of every process.
+const cmdlineSupported = true
+
+// processCmdline reads the command line of a process from /proc.
+func processCmdline(pid int) (string, error) {
+	b, err := ioutil.ReadFile("/proc/" + strconv.Itoa(pid) + "/cmdline")
+	if err != nil {
+		return "", err
+	}
+	// Arguments are separated, and ended, by null bytes.
+	args := bytes.Split(bytes.TrimRight(b, "\x00"), []byte{0})
+
+	s := make([]string, 0, len(args))
+	for _, arg := range args {
+		s = append(s, string(arg))
+	}
+
+	return strings.Join(s, " "), nil
+}
+
+// processPath reads the link to the executable of a process from /proc.
+func processPath(pid int) (string, error) {
+	path, err := os.Readlink("/proc/" + strconv.Itoa(pid) + "/exe")
+	if err != nil {
+		return "", err
+	}
+	// The link ends in " (deleted)" when the executable was replaced while running.
+	return strings.TrimSuffix(path, " (deleted)"), nil
+}
+
+// processZombie returns true if the process has exited but has not been
+// reaped by its parent yet.
+func processZombie(pid int) bool {
+	b, err := ioutil.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return false
+	}
+	// The state comes right after the executable name, which is in parentheses
+	// and can itself contain spaces or parentheses.
+	i := bytes.LastIndexByte(b, ')')
+	if i < 0 || i+2 >= len(b) {
+		return false
+	}
+
+	return b[i+2] == 'Z'
+}
+
+// processStartTime reads the time the process started, in clock ticks since
+// boot, from /proc. Together with the PID it identifies the process, as PIDs
+// are reused.
+func processStartTime(pid int) (uint64, error) {
+	b, err := ioutil.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return 0, err
+	}
+	// The start time is the 22nd field, the 20th after the executable name.
+	fields := strings.Fields(string(b[bytes.LastIndexByte(b, ')')+1:]))
+	if len(fields) < 20 {
+		return 0, errors.New("malformed stat of process " + strconv.Itoa(pid))
+	}
+
+	return strconv.ParseUint(fields[19], 10, 64)
+}
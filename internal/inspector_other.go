@@ -0,0 +1,33 @@
+//go:build !linux && !windows
+// +build !linux,!windows
+
+package procswap
+
+import (
+	"errors"
+	"runtime"
+)
+
+// inspectorSupported is true where the path and start time of a process can
+// be read, which matching by path and keeping a state file need.
+const inspectorSupported = false
+
+// cmdlineSupported is true where the command line of a process can be read,
+// which matching by command line needs.
+const cmdlineSupported = false
+
+func processCmdline(pid int) (string, error) {
+	return "", errors.New("reading the command line of a process is not supported on " + runtime.GOOS)
+}
+
+func processPath(pid int) (string, error) {
+	return "", errors.New("reading the path of a process is not supported on " + runtime.GOOS)
+}
+
+func processZombie(pid int) bool {
+	return false
+}
+
+func processStartTime(pid int) (uint64, error) {
+	return 0, errors.New("reading the start time of a process is not supported on " + runtime.GOOS)
+}
@@ -0,0 +1,41 @@
+package procswap_test
+
+import (
+	"os"
+	"runtime"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/billiford/procswap/internal"
+)
+
+var _ = Describe("Inspector", func() {
+	var inspector Inspector
+
+	BeforeEach(func() {
+		if runtime.GOOS != "linux" {
+			Skip("processes are only inspected through /proc on linux")
+		}
+
+		inspector = NewInspector()
+	})
+
+	Describe("#Path", func() {
+		It("returns the full path of the process' executable", func() {
+			expected, err := os.Executable()
+			Expect(err).To(BeNil())
+			path, err := inspector.Path(os.Getpid())
+			Expect(err).To(BeNil())
+			Expect(path).To(Equal(expected))
+		})
+	})
+
+	Describe("#Cmdline", func() {
+		It("returns the process' command line", func() {
+			cmdline, err := inspector.Cmdline(os.Getpid())
+			Expect(err).To(BeNil())
+			Expect(cmdline).To(HavePrefix(os.Args[0]))
+		})
+	})
+})
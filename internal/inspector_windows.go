@@ -0,0 +1,69 @@
+package procswap
+
+import (
+	"errors"
+	"syscall"
+	"unsafe"
+)
+
+// inspectorSupported is true as Windows can be asked for the path and start
+// time of a process.
+const inspectorSupported = true
+
+// cmdlineSupported is false as reading the command line of another process
+// means reading its memory, see processCmdline.
+const cmdlineSupported = false
+
+// processQueryLimitedInformation is the access right needed to query the
+// image name of a process.
+const processQueryLimitedInformation = 0x1000
+
+var procQueryFullProcessImageName = syscall.NewLazyDLL("kernel32.dll").NewProc("QueryFullProcessImageNameW")
+
+// processCmdline is not supported on Windows, reading the command line of
+// another process means reading its memory.
+func processCmdline(pid int) (string, error) {
+	return "", errors.New("reading the command line of a process is not supported on windows")
+}
+
+// processPath asks Windows for the full image name of a process.
+func processPath(pid int) (string, error) {
+	h, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err != nil {
+		return "", err
+	}
+	defer syscall.CloseHandle(h)
+
+	buf := make([]uint16, syscall.MAX_LONG_PATH)
+	size := uint32(len(buf))
+
+	r, _, err := procQueryFullProcessImageName.Call(uintptr(h), 0, uintptr(unsafe.Pointer(&buf[0])), uintptr(unsafe.Pointer(&size)))
+	if r == 0 {
+		return "", err
+	}
+
+	return syscall.UTF16ToString(buf[:size]), nil
+}
+
+// processZombie returns false, Windows has no zombie processes.
+func processZombie(pid int) bool {
+	return false
+}
+
+// processStartTime asks Windows for the time the process was created, in
+// nanoseconds since the epoch. Together with the PID it identifies the
+// process, as PIDs are reused.
+func processStartTime(pid int) (uint64, error) {
+	h, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err != nil {
+		return 0, err
+	}
+	defer syscall.CloseHandle(h)
+
+	var creation, exit, kernel, user syscall.Filetime
+	if err := syscall.GetProcessTimes(h, &creation, &exit, &kernel, &user); err != nil {
+		return 0, err
+	}
+
+	return uint64(creation.Nanoseconds()), nil
+}
@@ -1,13 +1,33 @@
 package procswap_test
 
 import (
+	"os"
+	"strings"
 	"testing"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+
+	. "github.com/billiford/procswap/internal"
 )
 
 func TestPkg(t *testing.T) {
 	RegisterFailHandler(Fail)
 	RunSpecs(t, "Procswap Suite")
 }
+
+// TestHelperProcess runs procswap with the arguments in PROCSWAP_HELPER_ARGS,
+// separated by newlines, when the test binary is run again by a test that
+// needs procswap to exit on its own.
+func TestHelperProcess(t *testing.T) {
+	args := os.Getenv("PROCSWAP_HELPER_ARGS")
+	if args == "" {
+		return
+	}
+
+	if err := NewApp().Run(strings.Split(args, "\n")); err != nil {
+		os.Exit(1)
+	}
+
+	os.Exit(0)
+}
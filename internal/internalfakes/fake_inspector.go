@@ -0,0 +1,195 @@
+// Code generated by counterfeiter. DO NOT EDIT.
+package internalfakes
+
+import (
+	"sync"
+
+	procswap "github.com/billiford/procswap/internal"
+)
+
+type FakeInspector struct {
+	CmdlineStub        func(int) (string, error)
+	cmdlineMutex       sync.RWMutex
+	cmdlineArgsForCall []struct {
+		arg1 int
+	}
+	cmdlineReturns struct {
+		result1 string
+		result2 error
+	}
+	cmdlineReturnsOnCall map[int]struct {
+		result1 string
+		result2 error
+	}
+	PathStub        func(int) (string, error)
+	pathMutex       sync.RWMutex
+	pathArgsForCall []struct {
+		arg1 int
+	}
+	pathReturns struct {
+		result1 string
+		result2 error
+	}
+	pathReturnsOnCall map[int]struct {
+		result1 string
+		result2 error
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeInspector) Cmdline(arg1 int) (string, error) {
+	fake.cmdlineMutex.Lock()
+	ret, specificReturn := fake.cmdlineReturnsOnCall[len(fake.cmdlineArgsForCall)]
+	fake.cmdlineArgsForCall = append(fake.cmdlineArgsForCall, struct {
+		arg1 int
+	}{arg1})
+	stub := fake.CmdlineStub
+	fakeReturns := fake.cmdlineReturns
+	fake.recordInvocation("Cmdline", []interface{}{arg1})
+	fake.cmdlineMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeInspector) CmdlineCallCount() int {
+	fake.cmdlineMutex.RLock()
+	defer fake.cmdlineMutex.RUnlock()
+	return len(fake.cmdlineArgsForCall)
+}
+
+func (fake *FakeInspector) CmdlineCalls(stub func(int) (string, error)) {
+	fake.cmdlineMutex.Lock()
+	defer fake.cmdlineMutex.Unlock()
+	fake.CmdlineStub = stub
+}
+
+func (fake *FakeInspector) CmdlineArgsForCall(i int) int {
+	fake.cmdlineMutex.RLock()
+	defer fake.cmdlineMutex.RUnlock()
+	argsForCall := fake.cmdlineArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeInspector) CmdlineReturns(result1 string, result2 error) {
+	fake.cmdlineMutex.Lock()
+	defer fake.cmdlineMutex.Unlock()
+	fake.CmdlineStub = nil
+	fake.cmdlineReturns = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeInspector) CmdlineReturnsOnCall(i int, result1 string, result2 error) {
+	fake.cmdlineMutex.Lock()
+	defer fake.cmdlineMutex.Unlock()
+	fake.CmdlineStub = nil
+	if fake.cmdlineReturnsOnCall == nil {
+		fake.cmdlineReturnsOnCall = make(map[int]struct {
+			result1 string
+			result2 error
+		})
+	}
+	fake.cmdlineReturnsOnCall[i] = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeInspector) Path(arg1 int) (string, error) {
+	fake.pathMutex.Lock()
+	ret, specificReturn := fake.pathReturnsOnCall[len(fake.pathArgsForCall)]
+	fake.pathArgsForCall = append(fake.pathArgsForCall, struct {
+		arg1 int
+	}{arg1})
+	stub := fake.PathStub
+	fakeReturns := fake.pathReturns
+	fake.recordInvocation("Path", []interface{}{arg1})
+	fake.pathMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeInspector) PathCallCount() int {
+	fake.pathMutex.RLock()
+	defer fake.pathMutex.RUnlock()
+	return len(fake.pathArgsForCall)
+}
+
+func (fake *FakeInspector) PathCalls(stub func(int) (string, error)) {
+	fake.pathMutex.Lock()
+	defer fake.pathMutex.Unlock()
+	fake.PathStub = stub
+}
+
+func (fake *FakeInspector) PathArgsForCall(i int) int {
+	fake.pathMutex.RLock()
+	defer fake.pathMutex.RUnlock()
+	argsForCall := fake.pathArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeInspector) PathReturns(result1 string, result2 error) {
+	fake.pathMutex.Lock()
+	defer fake.pathMutex.Unlock()
+	fake.PathStub = nil
+	fake.pathReturns = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeInspector) PathReturnsOnCall(i int, result1 string, result2 error) {
+	fake.pathMutex.Lock()
+	defer fake.pathMutex.Unlock()
+	fake.PathStub = nil
+	if fake.pathReturnsOnCall == nil {
+		fake.pathReturnsOnCall = make(map[int]struct {
+			result1 string
+			result2 error
+		})
+	}
+	fake.pathReturnsOnCall[i] = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeInspector) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.cmdlineMutex.RLock()
+	defer fake.cmdlineMutex.RUnlock()
+	fake.pathMutex.RLock()
+	defer fake.pathMutex.RUnlock()
+	copiedInvocations := map[string][][]interface{}{}
+	for key, value := range fake.invocations {
+		copiedInvocations[key] = value
+	}
+	return copiedInvocations
+}
+
+func (fake *FakeInspector) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ procswap.Inspector = new(FakeInspector)
@@ -9,6 +9,37 @@ import (
 )
 
 type FakeSwap struct {
+	AdoptStub        func(int) error
+	adoptMutex       sync.RWMutex
+	adoptArgsForCall []struct {
+		arg1 int
+	}
+	adoptReturns struct {
+		result1 error
+	}
+	adoptReturnsOnCall map[int]struct {
+		result1 error
+	}
+	CheckStub        func() error
+	checkMutex       sync.RWMutex
+	checkArgsForCall []struct {
+	}
+	checkReturns struct {
+		result1 error
+	}
+	checkReturnsOnCall map[int]struct {
+		result1 error
+	}
+	CloseOutputLogStub        func() error
+	closeOutputLogMutex       sync.RWMutex
+	closeOutputLogArgsForCall []struct {
+	}
+	closeOutputLogReturns struct {
+		result1 error
+	}
+	closeOutputLogReturnsOnCall map[int]struct {
+		result1 error
+	}
 	CmdStub        func() *exec.Cmd
 	cmdMutex       sync.RWMutex
 	cmdArgsForCall []struct {
@@ -19,6 +50,46 @@ type FakeSwap struct {
 	cmdReturnsOnCall map[int]struct {
 		result1 *exec.Cmd
 	}
+	ExitCodeStub        func() int
+	exitCodeMutex       sync.RWMutex
+	exitCodeArgsForCall []struct {
+	}
+	exitCodeReturns struct {
+		result1 int
+	}
+	exitCodeReturnsOnCall map[int]struct {
+		result1 int
+	}
+	ExitedStub        func() <-chan struct{}
+	exitedMutex       sync.RWMutex
+	exitedArgsForCall []struct {
+	}
+	exitedReturns struct {
+		result1 <-chan struct{}
+	}
+	exitedReturnsOnCall map[int]struct {
+		result1 <-chan struct{}
+	}
+	HealthStub        func() procswap.HealthProbe
+	healthMutex       sync.RWMutex
+	healthArgsForCall []struct {
+	}
+	healthReturns struct {
+		result1 procswap.HealthProbe
+	}
+	healthReturnsOnCall map[int]struct {
+		result1 procswap.HealthProbe
+	}
+	HistoryStub        func() []string
+	historyMutex       sync.RWMutex
+	historyArgsForCall []struct {
+	}
+	historyReturns struct {
+		result1 []string
+	}
+	historyReturnsOnCall map[int]struct {
+		result1 []string
+	}
 	KillStub        func() error
 	killMutex       sync.RWMutex
 	killArgsForCall []struct {
@@ -29,6 +100,26 @@ type FakeSwap struct {
 	killReturnsOnCall map[int]struct {
 		result1 error
 	}
+	ModeStub        func() string
+	modeMutex       sync.RWMutex
+	modeArgsForCall []struct {
+	}
+	modeReturns struct {
+		result1 string
+	}
+	modeReturnsOnCall map[int]struct {
+		result1 string
+	}
+	NameStub        func() string
+	nameMutex       sync.RWMutex
+	nameArgsForCall []struct {
+	}
+	nameReturns struct {
+		result1 string
+	}
+	nameReturnsOnCall map[int]struct {
+		result1 string
+	}
 	PIDStub        func() int
 	pIDMutex       sync.RWMutex
 	pIDArgsForCall []struct {
@@ -49,6 +140,56 @@ type FakeSwap struct {
 	pathReturnsOnCall map[int]struct {
 		result1 string
 	}
+	PauseStub        func() error
+	pauseMutex       sync.RWMutex
+	pauseArgsForCall []struct {
+	}
+	pauseReturns struct {
+		result1 error
+	}
+	pauseReturnsOnCall map[int]struct {
+		result1 error
+	}
+	PausedStub        func() bool
+	pausedMutex       sync.RWMutex
+	pausedArgsForCall []struct {
+	}
+	pausedReturns struct {
+		result1 bool
+	}
+	pausedReturnsOnCall map[int]struct {
+		result1 bool
+	}
+	ReadyStub        func() <-chan struct{}
+	readyMutex       sync.RWMutex
+	readyArgsForCall []struct {
+	}
+	readyReturns struct {
+		result1 <-chan struct{}
+	}
+	readyReturnsOnCall map[int]struct {
+		result1 <-chan struct{}
+	}
+	RestartStub        func() procswap.RestartPolicy
+	restartMutex       sync.RWMutex
+	restartArgsForCall []struct {
+	}
+	restartReturns struct {
+		result1 procswap.RestartPolicy
+	}
+	restartReturnsOnCall map[int]struct {
+		result1 procswap.RestartPolicy
+	}
+	ResumeStub        func() error
+	resumeMutex       sync.RWMutex
+	resumeArgsForCall []struct {
+	}
+	resumeReturns struct {
+		result1 error
+	}
+	resumeReturnsOnCall map[int]struct {
+		result1 error
+	}
 	ShowOutputStub        func(bool)
 	showOutputMutex       sync.RWMutex
 	showOutputArgsForCall []struct {
@@ -64,10 +205,326 @@ type FakeSwap struct {
 	startReturnsOnCall map[int]struct {
 		result1 error
 	}
+	StartupStub        func() procswap.Startup
+	startupMutex       sync.RWMutex
+	startupArgsForCall []struct {
+	}
+	startupReturns struct {
+		result1 procswap.Startup
+	}
+	startupReturnsOnCall map[int]struct {
+		result1 procswap.Startup
+	}
+	StateStub        func() procswap.SwapState
+	stateMutex       sync.RWMutex
+	stateArgsForCall []struct {
+	}
+	stateReturns struct {
+		result1 procswap.SwapState
+	}
+	stateReturnsOnCall map[int]struct {
+		result1 procswap.SwapState
+	}
+	StatsStub        func() (procswap.SwapStats, error)
+	statsMutex       sync.RWMutex
+	statsArgsForCall []struct {
+	}
+	statsReturns struct {
+		result1 procswap.SwapStats
+		result2 error
+	}
+	statsReturnsOnCall map[int]struct {
+		result1 procswap.SwapStats
+		result2 error
+	}
+	StopStub        func() ([]string, error)
+	stopMutex       sync.RWMutex
+	stopArgsForCall []struct {
+	}
+	stopReturns struct {
+		result1 []string
+		result2 error
+	}
+	stopReturnsOnCall map[int]struct {
+		result1 []string
+		result2 error
+	}
+	ThrottleStub        func() error
+	throttleMutex       sync.RWMutex
+	throttleArgsForCall []struct {
+	}
+	throttleReturns struct {
+		result1 error
+	}
+	throttleReturnsOnCall map[int]struct {
+		result1 error
+	}
+	ThrottledStub        func() bool
+	throttledMutex       sync.RWMutex
+	throttledArgsForCall []struct {
+	}
+	throttledReturns struct {
+		result1 bool
+	}
+	throttledReturnsOnCall map[int]struct {
+		result1 bool
+	}
+	UnthrottleStub        func() error
+	unthrottleMutex       sync.RWMutex
+	unthrottleArgsForCall []struct {
+	}
+	unthrottleReturns struct {
+		result1 error
+	}
+	unthrottleReturnsOnCall map[int]struct {
+		result1 error
+	}
+	WithArgsStub        func([]string)
+	withArgsMutex       sync.RWMutex
+	withArgsArgsForCall []struct {
+		arg1 []string
+	}
+	WithCgroupStub        func(procswap.Cgroup)
+	withCgroupMutex       sync.RWMutex
+	withCgroupArgsForCall []struct {
+		arg1 procswap.Cgroup
+	}
+	WithDirStub        func(string)
+	withDirMutex       sync.RWMutex
+	withDirArgsForCall []struct {
+		arg1 string
+	}
+	WithEnvStub        func([]string)
+	withEnvMutex       sync.RWMutex
+	withEnvArgsForCall []struct {
+		arg1 []string
+	}
+	WithHealthStub        func(procswap.HealthProbe)
+	withHealthMutex       sync.RWMutex
+	withHealthArgsForCall []struct {
+		arg1 procswap.HealthProbe
+	}
+	WithHistorySizeStub        func(int)
+	withHistorySizeMutex       sync.RWMutex
+	withHistorySizeArgsForCall []struct {
+		arg1 int
+	}
+	WithModeStub        func(string)
+	withModeMutex       sync.RWMutex
+	withModeArgsForCall []struct {
+		arg1 string
+	}
+	WithNameStub        func(string)
+	withNameMutex       sync.RWMutex
+	withNameArgsForCall []struct {
+		arg1 string
+	}
+	WithOutputLogStub        func(procswap.OutputLog)
+	withOutputLogMutex       sync.RWMutex
+	withOutputLogArgsForCall []struct {
+		arg1 procswap.OutputLog
+	}
+	WithRestartStub        func(procswap.RestartPolicy)
+	withRestartMutex       sync.RWMutex
+	withRestartArgsForCall []struct {
+		arg1 procswap.RestartPolicy
+	}
+	WithRunAsStub        func(procswap.RunAs)
+	withRunAsMutex       sync.RWMutex
+	withRunAsArgsForCall []struct {
+		arg1 procswap.RunAs
+	}
+	WithSpoolStub        func(string)
+	withSpoolMutex       sync.RWMutex
+	withSpoolArgsForCall []struct {
+		arg1 string
+	}
+	WithStartupStub        func(procswap.Startup)
+	withStartupMutex       sync.RWMutex
+	withStartupArgsForCall []struct {
+		arg1 procswap.Startup
+	}
+	WithStopStub        func(procswap.StopStrategy)
+	withStopMutex       sync.RWMutex
+	withStopArgsForCall []struct {
+		arg1 procswap.StopStrategy
+	}
+	WithThrottleStub        func(procswap.Throttle)
+	withThrottleMutex       sync.RWMutex
+	withThrottleArgsForCall []struct {
+		arg1 procswap.Throttle
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
 
+func (fake *FakeSwap) Adopt(arg1 int) error {
+	fake.adoptMutex.Lock()
+	ret, specificReturn := fake.adoptReturnsOnCall[len(fake.adoptArgsForCall)]
+	fake.adoptArgsForCall = append(fake.adoptArgsForCall, struct {
+		arg1 int
+	}{arg1})
+	stub := fake.AdoptStub
+	fakeReturns := fake.adoptReturns
+	fake.recordInvocation("Adopt", []interface{}{arg1})
+	fake.adoptMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeSwap) AdoptCallCount() int {
+	fake.adoptMutex.RLock()
+	defer fake.adoptMutex.RUnlock()
+	return len(fake.adoptArgsForCall)
+}
+
+func (fake *FakeSwap) AdoptCalls(stub func(int) error) {
+	fake.adoptMutex.Lock()
+	defer fake.adoptMutex.Unlock()
+	fake.AdoptStub = stub
+}
+
+func (fake *FakeSwap) AdoptArgsForCall(i int) int {
+	fake.adoptMutex.RLock()
+	defer fake.adoptMutex.RUnlock()
+	argsForCall := fake.adoptArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeSwap) AdoptReturns(result1 error) {
+	fake.adoptMutex.Lock()
+	defer fake.adoptMutex.Unlock()
+	fake.AdoptStub = nil
+	fake.adoptReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeSwap) AdoptReturnsOnCall(i int, result1 error) {
+	fake.adoptMutex.Lock()
+	defer fake.adoptMutex.Unlock()
+	fake.AdoptStub = nil
+	if fake.adoptReturnsOnCall == nil {
+		fake.adoptReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.adoptReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeSwap) Check() error {
+	fake.checkMutex.Lock()
+	ret, specificReturn := fake.checkReturnsOnCall[len(fake.checkArgsForCall)]
+	fake.checkArgsForCall = append(fake.checkArgsForCall, struct {
+	}{})
+	stub := fake.CheckStub
+	fakeReturns := fake.checkReturns
+	fake.recordInvocation("Check", []interface{}{})
+	fake.checkMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeSwap) CheckCallCount() int {
+	fake.checkMutex.RLock()
+	defer fake.checkMutex.RUnlock()
+	return len(fake.checkArgsForCall)
+}
+
+func (fake *FakeSwap) CheckCalls(stub func() error) {
+	fake.checkMutex.Lock()
+	defer fake.checkMutex.Unlock()
+	fake.CheckStub = stub
+}
+
+func (fake *FakeSwap) CheckReturns(result1 error) {
+	fake.checkMutex.Lock()
+	defer fake.checkMutex.Unlock()
+	fake.CheckStub = nil
+	fake.checkReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeSwap) CheckReturnsOnCall(i int, result1 error) {
+	fake.checkMutex.Lock()
+	defer fake.checkMutex.Unlock()
+	fake.CheckStub = nil
+	if fake.checkReturnsOnCall == nil {
+		fake.checkReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.checkReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeSwap) CloseOutputLog() error {
+	fake.closeOutputLogMutex.Lock()
+	ret, specificReturn := fake.closeOutputLogReturnsOnCall[len(fake.closeOutputLogArgsForCall)]
+	fake.closeOutputLogArgsForCall = append(fake.closeOutputLogArgsForCall, struct {
+	}{})
+	stub := fake.CloseOutputLogStub
+	fakeReturns := fake.closeOutputLogReturns
+	fake.recordInvocation("CloseOutputLog", []interface{}{})
+	fake.closeOutputLogMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeSwap) CloseOutputLogCallCount() int {
+	fake.closeOutputLogMutex.RLock()
+	defer fake.closeOutputLogMutex.RUnlock()
+	return len(fake.closeOutputLogArgsForCall)
+}
+
+func (fake *FakeSwap) CloseOutputLogCalls(stub func() error) {
+	fake.closeOutputLogMutex.Lock()
+	defer fake.closeOutputLogMutex.Unlock()
+	fake.CloseOutputLogStub = stub
+}
+
+func (fake *FakeSwap) CloseOutputLogReturns(result1 error) {
+	fake.closeOutputLogMutex.Lock()
+	defer fake.closeOutputLogMutex.Unlock()
+	fake.CloseOutputLogStub = nil
+	fake.closeOutputLogReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeSwap) CloseOutputLogReturnsOnCall(i int, result1 error) {
+	fake.closeOutputLogMutex.Lock()
+	defer fake.closeOutputLogMutex.Unlock()
+	fake.CloseOutputLogStub = nil
+	if fake.closeOutputLogReturnsOnCall == nil {
+		fake.closeOutputLogReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.closeOutputLogReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeSwap) Cmd() *exec.Cmd {
 	fake.cmdMutex.Lock()
 	ret, specificReturn := fake.cmdReturnsOnCall[len(fake.cmdArgsForCall)]
@@ -121,15 +578,15 @@ func (fake *FakeSwap) CmdReturnsOnCall(i int, result1 *exec.Cmd) {
 	}{result1}
 }
 
-func (fake *FakeSwap) Kill() error {
-	fake.killMutex.Lock()
-	ret, specificReturn := fake.killReturnsOnCall[len(fake.killArgsForCall)]
-	fake.killArgsForCall = append(fake.killArgsForCall, struct {
+func (fake *FakeSwap) ExitCode() int {
+	fake.exitCodeMutex.Lock()
+	ret, specificReturn := fake.exitCodeReturnsOnCall[len(fake.exitCodeArgsForCall)]
+	fake.exitCodeArgsForCall = append(fake.exitCodeArgsForCall, struct {
 	}{})
-	stub := fake.KillStub
-	fakeReturns := fake.killReturns
-	fake.recordInvocation("Kill", []interface{}{})
-	fake.killMutex.Unlock()
+	stub := fake.ExitCodeStub
+	fakeReturns := fake.exitCodeReturns
+	fake.recordInvocation("ExitCode", []interface{}{})
+	fake.exitCodeMutex.Unlock()
 	if stub != nil {
 		return stub()
 	}
@@ -139,50 +596,50 @@ func (fake *FakeSwap) Kill() error {
 	return fakeReturns.result1
 }
 
-func (fake *FakeSwap) KillCallCount() int {
-	fake.killMutex.RLock()
-	defer fake.killMutex.RUnlock()
-	return len(fake.killArgsForCall)
+func (fake *FakeSwap) ExitCodeCallCount() int {
+	fake.exitCodeMutex.RLock()
+	defer fake.exitCodeMutex.RUnlock()
+	return len(fake.exitCodeArgsForCall)
 }
 
-func (fake *FakeSwap) KillCalls(stub func() error) {
-	fake.killMutex.Lock()
-	defer fake.killMutex.Unlock()
-	fake.KillStub = stub
+func (fake *FakeSwap) ExitCodeCalls(stub func() int) {
+	fake.exitCodeMutex.Lock()
+	defer fake.exitCodeMutex.Unlock()
+	fake.ExitCodeStub = stub
 }
 
-func (fake *FakeSwap) KillReturns(result1 error) {
-	fake.killMutex.Lock()
-	defer fake.killMutex.Unlock()
-	fake.KillStub = nil
-	fake.killReturns = struct {
-		result1 error
+func (fake *FakeSwap) ExitCodeReturns(result1 int) {
+	fake.exitCodeMutex.Lock()
+	defer fake.exitCodeMutex.Unlock()
+	fake.ExitCodeStub = nil
+	fake.exitCodeReturns = struct {
+		result1 int
 	}{result1}
 }
 
-func (fake *FakeSwap) KillReturnsOnCall(i int, result1 error) {
-	fake.killMutex.Lock()
-	defer fake.killMutex.Unlock()
-	fake.KillStub = nil
-	if fake.killReturnsOnCall == nil {
-		fake.killReturnsOnCall = make(map[int]struct {
-			result1 error
+func (fake *FakeSwap) ExitCodeReturnsOnCall(i int, result1 int) {
+	fake.exitCodeMutex.Lock()
+	defer fake.exitCodeMutex.Unlock()
+	fake.ExitCodeStub = nil
+	if fake.exitCodeReturnsOnCall == nil {
+		fake.exitCodeReturnsOnCall = make(map[int]struct {
+			result1 int
 		})
 	}
-	fake.killReturnsOnCall[i] = struct {
-		result1 error
+	fake.exitCodeReturnsOnCall[i] = struct {
+		result1 int
 	}{result1}
 }
 
-func (fake *FakeSwap) PID() int {
-	fake.pIDMutex.Lock()
-	ret, specificReturn := fake.pIDReturnsOnCall[len(fake.pIDArgsForCall)]
-	fake.pIDArgsForCall = append(fake.pIDArgsForCall, struct {
+func (fake *FakeSwap) Exited() <-chan struct{} {
+	fake.exitedMutex.Lock()
+	ret, specificReturn := fake.exitedReturnsOnCall[len(fake.exitedArgsForCall)]
+	fake.exitedArgsForCall = append(fake.exitedArgsForCall, struct {
 	}{})
-	stub := fake.PIDStub
-	fakeReturns := fake.pIDReturns
-	fake.recordInvocation("PID", []interface{}{})
-	fake.pIDMutex.Unlock()
+	stub := fake.ExitedStub
+	fakeReturns := fake.exitedReturns
+	fake.recordInvocation("Exited", []interface{}{})
+	fake.exitedMutex.Unlock()
 	if stub != nil {
 		return stub()
 	}
@@ -192,50 +649,50 @@ func (fake *FakeSwap) PID() int {
 	return fakeReturns.result1
 }
 
-func (fake *FakeSwap) PIDCallCount() int {
-	fake.pIDMutex.RLock()
-	defer fake.pIDMutex.RUnlock()
-	return len(fake.pIDArgsForCall)
+func (fake *FakeSwap) ExitedCallCount() int {
+	fake.exitedMutex.RLock()
+	defer fake.exitedMutex.RUnlock()
+	return len(fake.exitedArgsForCall)
 }
 
-func (fake *FakeSwap) PIDCalls(stub func() int) {
-	fake.pIDMutex.Lock()
-	defer fake.pIDMutex.Unlock()
-	fake.PIDStub = stub
+func (fake *FakeSwap) ExitedCalls(stub func() <-chan struct{}) {
+	fake.exitedMutex.Lock()
+	defer fake.exitedMutex.Unlock()
+	fake.ExitedStub = stub
 }
 
-func (fake *FakeSwap) PIDReturns(result1 int) {
-	fake.pIDMutex.Lock()
-	defer fake.pIDMutex.Unlock()
-	fake.PIDStub = nil
-	fake.pIDReturns = struct {
-		result1 int
+func (fake *FakeSwap) ExitedReturns(result1 <-chan struct{}) {
+	fake.exitedMutex.Lock()
+	defer fake.exitedMutex.Unlock()
+	fake.ExitedStub = nil
+	fake.exitedReturns = struct {
+		result1 <-chan struct{}
 	}{result1}
 }
 
-func (fake *FakeSwap) PIDReturnsOnCall(i int, result1 int) {
-	fake.pIDMutex.Lock()
-	defer fake.pIDMutex.Unlock()
-	fake.PIDStub = nil
-	if fake.pIDReturnsOnCall == nil {
-		fake.pIDReturnsOnCall = make(map[int]struct {
-			result1 int
+func (fake *FakeSwap) ExitedReturnsOnCall(i int, result1 <-chan struct{}) {
+	fake.exitedMutex.Lock()
+	defer fake.exitedMutex.Unlock()
+	fake.ExitedStub = nil
+	if fake.exitedReturnsOnCall == nil {
+		fake.exitedReturnsOnCall = make(map[int]struct {
+			result1 <-chan struct{}
 		})
 	}
-	fake.pIDReturnsOnCall[i] = struct {
-		result1 int
+	fake.exitedReturnsOnCall[i] = struct {
+		result1 <-chan struct{}
 	}{result1}
 }
 
-func (fake *FakeSwap) Path() string {
-	fake.pathMutex.Lock()
-	ret, specificReturn := fake.pathReturnsOnCall[len(fake.pathArgsForCall)]
-	fake.pathArgsForCall = append(fake.pathArgsForCall, struct {
+func (fake *FakeSwap) Health() procswap.HealthProbe {
+	fake.healthMutex.Lock()
+	ret, specificReturn := fake.healthReturnsOnCall[len(fake.healthArgsForCall)]
+	fake.healthArgsForCall = append(fake.healthArgsForCall, struct {
 	}{})
-	stub := fake.PathStub
-	fakeReturns := fake.pathReturns
-	fake.recordInvocation("Path", []interface{}{})
-	fake.pathMutex.Unlock()
+	stub := fake.HealthStub
+	fakeReturns := fake.healthReturns
+	fake.recordInvocation("Health", []interface{}{})
+	fake.healthMutex.Unlock()
 	if stub != nil {
 		return stub()
 	}
@@ -245,16 +702,334 @@ func (fake *FakeSwap) Path() string {
 	return fakeReturns.result1
 }
 
-func (fake *FakeSwap) PathCallCount() int {
-	fake.pathMutex.RLock()
-	defer fake.pathMutex.RUnlock()
-	return len(fake.pathArgsForCall)
+func (fake *FakeSwap) HealthCallCount() int {
+	fake.healthMutex.RLock()
+	defer fake.healthMutex.RUnlock()
+	return len(fake.healthArgsForCall)
 }
 
-func (fake *FakeSwap) PathCalls(stub func() string) {
-	fake.pathMutex.Lock()
-	defer fake.pathMutex.Unlock()
-	fake.PathStub = stub
+func (fake *FakeSwap) HealthCalls(stub func() procswap.HealthProbe) {
+	fake.healthMutex.Lock()
+	defer fake.healthMutex.Unlock()
+	fake.HealthStub = stub
+}
+
+func (fake *FakeSwap) HealthReturns(result1 procswap.HealthProbe) {
+	fake.healthMutex.Lock()
+	defer fake.healthMutex.Unlock()
+	fake.HealthStub = nil
+	fake.healthReturns = struct {
+		result1 procswap.HealthProbe
+	}{result1}
+}
+
+func (fake *FakeSwap) HealthReturnsOnCall(i int, result1 procswap.HealthProbe) {
+	fake.healthMutex.Lock()
+	defer fake.healthMutex.Unlock()
+	fake.HealthStub = nil
+	if fake.healthReturnsOnCall == nil {
+		fake.healthReturnsOnCall = make(map[int]struct {
+			result1 procswap.HealthProbe
+		})
+	}
+	fake.healthReturnsOnCall[i] = struct {
+		result1 procswap.HealthProbe
+	}{result1}
+}
+
+func (fake *FakeSwap) History() []string {
+	fake.historyMutex.Lock()
+	ret, specificReturn := fake.historyReturnsOnCall[len(fake.historyArgsForCall)]
+	fake.historyArgsForCall = append(fake.historyArgsForCall, struct {
+	}{})
+	stub := fake.HistoryStub
+	fakeReturns := fake.historyReturns
+	fake.recordInvocation("History", []interface{}{})
+	fake.historyMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeSwap) HistoryCallCount() int {
+	fake.historyMutex.RLock()
+	defer fake.historyMutex.RUnlock()
+	return len(fake.historyArgsForCall)
+}
+
+func (fake *FakeSwap) HistoryCalls(stub func() []string) {
+	fake.historyMutex.Lock()
+	defer fake.historyMutex.Unlock()
+	fake.HistoryStub = stub
+}
+
+func (fake *FakeSwap) HistoryReturns(result1 []string) {
+	fake.historyMutex.Lock()
+	defer fake.historyMutex.Unlock()
+	fake.HistoryStub = nil
+	fake.historyReturns = struct {
+		result1 []string
+	}{result1}
+}
+
+func (fake *FakeSwap) HistoryReturnsOnCall(i int, result1 []string) {
+	fake.historyMutex.Lock()
+	defer fake.historyMutex.Unlock()
+	fake.HistoryStub = nil
+	if fake.historyReturnsOnCall == nil {
+		fake.historyReturnsOnCall = make(map[int]struct {
+			result1 []string
+		})
+	}
+	fake.historyReturnsOnCall[i] = struct {
+		result1 []string
+	}{result1}
+}
+
+func (fake *FakeSwap) Kill() error {
+	fake.killMutex.Lock()
+	ret, specificReturn := fake.killReturnsOnCall[len(fake.killArgsForCall)]
+	fake.killArgsForCall = append(fake.killArgsForCall, struct {
+	}{})
+	stub := fake.KillStub
+	fakeReturns := fake.killReturns
+	fake.recordInvocation("Kill", []interface{}{})
+	fake.killMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeSwap) KillCallCount() int {
+	fake.killMutex.RLock()
+	defer fake.killMutex.RUnlock()
+	return len(fake.killArgsForCall)
+}
+
+func (fake *FakeSwap) KillCalls(stub func() error) {
+	fake.killMutex.Lock()
+	defer fake.killMutex.Unlock()
+	fake.KillStub = stub
+}
+
+func (fake *FakeSwap) KillReturns(result1 error) {
+	fake.killMutex.Lock()
+	defer fake.killMutex.Unlock()
+	fake.KillStub = nil
+	fake.killReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeSwap) KillReturnsOnCall(i int, result1 error) {
+	fake.killMutex.Lock()
+	defer fake.killMutex.Unlock()
+	fake.KillStub = nil
+	if fake.killReturnsOnCall == nil {
+		fake.killReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.killReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeSwap) Mode() string {
+	fake.modeMutex.Lock()
+	ret, specificReturn := fake.modeReturnsOnCall[len(fake.modeArgsForCall)]
+	fake.modeArgsForCall = append(fake.modeArgsForCall, struct {
+	}{})
+	stub := fake.ModeStub
+	fakeReturns := fake.modeReturns
+	fake.recordInvocation("Mode", []interface{}{})
+	fake.modeMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeSwap) ModeCallCount() int {
+	fake.modeMutex.RLock()
+	defer fake.modeMutex.RUnlock()
+	return len(fake.modeArgsForCall)
+}
+
+func (fake *FakeSwap) ModeCalls(stub func() string) {
+	fake.modeMutex.Lock()
+	defer fake.modeMutex.Unlock()
+	fake.ModeStub = stub
+}
+
+func (fake *FakeSwap) ModeReturns(result1 string) {
+	fake.modeMutex.Lock()
+	defer fake.modeMutex.Unlock()
+	fake.ModeStub = nil
+	fake.modeReturns = struct {
+		result1 string
+	}{result1}
+}
+
+func (fake *FakeSwap) ModeReturnsOnCall(i int, result1 string) {
+	fake.modeMutex.Lock()
+	defer fake.modeMutex.Unlock()
+	fake.ModeStub = nil
+	if fake.modeReturnsOnCall == nil {
+		fake.modeReturnsOnCall = make(map[int]struct {
+			result1 string
+		})
+	}
+	fake.modeReturnsOnCall[i] = struct {
+		result1 string
+	}{result1}
+}
+
+func (fake *FakeSwap) Name() string {
+	fake.nameMutex.Lock()
+	ret, specificReturn := fake.nameReturnsOnCall[len(fake.nameArgsForCall)]
+	fake.nameArgsForCall = append(fake.nameArgsForCall, struct {
+	}{})
+	stub := fake.NameStub
+	fakeReturns := fake.nameReturns
+	fake.recordInvocation("Name", []interface{}{})
+	fake.nameMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeSwap) NameCallCount() int {
+	fake.nameMutex.RLock()
+	defer fake.nameMutex.RUnlock()
+	return len(fake.nameArgsForCall)
+}
+
+func (fake *FakeSwap) NameCalls(stub func() string) {
+	fake.nameMutex.Lock()
+	defer fake.nameMutex.Unlock()
+	fake.NameStub = stub
+}
+
+func (fake *FakeSwap) NameReturns(result1 string) {
+	fake.nameMutex.Lock()
+	defer fake.nameMutex.Unlock()
+	fake.NameStub = nil
+	fake.nameReturns = struct {
+		result1 string
+	}{result1}
+}
+
+func (fake *FakeSwap) NameReturnsOnCall(i int, result1 string) {
+	fake.nameMutex.Lock()
+	defer fake.nameMutex.Unlock()
+	fake.NameStub = nil
+	if fake.nameReturnsOnCall == nil {
+		fake.nameReturnsOnCall = make(map[int]struct {
+			result1 string
+		})
+	}
+	fake.nameReturnsOnCall[i] = struct {
+		result1 string
+	}{result1}
+}
+
+func (fake *FakeSwap) PID() int {
+	fake.pIDMutex.Lock()
+	ret, specificReturn := fake.pIDReturnsOnCall[len(fake.pIDArgsForCall)]
+	fake.pIDArgsForCall = append(fake.pIDArgsForCall, struct {
+	}{})
+	stub := fake.PIDStub
+	fakeReturns := fake.pIDReturns
+	fake.recordInvocation("PID", []interface{}{})
+	fake.pIDMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeSwap) PIDCallCount() int {
+	fake.pIDMutex.RLock()
+	defer fake.pIDMutex.RUnlock()
+	return len(fake.pIDArgsForCall)
+}
+
+func (fake *FakeSwap) PIDCalls(stub func() int) {
+	fake.pIDMutex.Lock()
+	defer fake.pIDMutex.Unlock()
+	fake.PIDStub = stub
+}
+
+func (fake *FakeSwap) PIDReturns(result1 int) {
+	fake.pIDMutex.Lock()
+	defer fake.pIDMutex.Unlock()
+	fake.PIDStub = nil
+	fake.pIDReturns = struct {
+		result1 int
+	}{result1}
+}
+
+func (fake *FakeSwap) PIDReturnsOnCall(i int, result1 int) {
+	fake.pIDMutex.Lock()
+	defer fake.pIDMutex.Unlock()
+	fake.PIDStub = nil
+	if fake.pIDReturnsOnCall == nil {
+		fake.pIDReturnsOnCall = make(map[int]struct {
+			result1 int
+		})
+	}
+	fake.pIDReturnsOnCall[i] = struct {
+		result1 int
+	}{result1}
+}
+
+func (fake *FakeSwap) Path() string {
+	fake.pathMutex.Lock()
+	ret, specificReturn := fake.pathReturnsOnCall[len(fake.pathArgsForCall)]
+	fake.pathArgsForCall = append(fake.pathArgsForCall, struct {
+	}{})
+	stub := fake.PathStub
+	fakeReturns := fake.pathReturns
+	fake.recordInvocation("Path", []interface{}{})
+	fake.pathMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeSwap) PathCallCount() int {
+	fake.pathMutex.RLock()
+	defer fake.pathMutex.RUnlock()
+	return len(fake.pathArgsForCall)
+}
+
+func (fake *FakeSwap) PathCalls(stub func() string) {
+	fake.pathMutex.Lock()
+	defer fake.pathMutex.Unlock()
+	fake.PathStub = stub
 }
 
 func (fake *FakeSwap) PathReturns(result1 string) {
@@ -266,61 +1041,703 @@ func (fake *FakeSwap) PathReturns(result1 string) {
 	}{result1}
 }
 
-func (fake *FakeSwap) PathReturnsOnCall(i int, result1 string) {
-	fake.pathMutex.Lock()
-	defer fake.pathMutex.Unlock()
-	fake.PathStub = nil
-	if fake.pathReturnsOnCall == nil {
-		fake.pathReturnsOnCall = make(map[int]struct {
-			result1 string
+func (fake *FakeSwap) PathReturnsOnCall(i int, result1 string) {
+	fake.pathMutex.Lock()
+	defer fake.pathMutex.Unlock()
+	fake.PathStub = nil
+	if fake.pathReturnsOnCall == nil {
+		fake.pathReturnsOnCall = make(map[int]struct {
+			result1 string
+		})
+	}
+	fake.pathReturnsOnCall[i] = struct {
+		result1 string
+	}{result1}
+}
+
+func (fake *FakeSwap) Pause() error {
+	fake.pauseMutex.Lock()
+	ret, specificReturn := fake.pauseReturnsOnCall[len(fake.pauseArgsForCall)]
+	fake.pauseArgsForCall = append(fake.pauseArgsForCall, struct {
+	}{})
+	stub := fake.PauseStub
+	fakeReturns := fake.pauseReturns
+	fake.recordInvocation("Pause", []interface{}{})
+	fake.pauseMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeSwap) PauseCallCount() int {
+	fake.pauseMutex.RLock()
+	defer fake.pauseMutex.RUnlock()
+	return len(fake.pauseArgsForCall)
+}
+
+func (fake *FakeSwap) PauseCalls(stub func() error) {
+	fake.pauseMutex.Lock()
+	defer fake.pauseMutex.Unlock()
+	fake.PauseStub = stub
+}
+
+func (fake *FakeSwap) PauseReturns(result1 error) {
+	fake.pauseMutex.Lock()
+	defer fake.pauseMutex.Unlock()
+	fake.PauseStub = nil
+	fake.pauseReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeSwap) PauseReturnsOnCall(i int, result1 error) {
+	fake.pauseMutex.Lock()
+	defer fake.pauseMutex.Unlock()
+	fake.PauseStub = nil
+	if fake.pauseReturnsOnCall == nil {
+		fake.pauseReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.pauseReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeSwap) Paused() bool {
+	fake.pausedMutex.Lock()
+	ret, specificReturn := fake.pausedReturnsOnCall[len(fake.pausedArgsForCall)]
+	fake.pausedArgsForCall = append(fake.pausedArgsForCall, struct {
+	}{})
+	stub := fake.PausedStub
+	fakeReturns := fake.pausedReturns
+	fake.recordInvocation("Paused", []interface{}{})
+	fake.pausedMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeSwap) PausedCallCount() int {
+	fake.pausedMutex.RLock()
+	defer fake.pausedMutex.RUnlock()
+	return len(fake.pausedArgsForCall)
+}
+
+func (fake *FakeSwap) PausedCalls(stub func() bool) {
+	fake.pausedMutex.Lock()
+	defer fake.pausedMutex.Unlock()
+	fake.PausedStub = stub
+}
+
+func (fake *FakeSwap) PausedReturns(result1 bool) {
+	fake.pausedMutex.Lock()
+	defer fake.pausedMutex.Unlock()
+	fake.PausedStub = nil
+	fake.pausedReturns = struct {
+		result1 bool
+	}{result1}
+}
+
+func (fake *FakeSwap) PausedReturnsOnCall(i int, result1 bool) {
+	fake.pausedMutex.Lock()
+	defer fake.pausedMutex.Unlock()
+	fake.PausedStub = nil
+	if fake.pausedReturnsOnCall == nil {
+		fake.pausedReturnsOnCall = make(map[int]struct {
+			result1 bool
+		})
+	}
+	fake.pausedReturnsOnCall[i] = struct {
+		result1 bool
+	}{result1}
+}
+
+func (fake *FakeSwap) Ready() <-chan struct{} {
+	fake.readyMutex.Lock()
+	ret, specificReturn := fake.readyReturnsOnCall[len(fake.readyArgsForCall)]
+	fake.readyArgsForCall = append(fake.readyArgsForCall, struct {
+	}{})
+	stub := fake.ReadyStub
+	fakeReturns := fake.readyReturns
+	fake.recordInvocation("Ready", []interface{}{})
+	fake.readyMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeSwap) ReadyCallCount() int {
+	fake.readyMutex.RLock()
+	defer fake.readyMutex.RUnlock()
+	return len(fake.readyArgsForCall)
+}
+
+func (fake *FakeSwap) ReadyCalls(stub func() <-chan struct{}) {
+	fake.readyMutex.Lock()
+	defer fake.readyMutex.Unlock()
+	fake.ReadyStub = stub
+}
+
+func (fake *FakeSwap) ReadyReturns(result1 <-chan struct{}) {
+	fake.readyMutex.Lock()
+	defer fake.readyMutex.Unlock()
+	fake.ReadyStub = nil
+	fake.readyReturns = struct {
+		result1 <-chan struct{}
+	}{result1}
+}
+
+func (fake *FakeSwap) ReadyReturnsOnCall(i int, result1 <-chan struct{}) {
+	fake.readyMutex.Lock()
+	defer fake.readyMutex.Unlock()
+	fake.ReadyStub = nil
+	if fake.readyReturnsOnCall == nil {
+		fake.readyReturnsOnCall = make(map[int]struct {
+			result1 <-chan struct{}
+		})
+	}
+	fake.readyReturnsOnCall[i] = struct {
+		result1 <-chan struct{}
+	}{result1}
+}
+
+func (fake *FakeSwap) Restart() procswap.RestartPolicy {
+	fake.restartMutex.Lock()
+	ret, specificReturn := fake.restartReturnsOnCall[len(fake.restartArgsForCall)]
+	fake.restartArgsForCall = append(fake.restartArgsForCall, struct {
+	}{})
+	stub := fake.RestartStub
+	fakeReturns := fake.restartReturns
+	fake.recordInvocation("Restart", []interface{}{})
+	fake.restartMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeSwap) RestartCallCount() int {
+	fake.restartMutex.RLock()
+	defer fake.restartMutex.RUnlock()
+	return len(fake.restartArgsForCall)
+}
+
+func (fake *FakeSwap) RestartCalls(stub func() procswap.RestartPolicy) {
+	fake.restartMutex.Lock()
+	defer fake.restartMutex.Unlock()
+	fake.RestartStub = stub
+}
+
+func (fake *FakeSwap) RestartReturns(result1 procswap.RestartPolicy) {
+	fake.restartMutex.Lock()
+	defer fake.restartMutex.Unlock()
+	fake.RestartStub = nil
+	fake.restartReturns = struct {
+		result1 procswap.RestartPolicy
+	}{result1}
+}
+
+func (fake *FakeSwap) RestartReturnsOnCall(i int, result1 procswap.RestartPolicy) {
+	fake.restartMutex.Lock()
+	defer fake.restartMutex.Unlock()
+	fake.RestartStub = nil
+	if fake.restartReturnsOnCall == nil {
+		fake.restartReturnsOnCall = make(map[int]struct {
+			result1 procswap.RestartPolicy
+		})
+	}
+	fake.restartReturnsOnCall[i] = struct {
+		result1 procswap.RestartPolicy
+	}{result1}
+}
+
+func (fake *FakeSwap) Resume() error {
+	fake.resumeMutex.Lock()
+	ret, specificReturn := fake.resumeReturnsOnCall[len(fake.resumeArgsForCall)]
+	fake.resumeArgsForCall = append(fake.resumeArgsForCall, struct {
+	}{})
+	stub := fake.ResumeStub
+	fakeReturns := fake.resumeReturns
+	fake.recordInvocation("Resume", []interface{}{})
+	fake.resumeMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeSwap) ResumeCallCount() int {
+	fake.resumeMutex.RLock()
+	defer fake.resumeMutex.RUnlock()
+	return len(fake.resumeArgsForCall)
+}
+
+func (fake *FakeSwap) ResumeCalls(stub func() error) {
+	fake.resumeMutex.Lock()
+	defer fake.resumeMutex.Unlock()
+	fake.ResumeStub = stub
+}
+
+func (fake *FakeSwap) ResumeReturns(result1 error) {
+	fake.resumeMutex.Lock()
+	defer fake.resumeMutex.Unlock()
+	fake.ResumeStub = nil
+	fake.resumeReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeSwap) ResumeReturnsOnCall(i int, result1 error) {
+	fake.resumeMutex.Lock()
+	defer fake.resumeMutex.Unlock()
+	fake.ResumeStub = nil
+	if fake.resumeReturnsOnCall == nil {
+		fake.resumeReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.resumeReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeSwap) ShowOutput(arg1 bool) {
+	fake.showOutputMutex.Lock()
+	fake.showOutputArgsForCall = append(fake.showOutputArgsForCall, struct {
+		arg1 bool
+	}{arg1})
+	stub := fake.ShowOutputStub
+	fake.recordInvocation("ShowOutput", []interface{}{arg1})
+	fake.showOutputMutex.Unlock()
+	if stub != nil {
+		fake.ShowOutputStub(arg1)
+	}
+}
+
+func (fake *FakeSwap) ShowOutputCallCount() int {
+	fake.showOutputMutex.RLock()
+	defer fake.showOutputMutex.RUnlock()
+	return len(fake.showOutputArgsForCall)
+}
+
+func (fake *FakeSwap) ShowOutputCalls(stub func(bool)) {
+	fake.showOutputMutex.Lock()
+	defer fake.showOutputMutex.Unlock()
+	fake.ShowOutputStub = stub
+}
+
+func (fake *FakeSwap) ShowOutputArgsForCall(i int) bool {
+	fake.showOutputMutex.RLock()
+	defer fake.showOutputMutex.RUnlock()
+	argsForCall := fake.showOutputArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeSwap) Start() error {
+	fake.startMutex.Lock()
+	ret, specificReturn := fake.startReturnsOnCall[len(fake.startArgsForCall)]
+	fake.startArgsForCall = append(fake.startArgsForCall, struct {
+	}{})
+	stub := fake.StartStub
+	fakeReturns := fake.startReturns
+	fake.recordInvocation("Start", []interface{}{})
+	fake.startMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeSwap) StartCallCount() int {
+	fake.startMutex.RLock()
+	defer fake.startMutex.RUnlock()
+	return len(fake.startArgsForCall)
+}
+
+func (fake *FakeSwap) StartCalls(stub func() error) {
+	fake.startMutex.Lock()
+	defer fake.startMutex.Unlock()
+	fake.StartStub = stub
+}
+
+func (fake *FakeSwap) StartReturns(result1 error) {
+	fake.startMutex.Lock()
+	defer fake.startMutex.Unlock()
+	fake.StartStub = nil
+	fake.startReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeSwap) StartReturnsOnCall(i int, result1 error) {
+	fake.startMutex.Lock()
+	defer fake.startMutex.Unlock()
+	fake.StartStub = nil
+	if fake.startReturnsOnCall == nil {
+		fake.startReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.startReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeSwap) Startup() procswap.Startup {
+	fake.startupMutex.Lock()
+	ret, specificReturn := fake.startupReturnsOnCall[len(fake.startupArgsForCall)]
+	fake.startupArgsForCall = append(fake.startupArgsForCall, struct {
+	}{})
+	stub := fake.StartupStub
+	fakeReturns := fake.startupReturns
+	fake.recordInvocation("Startup", []interface{}{})
+	fake.startupMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeSwap) StartupCallCount() int {
+	fake.startupMutex.RLock()
+	defer fake.startupMutex.RUnlock()
+	return len(fake.startupArgsForCall)
+}
+
+func (fake *FakeSwap) StartupCalls(stub func() procswap.Startup) {
+	fake.startupMutex.Lock()
+	defer fake.startupMutex.Unlock()
+	fake.StartupStub = stub
+}
+
+func (fake *FakeSwap) StartupReturns(result1 procswap.Startup) {
+	fake.startupMutex.Lock()
+	defer fake.startupMutex.Unlock()
+	fake.StartupStub = nil
+	fake.startupReturns = struct {
+		result1 procswap.Startup
+	}{result1}
+}
+
+func (fake *FakeSwap) StartupReturnsOnCall(i int, result1 procswap.Startup) {
+	fake.startupMutex.Lock()
+	defer fake.startupMutex.Unlock()
+	fake.StartupStub = nil
+	if fake.startupReturnsOnCall == nil {
+		fake.startupReturnsOnCall = make(map[int]struct {
+			result1 procswap.Startup
+		})
+	}
+	fake.startupReturnsOnCall[i] = struct {
+		result1 procswap.Startup
+	}{result1}
+}
+
+func (fake *FakeSwap) State() procswap.SwapState {
+	fake.stateMutex.Lock()
+	ret, specificReturn := fake.stateReturnsOnCall[len(fake.stateArgsForCall)]
+	fake.stateArgsForCall = append(fake.stateArgsForCall, struct {
+	}{})
+	stub := fake.StateStub
+	fakeReturns := fake.stateReturns
+	fake.recordInvocation("State", []interface{}{})
+	fake.stateMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeSwap) StateCallCount() int {
+	fake.stateMutex.RLock()
+	defer fake.stateMutex.RUnlock()
+	return len(fake.stateArgsForCall)
+}
+
+func (fake *FakeSwap) StateCalls(stub func() procswap.SwapState) {
+	fake.stateMutex.Lock()
+	defer fake.stateMutex.Unlock()
+	fake.StateStub = stub
+}
+
+func (fake *FakeSwap) StateReturns(result1 procswap.SwapState) {
+	fake.stateMutex.Lock()
+	defer fake.stateMutex.Unlock()
+	fake.StateStub = nil
+	fake.stateReturns = struct {
+		result1 procswap.SwapState
+	}{result1}
+}
+
+func (fake *FakeSwap) StateReturnsOnCall(i int, result1 procswap.SwapState) {
+	fake.stateMutex.Lock()
+	defer fake.stateMutex.Unlock()
+	fake.StateStub = nil
+	if fake.stateReturnsOnCall == nil {
+		fake.stateReturnsOnCall = make(map[int]struct {
+			result1 procswap.SwapState
+		})
+	}
+	fake.stateReturnsOnCall[i] = struct {
+		result1 procswap.SwapState
+	}{result1}
+}
+
+func (fake *FakeSwap) Stats() (procswap.SwapStats, error) {
+	fake.statsMutex.Lock()
+	ret, specificReturn := fake.statsReturnsOnCall[len(fake.statsArgsForCall)]
+	fake.statsArgsForCall = append(fake.statsArgsForCall, struct {
+	}{})
+	stub := fake.StatsStub
+	fakeReturns := fake.statsReturns
+	fake.recordInvocation("Stats", []interface{}{})
+	fake.statsMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeSwap) StatsCallCount() int {
+	fake.statsMutex.RLock()
+	defer fake.statsMutex.RUnlock()
+	return len(fake.statsArgsForCall)
+}
+
+func (fake *FakeSwap) StatsCalls(stub func() (procswap.SwapStats, error)) {
+	fake.statsMutex.Lock()
+	defer fake.statsMutex.Unlock()
+	fake.StatsStub = stub
+}
+
+func (fake *FakeSwap) StatsReturns(result1 procswap.SwapStats, result2 error) {
+	fake.statsMutex.Lock()
+	defer fake.statsMutex.Unlock()
+	fake.StatsStub = nil
+	fake.statsReturns = struct {
+		result1 procswap.SwapStats
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeSwap) StatsReturnsOnCall(i int, result1 procswap.SwapStats, result2 error) {
+	fake.statsMutex.Lock()
+	defer fake.statsMutex.Unlock()
+	fake.StatsStub = nil
+	if fake.statsReturnsOnCall == nil {
+		fake.statsReturnsOnCall = make(map[int]struct {
+			result1 procswap.SwapStats
+			result2 error
+		})
+	}
+	fake.statsReturnsOnCall[i] = struct {
+		result1 procswap.SwapStats
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeSwap) Stop() ([]string, error) {
+	fake.stopMutex.Lock()
+	ret, specificReturn := fake.stopReturnsOnCall[len(fake.stopArgsForCall)]
+	fake.stopArgsForCall = append(fake.stopArgsForCall, struct {
+	}{})
+	stub := fake.StopStub
+	fakeReturns := fake.stopReturns
+	fake.recordInvocation("Stop", []interface{}{})
+	fake.stopMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeSwap) StopCallCount() int {
+	fake.stopMutex.RLock()
+	defer fake.stopMutex.RUnlock()
+	return len(fake.stopArgsForCall)
+}
+
+func (fake *FakeSwap) StopCalls(stub func() ([]string, error)) {
+	fake.stopMutex.Lock()
+	defer fake.stopMutex.Unlock()
+	fake.StopStub = stub
+}
+
+func (fake *FakeSwap) StopReturns(result1 []string, result2 error) {
+	fake.stopMutex.Lock()
+	defer fake.stopMutex.Unlock()
+	fake.StopStub = nil
+	fake.stopReturns = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeSwap) StopReturnsOnCall(i int, result1 []string, result2 error) {
+	fake.stopMutex.Lock()
+	defer fake.stopMutex.Unlock()
+	fake.StopStub = nil
+	if fake.stopReturnsOnCall == nil {
+		fake.stopReturnsOnCall = make(map[int]struct {
+			result1 []string
+			result2 error
+		})
+	}
+	fake.stopReturnsOnCall[i] = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeSwap) Throttle() error {
+	fake.throttleMutex.Lock()
+	ret, specificReturn := fake.throttleReturnsOnCall[len(fake.throttleArgsForCall)]
+	fake.throttleArgsForCall = append(fake.throttleArgsForCall, struct {
+	}{})
+	stub := fake.ThrottleStub
+	fakeReturns := fake.throttleReturns
+	fake.recordInvocation("Throttle", []interface{}{})
+	fake.throttleMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeSwap) ThrottleCallCount() int {
+	fake.throttleMutex.RLock()
+	defer fake.throttleMutex.RUnlock()
+	return len(fake.throttleArgsForCall)
+}
+
+func (fake *FakeSwap) ThrottleCalls(stub func() error) {
+	fake.throttleMutex.Lock()
+	defer fake.throttleMutex.Unlock()
+	fake.ThrottleStub = stub
+}
+
+func (fake *FakeSwap) ThrottleReturns(result1 error) {
+	fake.throttleMutex.Lock()
+	defer fake.throttleMutex.Unlock()
+	fake.ThrottleStub = nil
+	fake.throttleReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeSwap) ThrottleReturnsOnCall(i int, result1 error) {
+	fake.throttleMutex.Lock()
+	defer fake.throttleMutex.Unlock()
+	fake.ThrottleStub = nil
+	if fake.throttleReturnsOnCall == nil {
+		fake.throttleReturnsOnCall = make(map[int]struct {
+			result1 error
 		})
 	}
-	fake.pathReturnsOnCall[i] = struct {
-		result1 string
+	fake.throttleReturnsOnCall[i] = struct {
+		result1 error
 	}{result1}
 }
 
-func (fake *FakeSwap) ShowOutput(arg1 bool) {
-	fake.showOutputMutex.Lock()
-	fake.showOutputArgsForCall = append(fake.showOutputArgsForCall, struct {
-		arg1 bool
-	}{arg1})
-	stub := fake.ShowOutputStub
-	fake.recordInvocation("ShowOutput", []interface{}{arg1})
-	fake.showOutputMutex.Unlock()
+func (fake *FakeSwap) Throttled() bool {
+	fake.throttledMutex.Lock()
+	ret, specificReturn := fake.throttledReturnsOnCall[len(fake.throttledArgsForCall)]
+	fake.throttledArgsForCall = append(fake.throttledArgsForCall, struct {
+	}{})
+	stub := fake.ThrottledStub
+	fakeReturns := fake.throttledReturns
+	fake.recordInvocation("Throttled", []interface{}{})
+	fake.throttledMutex.Unlock()
 	if stub != nil {
-		fake.ShowOutputStub(arg1)
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
 	}
+	return fakeReturns.result1
 }
 
-func (fake *FakeSwap) ShowOutputCallCount() int {
-	fake.showOutputMutex.RLock()
-	defer fake.showOutputMutex.RUnlock()
-	return len(fake.showOutputArgsForCall)
+func (fake *FakeSwap) ThrottledCallCount() int {
+	fake.throttledMutex.RLock()
+	defer fake.throttledMutex.RUnlock()
+	return len(fake.throttledArgsForCall)
 }
 
-func (fake *FakeSwap) ShowOutputCalls(stub func(bool)) {
-	fake.showOutputMutex.Lock()
-	defer fake.showOutputMutex.Unlock()
-	fake.ShowOutputStub = stub
+func (fake *FakeSwap) ThrottledCalls(stub func() bool) {
+	fake.throttledMutex.Lock()
+	defer fake.throttledMutex.Unlock()
+	fake.ThrottledStub = stub
 }
 
-func (fake *FakeSwap) ShowOutputArgsForCall(i int) bool {
-	fake.showOutputMutex.RLock()
-	defer fake.showOutputMutex.RUnlock()
-	argsForCall := fake.showOutputArgsForCall[i]
-	return argsForCall.arg1
+func (fake *FakeSwap) ThrottledReturns(result1 bool) {
+	fake.throttledMutex.Lock()
+	defer fake.throttledMutex.Unlock()
+	fake.ThrottledStub = nil
+	fake.throttledReturns = struct {
+		result1 bool
+	}{result1}
 }
 
-func (fake *FakeSwap) Start() error {
-	fake.startMutex.Lock()
-	ret, specificReturn := fake.startReturnsOnCall[len(fake.startArgsForCall)]
-	fake.startArgsForCall = append(fake.startArgsForCall, struct {
+func (fake *FakeSwap) ThrottledReturnsOnCall(i int, result1 bool) {
+	fake.throttledMutex.Lock()
+	defer fake.throttledMutex.Unlock()
+	fake.ThrottledStub = nil
+	if fake.throttledReturnsOnCall == nil {
+		fake.throttledReturnsOnCall = make(map[int]struct {
+			result1 bool
+		})
+	}
+	fake.throttledReturnsOnCall[i] = struct {
+		result1 bool
+	}{result1}
+}
+
+func (fake *FakeSwap) Unthrottle() error {
+	fake.unthrottleMutex.Lock()
+	ret, specificReturn := fake.unthrottleReturnsOnCall[len(fake.unthrottleArgsForCall)]
+	fake.unthrottleArgsForCall = append(fake.unthrottleArgsForCall, struct {
 	}{})
-	stub := fake.StartStub
-	fakeReturns := fake.startReturns
-	fake.recordInvocation("Start", []interface{}{})
-	fake.startMutex.Unlock()
+	stub := fake.UnthrottleStub
+	fakeReturns := fake.unthrottleReturns
+	fake.recordInvocation("Unthrottle", []interface{}{})
+	fake.unthrottleMutex.Unlock()
 	if stub != nil {
 		return stub()
 	}
@@ -330,56 +1747,618 @@ func (fake *FakeSwap) Start() error {
 	return fakeReturns.result1
 }
 
-func (fake *FakeSwap) StartCallCount() int {
-	fake.startMutex.RLock()
-	defer fake.startMutex.RUnlock()
-	return len(fake.startArgsForCall)
+func (fake *FakeSwap) UnthrottleCallCount() int {
+	fake.unthrottleMutex.RLock()
+	defer fake.unthrottleMutex.RUnlock()
+	return len(fake.unthrottleArgsForCall)
 }
 
-func (fake *FakeSwap) StartCalls(stub func() error) {
-	fake.startMutex.Lock()
-	defer fake.startMutex.Unlock()
-	fake.StartStub = stub
+func (fake *FakeSwap) UnthrottleCalls(stub func() error) {
+	fake.unthrottleMutex.Lock()
+	defer fake.unthrottleMutex.Unlock()
+	fake.UnthrottleStub = stub
 }
 
-func (fake *FakeSwap) StartReturns(result1 error) {
-	fake.startMutex.Lock()
-	defer fake.startMutex.Unlock()
-	fake.StartStub = nil
-	fake.startReturns = struct {
+func (fake *FakeSwap) UnthrottleReturns(result1 error) {
+	fake.unthrottleMutex.Lock()
+	defer fake.unthrottleMutex.Unlock()
+	fake.UnthrottleStub = nil
+	fake.unthrottleReturns = struct {
 		result1 error
 	}{result1}
 }
 
-func (fake *FakeSwap) StartReturnsOnCall(i int, result1 error) {
-	fake.startMutex.Lock()
-	defer fake.startMutex.Unlock()
-	fake.StartStub = nil
-	if fake.startReturnsOnCall == nil {
-		fake.startReturnsOnCall = make(map[int]struct {
+func (fake *FakeSwap) UnthrottleReturnsOnCall(i int, result1 error) {
+	fake.unthrottleMutex.Lock()
+	defer fake.unthrottleMutex.Unlock()
+	fake.UnthrottleStub = nil
+	if fake.unthrottleReturnsOnCall == nil {
+		fake.unthrottleReturnsOnCall = make(map[int]struct {
 			result1 error
 		})
 	}
-	fake.startReturnsOnCall[i] = struct {
+	fake.unthrottleReturnsOnCall[i] = struct {
 		result1 error
 	}{result1}
 }
 
+func (fake *FakeSwap) WithArgs(arg1 []string) {
+	var arg1Copy []string
+	if arg1 != nil {
+		arg1Copy = make([]string, len(arg1))
+		copy(arg1Copy, arg1)
+	}
+	fake.withArgsMutex.Lock()
+	fake.withArgsArgsForCall = append(fake.withArgsArgsForCall, struct {
+		arg1 []string
+	}{arg1Copy})
+	stub := fake.WithArgsStub
+	fake.recordInvocation("WithArgs", []interface{}{arg1Copy})
+	fake.withArgsMutex.Unlock()
+	if stub != nil {
+		fake.WithArgsStub(arg1)
+	}
+}
+
+func (fake *FakeSwap) WithArgsCallCount() int {
+	fake.withArgsMutex.RLock()
+	defer fake.withArgsMutex.RUnlock()
+	return len(fake.withArgsArgsForCall)
+}
+
+func (fake *FakeSwap) WithArgsCalls(stub func([]string)) {
+	fake.withArgsMutex.Lock()
+	defer fake.withArgsMutex.Unlock()
+	fake.WithArgsStub = stub
+}
+
+func (fake *FakeSwap) WithArgsArgsForCall(i int) []string {
+	fake.withArgsMutex.RLock()
+	defer fake.withArgsMutex.RUnlock()
+	argsForCall := fake.withArgsArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeSwap) WithCgroup(arg1 procswap.Cgroup) {
+	fake.withCgroupMutex.Lock()
+	fake.withCgroupArgsForCall = append(fake.withCgroupArgsForCall, struct {
+		arg1 procswap.Cgroup
+	}{arg1})
+	stub := fake.WithCgroupStub
+	fake.recordInvocation("WithCgroup", []interface{}{arg1})
+	fake.withCgroupMutex.Unlock()
+	if stub != nil {
+		fake.WithCgroupStub(arg1)
+	}
+}
+
+func (fake *FakeSwap) WithCgroupCallCount() int {
+	fake.withCgroupMutex.RLock()
+	defer fake.withCgroupMutex.RUnlock()
+	return len(fake.withCgroupArgsForCall)
+}
+
+func (fake *FakeSwap) WithCgroupCalls(stub func(procswap.Cgroup)) {
+	fake.withCgroupMutex.Lock()
+	defer fake.withCgroupMutex.Unlock()
+	fake.WithCgroupStub = stub
+}
+
+func (fake *FakeSwap) WithCgroupArgsForCall(i int) procswap.Cgroup {
+	fake.withCgroupMutex.RLock()
+	defer fake.withCgroupMutex.RUnlock()
+	argsForCall := fake.withCgroupArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeSwap) WithDir(arg1 string) {
+	fake.withDirMutex.Lock()
+	fake.withDirArgsForCall = append(fake.withDirArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.WithDirStub
+	fake.recordInvocation("WithDir", []interface{}{arg1})
+	fake.withDirMutex.Unlock()
+	if stub != nil {
+		fake.WithDirStub(arg1)
+	}
+}
+
+func (fake *FakeSwap) WithDirCallCount() int {
+	fake.withDirMutex.RLock()
+	defer fake.withDirMutex.RUnlock()
+	return len(fake.withDirArgsForCall)
+}
+
+func (fake *FakeSwap) WithDirCalls(stub func(string)) {
+	fake.withDirMutex.Lock()
+	defer fake.withDirMutex.Unlock()
+	fake.WithDirStub = stub
+}
+
+func (fake *FakeSwap) WithDirArgsForCall(i int) string {
+	fake.withDirMutex.RLock()
+	defer fake.withDirMutex.RUnlock()
+	argsForCall := fake.withDirArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeSwap) WithEnv(arg1 []string) {
+	var arg1Copy []string
+	if arg1 != nil {
+		arg1Copy = make([]string, len(arg1))
+		copy(arg1Copy, arg1)
+	}
+	fake.withEnvMutex.Lock()
+	fake.withEnvArgsForCall = append(fake.withEnvArgsForCall, struct {
+		arg1 []string
+	}{arg1Copy})
+	stub := fake.WithEnvStub
+	fake.recordInvocation("WithEnv", []interface{}{arg1Copy})
+	fake.withEnvMutex.Unlock()
+	if stub != nil {
+		fake.WithEnvStub(arg1)
+	}
+}
+
+func (fake *FakeSwap) WithEnvCallCount() int {
+	fake.withEnvMutex.RLock()
+	defer fake.withEnvMutex.RUnlock()
+	return len(fake.withEnvArgsForCall)
+}
+
+func (fake *FakeSwap) WithEnvCalls(stub func([]string)) {
+	fake.withEnvMutex.Lock()
+	defer fake.withEnvMutex.Unlock()
+	fake.WithEnvStub = stub
+}
+
+func (fake *FakeSwap) WithEnvArgsForCall(i int) []string {
+	fake.withEnvMutex.RLock()
+	defer fake.withEnvMutex.RUnlock()
+	argsForCall := fake.withEnvArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeSwap) WithHealth(arg1 procswap.HealthProbe) {
+	fake.withHealthMutex.Lock()
+	fake.withHealthArgsForCall = append(fake.withHealthArgsForCall, struct {
+		arg1 procswap.HealthProbe
+	}{arg1})
+	stub := fake.WithHealthStub
+	fake.recordInvocation("WithHealth", []interface{}{arg1})
+	fake.withHealthMutex.Unlock()
+	if stub != nil {
+		fake.WithHealthStub(arg1)
+	}
+}
+
+func (fake *FakeSwap) WithHealthCallCount() int {
+	fake.withHealthMutex.RLock()
+	defer fake.withHealthMutex.RUnlock()
+	return len(fake.withHealthArgsForCall)
+}
+
+func (fake *FakeSwap) WithHealthCalls(stub func(procswap.HealthProbe)) {
+	fake.withHealthMutex.Lock()
+	defer fake.withHealthMutex.Unlock()
+	fake.WithHealthStub = stub
+}
+
+func (fake *FakeSwap) WithHealthArgsForCall(i int) procswap.HealthProbe {
+	fake.withHealthMutex.RLock()
+	defer fake.withHealthMutex.RUnlock()
+	argsForCall := fake.withHealthArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeSwap) WithHistorySize(arg1 int) {
+	fake.withHistorySizeMutex.Lock()
+	fake.withHistorySizeArgsForCall = append(fake.withHistorySizeArgsForCall, struct {
+		arg1 int
+	}{arg1})
+	stub := fake.WithHistorySizeStub
+	fake.recordInvocation("WithHistorySize", []interface{}{arg1})
+	fake.withHistorySizeMutex.Unlock()
+	if stub != nil {
+		fake.WithHistorySizeStub(arg1)
+	}
+}
+
+func (fake *FakeSwap) WithHistorySizeCallCount() int {
+	fake.withHistorySizeMutex.RLock()
+	defer fake.withHistorySizeMutex.RUnlock()
+	return len(fake.withHistorySizeArgsForCall)
+}
+
+func (fake *FakeSwap) WithHistorySizeCalls(stub func(int)) {
+	fake.withHistorySizeMutex.Lock()
+	defer fake.withHistorySizeMutex.Unlock()
+	fake.WithHistorySizeStub = stub
+}
+
+func (fake *FakeSwap) WithHistorySizeArgsForCall(i int) int {
+	fake.withHistorySizeMutex.RLock()
+	defer fake.withHistorySizeMutex.RUnlock()
+	argsForCall := fake.withHistorySizeArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeSwap) WithMode(arg1 string) {
+	fake.withModeMutex.Lock()
+	fake.withModeArgsForCall = append(fake.withModeArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.WithModeStub
+	fake.recordInvocation("WithMode", []interface{}{arg1})
+	fake.withModeMutex.Unlock()
+	if stub != nil {
+		fake.WithModeStub(arg1)
+	}
+}
+
+func (fake *FakeSwap) WithModeCallCount() int {
+	fake.withModeMutex.RLock()
+	defer fake.withModeMutex.RUnlock()
+	return len(fake.withModeArgsForCall)
+}
+
+func (fake *FakeSwap) WithModeCalls(stub func(string)) {
+	fake.withModeMutex.Lock()
+	defer fake.withModeMutex.Unlock()
+	fake.WithModeStub = stub
+}
+
+func (fake *FakeSwap) WithModeArgsForCall(i int) string {
+	fake.withModeMutex.RLock()
+	defer fake.withModeMutex.RUnlock()
+	argsForCall := fake.withModeArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeSwap) WithName(arg1 string) {
+	fake.withNameMutex.Lock()
+	fake.withNameArgsForCall = append(fake.withNameArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.WithNameStub
+	fake.recordInvocation("WithName", []interface{}{arg1})
+	fake.withNameMutex.Unlock()
+	if stub != nil {
+		fake.WithNameStub(arg1)
+	}
+}
+
+func (fake *FakeSwap) WithNameCallCount() int {
+	fake.withNameMutex.RLock()
+	defer fake.withNameMutex.RUnlock()
+	return len(fake.withNameArgsForCall)
+}
+
+func (fake *FakeSwap) WithNameCalls(stub func(string)) {
+	fake.withNameMutex.Lock()
+	defer fake.withNameMutex.Unlock()
+	fake.WithNameStub = stub
+}
+
+func (fake *FakeSwap) WithNameArgsForCall(i int) string {
+	fake.withNameMutex.RLock()
+	defer fake.withNameMutex.RUnlock()
+	argsForCall := fake.withNameArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeSwap) WithOutputLog(arg1 procswap.OutputLog) {
+	fake.withOutputLogMutex.Lock()
+	fake.withOutputLogArgsForCall = append(fake.withOutputLogArgsForCall, struct {
+		arg1 procswap.OutputLog
+	}{arg1})
+	stub := fake.WithOutputLogStub
+	fake.recordInvocation("WithOutputLog", []interface{}{arg1})
+	fake.withOutputLogMutex.Unlock()
+	if stub != nil {
+		fake.WithOutputLogStub(arg1)
+	}
+}
+
+func (fake *FakeSwap) WithOutputLogCallCount() int {
+	fake.withOutputLogMutex.RLock()
+	defer fake.withOutputLogMutex.RUnlock()
+	return len(fake.withOutputLogArgsForCall)
+}
+
+func (fake *FakeSwap) WithOutputLogCalls(stub func(procswap.OutputLog)) {
+	fake.withOutputLogMutex.Lock()
+	defer fake.withOutputLogMutex.Unlock()
+	fake.WithOutputLogStub = stub
+}
+
+func (fake *FakeSwap) WithOutputLogArgsForCall(i int) procswap.OutputLog {
+	fake.withOutputLogMutex.RLock()
+	defer fake.withOutputLogMutex.RUnlock()
+	argsForCall := fake.withOutputLogArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeSwap) WithRestart(arg1 procswap.RestartPolicy) {
+	fake.withRestartMutex.Lock()
+	fake.withRestartArgsForCall = append(fake.withRestartArgsForCall, struct {
+		arg1 procswap.RestartPolicy
+	}{arg1})
+	stub := fake.WithRestartStub
+	fake.recordInvocation("WithRestart", []interface{}{arg1})
+	fake.withRestartMutex.Unlock()
+	if stub != nil {
+		fake.WithRestartStub(arg1)
+	}
+}
+
+func (fake *FakeSwap) WithRestartCallCount() int {
+	fake.withRestartMutex.RLock()
+	defer fake.withRestartMutex.RUnlock()
+	return len(fake.withRestartArgsForCall)
+}
+
+func (fake *FakeSwap) WithRestartCalls(stub func(procswap.RestartPolicy)) {
+	fake.withRestartMutex.Lock()
+	defer fake.withRestartMutex.Unlock()
+	fake.WithRestartStub = stub
+}
+
+func (fake *FakeSwap) WithRestartArgsForCall(i int) procswap.RestartPolicy {
+	fake.withRestartMutex.RLock()
+	defer fake.withRestartMutex.RUnlock()
+	argsForCall := fake.withRestartArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeSwap) WithRunAs(arg1 procswap.RunAs) {
+	fake.withRunAsMutex.Lock()
+	fake.withRunAsArgsForCall = append(fake.withRunAsArgsForCall, struct {
+		arg1 procswap.RunAs
+	}{arg1})
+	stub := fake.WithRunAsStub
+	fake.recordInvocation("WithRunAs", []interface{}{arg1})
+	fake.withRunAsMutex.Unlock()
+	if stub != nil {
+		fake.WithRunAsStub(arg1)
+	}
+}
+
+func (fake *FakeSwap) WithRunAsCallCount() int {
+	fake.withRunAsMutex.RLock()
+	defer fake.withRunAsMutex.RUnlock()
+	return len(fake.withRunAsArgsForCall)
+}
+
+func (fake *FakeSwap) WithRunAsCalls(stub func(procswap.RunAs)) {
+	fake.withRunAsMutex.Lock()
+	defer fake.withRunAsMutex.Unlock()
+	fake.WithRunAsStub = stub
+}
+
+func (fake *FakeSwap) WithRunAsArgsForCall(i int) procswap.RunAs {
+	fake.withRunAsMutex.RLock()
+	defer fake.withRunAsMutex.RUnlock()
+	argsForCall := fake.withRunAsArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeSwap) WithSpool(arg1 string) {
+	fake.withSpoolMutex.Lock()
+	fake.withSpoolArgsForCall = append(fake.withSpoolArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.WithSpoolStub
+	fake.recordInvocation("WithSpool", []interface{}{arg1})
+	fake.withSpoolMutex.Unlock()
+	if stub != nil {
+		fake.WithSpoolStub(arg1)
+	}
+}
+
+func (fake *FakeSwap) WithSpoolCallCount() int {
+	fake.withSpoolMutex.RLock()
+	defer fake.withSpoolMutex.RUnlock()
+	return len(fake.withSpoolArgsForCall)
+}
+
+func (fake *FakeSwap) WithSpoolCalls(stub func(string)) {
+	fake.withSpoolMutex.Lock()
+	defer fake.withSpoolMutex.Unlock()
+	fake.WithSpoolStub = stub
+}
+
+func (fake *FakeSwap) WithSpoolArgsForCall(i int) string {
+	fake.withSpoolMutex.RLock()
+	defer fake.withSpoolMutex.RUnlock()
+	argsForCall := fake.withSpoolArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeSwap) WithStartup(arg1 procswap.Startup) {
+	fake.withStartupMutex.Lock()
+	fake.withStartupArgsForCall = append(fake.withStartupArgsForCall, struct {
+		arg1 procswap.Startup
+	}{arg1})
+	stub := fake.WithStartupStub
+	fake.recordInvocation("WithStartup", []interface{}{arg1})
+	fake.withStartupMutex.Unlock()
+	if stub != nil {
+		fake.WithStartupStub(arg1)
+	}
+}
+
+func (fake *FakeSwap) WithStartupCallCount() int {
+	fake.withStartupMutex.RLock()
+	defer fake.withStartupMutex.RUnlock()
+	return len(fake.withStartupArgsForCall)
+}
+
+func (fake *FakeSwap) WithStartupCalls(stub func(procswap.Startup)) {
+	fake.withStartupMutex.Lock()
+	defer fake.withStartupMutex.Unlock()
+	fake.WithStartupStub = stub
+}
+
+func (fake *FakeSwap) WithStartupArgsForCall(i int) procswap.Startup {
+	fake.withStartupMutex.RLock()
+	defer fake.withStartupMutex.RUnlock()
+	argsForCall := fake.withStartupArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeSwap) WithStop(arg1 procswap.StopStrategy) {
+	fake.withStopMutex.Lock()
+	fake.withStopArgsForCall = append(fake.withStopArgsForCall, struct {
+		arg1 procswap.StopStrategy
+	}{arg1})
+	stub := fake.WithStopStub
+	fake.recordInvocation("WithStop", []interface{}{arg1})
+	fake.withStopMutex.Unlock()
+	if stub != nil {
+		fake.WithStopStub(arg1)
+	}
+}
+
+func (fake *FakeSwap) WithStopCallCount() int {
+	fake.withStopMutex.RLock()
+	defer fake.withStopMutex.RUnlock()
+	return len(fake.withStopArgsForCall)
+}
+
+func (fake *FakeSwap) WithStopCalls(stub func(procswap.StopStrategy)) {
+	fake.withStopMutex.Lock()
+	defer fake.withStopMutex.Unlock()
+	fake.WithStopStub = stub
+}
+
+func (fake *FakeSwap) WithStopArgsForCall(i int) procswap.StopStrategy {
+	fake.withStopMutex.RLock()
+	defer fake.withStopMutex.RUnlock()
+	argsForCall := fake.withStopArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeSwap) WithThrottle(arg1 procswap.Throttle) {
+	fake.withThrottleMutex.Lock()
+	fake.withThrottleArgsForCall = append(fake.withThrottleArgsForCall, struct {
+		arg1 procswap.Throttle
+	}{arg1})
+	stub := fake.WithThrottleStub
+	fake.recordInvocation("WithThrottle", []interface{}{arg1})
+	fake.withThrottleMutex.Unlock()
+	if stub != nil {
+		fake.WithThrottleStub(arg1)
+	}
+}
+
+func (fake *FakeSwap) WithThrottleCallCount() int {
+	fake.withThrottleMutex.RLock()
+	defer fake.withThrottleMutex.RUnlock()
+	return len(fake.withThrottleArgsForCall)
+}
+
+func (fake *FakeSwap) WithThrottleCalls(stub func(procswap.Throttle)) {
+	fake.withThrottleMutex.Lock()
+	defer fake.withThrottleMutex.Unlock()
+	fake.WithThrottleStub = stub
+}
+
+func (fake *FakeSwap) WithThrottleArgsForCall(i int) procswap.Throttle {
+	fake.withThrottleMutex.RLock()
+	defer fake.withThrottleMutex.RUnlock()
+	argsForCall := fake.withThrottleArgsForCall[i]
+	return argsForCall.arg1
+}
+
 func (fake *FakeSwap) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
+	fake.adoptMutex.RLock()
+	defer fake.adoptMutex.RUnlock()
+	fake.checkMutex.RLock()
+	defer fake.checkMutex.RUnlock()
+	fake.closeOutputLogMutex.RLock()
+	defer fake.closeOutputLogMutex.RUnlock()
 	fake.cmdMutex.RLock()
 	defer fake.cmdMutex.RUnlock()
+	fake.exitCodeMutex.RLock()
+	defer fake.exitCodeMutex.RUnlock()
+	fake.exitedMutex.RLock()
+	defer fake.exitedMutex.RUnlock()
+	fake.healthMutex.RLock()
+	defer fake.healthMutex.RUnlock()
+	fake.historyMutex.RLock()
+	defer fake.historyMutex.RUnlock()
 	fake.killMutex.RLock()
 	defer fake.killMutex.RUnlock()
+	fake.modeMutex.RLock()
+	defer fake.modeMutex.RUnlock()
+	fake.nameMutex.RLock()
+	defer fake.nameMutex.RUnlock()
 	fake.pIDMutex.RLock()
 	defer fake.pIDMutex.RUnlock()
 	fake.pathMutex.RLock()
 	defer fake.pathMutex.RUnlock()
+	fake.pauseMutex.RLock()
+	defer fake.pauseMutex.RUnlock()
+	fake.pausedMutex.RLock()
+	defer fake.pausedMutex.RUnlock()
+	fake.readyMutex.RLock()
+	defer fake.readyMutex.RUnlock()
+	fake.restartMutex.RLock()
+	defer fake.restartMutex.RUnlock()
+	fake.resumeMutex.RLock()
+	defer fake.resumeMutex.RUnlock()
 	fake.showOutputMutex.RLock()
 	defer fake.showOutputMutex.RUnlock()
 	fake.startMutex.RLock()
 	defer fake.startMutex.RUnlock()
+	fake.startupMutex.RLock()
+	defer fake.startupMutex.RUnlock()
+	fake.stateMutex.RLock()
+	defer fake.stateMutex.RUnlock()
+	fake.statsMutex.RLock()
+	defer fake.statsMutex.RUnlock()
+	fake.stopMutex.RLock()
+	defer fake.stopMutex.RUnlock()
+	fake.throttleMutex.RLock()
+	defer fake.throttleMutex.RUnlock()
+	fake.throttledMutex.RLock()
+	defer fake.throttledMutex.RUnlock()
+	fake.unthrottleMutex.RLock()
+	defer fake.unthrottleMutex.RUnlock()
+	fake.withArgsMutex.RLock()
+	defer fake.withArgsMutex.RUnlock()
+	fake.withCgroupMutex.RLock()
+	defer fake.withCgroupMutex.RUnlock()
+	fake.withDirMutex.RLock()
+	defer fake.withDirMutex.RUnlock()
+	fake.withEnvMutex.RLock()
+	defer fake.withEnvMutex.RUnlock()
+	fake.withHealthMutex.RLock()
+	defer fake.withHealthMutex.RUnlock()
+	fake.withHistorySizeMutex.RLock()
+	defer fake.withHistorySizeMutex.RUnlock()
+	fake.withModeMutex.RLock()
+	defer fake.withModeMutex.RUnlock()
+	fake.withNameMutex.RLock()
+	defer fake.withNameMutex.RUnlock()
+	fake.withOutputLogMutex.RLock()
+	defer fake.withOutputLogMutex.RUnlock()
+	fake.withRestartMutex.RLock()
+	defer fake.withRestartMutex.RUnlock()
+	fake.withRunAsMutex.RLock()
+	defer fake.withRunAsMutex.RUnlock()
+	fake.withSpoolMutex.RLock()
+	defer fake.withSpoolMutex.RUnlock()
+	fake.withStartupMutex.RLock()
+	defer fake.withStartupMutex.RUnlock()
+	fake.withStopMutex.RLock()
+	defer fake.withStopMutex.RUnlock()
+	fake.withThrottleMutex.RLock()
+	defer fake.withThrottleMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
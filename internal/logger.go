@@ -3,6 +3,7 @@ package procswap
 import (
 	"fmt"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/logrusorgru/aurora"
@@ -26,6 +27,17 @@ const (
 	logLevelWarn  = logLevel("WARN")
 	logLevelError = logLevel("ERROR")
 	logLevelFatal = logLevel("FATAL")
+
+	// logTimeFormat is the format of the time at the start of each log message.
+	logTimeFormat = "2006/01/02 - 15:04:05"
+)
+
+var (
+	// logMu keeps lines written to std out from interleaving.
+	logMu sync.Mutex
+	// pendingLine is the line logged without a newline that is waiting for
+	// logOK or logFailed to finish it, if any.
+	pendingLine string
 )
 
 // logWithLevel logs a given message in a nice format.
@@ -52,20 +64,46 @@ func logWithLevel(level logLevel, message string, newline ...bool) {
 		logColor = red
 	}
 
-	messageFormat := "%v |%s %-5s %s| %s"
+	line := fmt.Sprintf("%v |%s %-5s %s| %s",
+		time.Now().Format(logTimeFormat),
+		logColor, level, reset,
+		message,
+	)
+
 	if nl {
-		messageFormat += "\n"
+		printLine(line)
+
+		return
 	}
 
-	w := ansicolor.NewAnsiColorWriter(os.Stdout)
+	logMu.Lock()
+	defer logMu.Unlock()
 
+	w := ansicolor.NewAnsiColorWriter(os.Stdout)
+	if pendingLine != "" {
+		fmt.Fprintln(w)
+	}
 	// the log package always adds a newline even if one is not present, so
 	// just use fmt for this.
-	fmt.Fprintf(w, messageFormat,
-		time.Now().Format("2006/01/02 - 15:04:05"),
-		logColor, level, reset,
-		message,
-	)
+	fmt.Fprint(w, line)
+
+	pendingLine = line
+}
+
+// printLine prints a whole line to std out. A line still waiting for its
+// outcome is ended first and printed again after, so the outcome lands on it
+// instead of in the middle of something else.
+func printLine(line string) {
+	logMu.Lock()
+	defer logMu.Unlock()
+
+	w := ansicolor.NewAnsiColorWriter(os.Stdout)
+	if pendingLine != "" {
+		fmt.Fprintln(w)
+	}
+
+	fmt.Fprintln(w, line)
+	fmt.Fprint(w, pendingLine)
 }
 
 func logDebug(message string, newline ...bool) {
@@ -92,12 +130,21 @@ func logFatal(message string, newline ...bool) {
 
 // logOK logs "OK" in green followed by a newline.
 func logOK() {
-	w := ansicolor.NewAnsiColorWriter(os.Stdout)
-	fmt.Fprintf(w, " %s\n", aurora.Green("OK"))
+	finishLine(aurora.Green("OK"))
 }
 
 // logFailed logs "FAILED" in red followed by a newline.
 func logFailed() {
+	finishLine(aurora.Red("FAILED"))
+}
+
+// finishLine ends the line waiting for its outcome with the outcome.
+func finishLine(outcome aurora.Value) {
+	logMu.Lock()
+	defer logMu.Unlock()
+
 	w := ansicolor.NewAnsiColorWriter(os.Stdout)
-	fmt.Fprintf(w, " %s\n", aurora.Red("FAILED"))
+	fmt.Fprintf(w, " %s\n", outcome)
+
+	pendingLine = ""
 }
@@ -33,9 +33,26 @@ var _ = Describe("Logger", func() {
 			logDebug(message, false)
 		})
 
+		AfterEach(func() {
+			logOK()
+		})
+
 		It("logs the message without a newline on the end", func() {
 			Eventually(buffer).Should(Say(`\d{4}\/\d{2}\/\d{2} - \d{2}:\d{2}:\d{2} \|.*DEBUG.*\| test$`))
 		})
+
+		When("another line is logged before the outcome", func() {
+			JustBeforeEach(func() {
+				logWarn("miner stderr pool disconnected")
+				logOK()
+			})
+
+			It("logs the line on its own and the message again with its outcome", func() {
+				Eventually(buffer).Should(Say(`\| test\n`))
+				Eventually(buffer).Should(Say(`\|.*WARN.*\| miner stderr pool disconnected\n`))
+				Eventually(buffer).Should(Say(`\|.*DEBUG.*\| test .*OK.*\n`))
+			})
+		})
 	})
 
 	Describe("#logDebug", func() {
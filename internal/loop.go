@@ -2,9 +2,11 @@ package procswap
 
 import (
 	"fmt"
-	"os/exec"
-	"sort"
-	"strings"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/billiford/go-ps"
@@ -20,40 +22,66 @@ var (
 	currentSwapOutputIndex = -1
 )
 
+// truncatedNameLength is the length Linux truncates the names of processes
+// to.
+const truncatedNameLength = 15
+
 // Loop is the interface that runs indefinitely.
 type Loop interface {
-	Run()
+	Reload([]Group)
+	Run() int
 	WithActionsEnabled(bool)
+	WithDryRun(bool)
+	WithGroups([]Group)
+	WithInspector(Inspector)
+	WithLeaveSwapsRunning(bool)
 	WithLimit(int)
 	WithPollInterval(int)
 	WithPriorities([]*godirwalk.Dirent)
 	WithPriorityScript(string)
 	WithPs(ps.Ps)
+	WithRules([]Rule)
+	WithShutdownSignals(...os.Signal)
+	WithStateFile(string)
 	WithSwaps([]Swap)
 }
 
-// loop holds the priority executables and swap processes defined at startup.
+// loop holds the groups of priority executables and swap processes defined at startup.
 type loop struct {
+	// mu guards the loop's state, which can be reloaded while the loop is running.
+	mu sync.Mutex
 	// limit is the limit the loop (polling windows ps) will run; less than 1 is infinite times.
 	limit int
 	// internal storage of how many times we've looped.
 	loopCount int
 	// poll interval sets how much time in seconds we wait before polling the windows processes.
 	pollInterval int
-	// list of priorities defined at startup.
-	priorities []*godirwalk.Dirent
-	// a script that will run when any priority starts
-	priorityScript string
+	// list of groups, each with their own priorities and swaps.
+	groups []*group
 	// ps is the interface for listing processes
 	ps ps.Ps
-	// if the swap scripts have been started or not.
-	started bool
-	// list of paths to swap scripts.
-	swaps []Swap
-	// list of currently running swaps.
-	runningSwaps []Swap
+	// inspector looks up the paths and command lines of running processes.
+	inspector Inspector
 	// actionsEnabled defines if actions are enabled or not.
 	actionsEnabled bool
+	// dryRun logs what the loop would do without starting or stopping anything.
+	dryRun bool
+	// stateFile is where the loop keeps its running swaps, so they can be
+	// adopted when procswap restarts. Nothing is kept when it is empty.
+	stateFile string
+	// savedState is what was last written to the state file.
+	savedState []byte
+	// shutdownSignals are the signals that make the loop stop its swaps and exit.
+	shutdownSignals []os.Signal
+	// leaveSwapsRunning leaves the swaps running when the loop exits on a signal.
+	leaveSwapsRunning bool
+	// signals receives the shutdown signals, and Ctrl+C pressed while key
+	// input is read.
+	signals chan os.Signal
+	// quit is closed once the loop is shutting down, keysDone once key input
+	// is no longer read.
+	quit     chan struct{}
+	keysDone chan struct{}
 	// actions is a map of key input to action.
 	actions map[rune]action
 }
@@ -68,17 +96,35 @@ type action struct {
 func NewLoop() Loop {
 	// Define the loop.
 	loop := &loop{
-		swaps:        []Swap{},
-		priorities:   []*godirwalk.Dirent{},
 		limit:        0,
 		loopCount:    0,
 		ps:           ps.New(),
+		inspector:    NewInspector(),
 		pollInterval: defaultPollInterval,
-		runningSwaps: []Swap{},
+		// Ctrl+C and kill. SIGHUP is left to reload the config.
+		shutdownSignals: []os.Signal{os.Interrupt, syscall.SIGTERM},
+		signals:         make(chan os.Signal, 1),
+		quit:            make(chan struct{}),
+		keysDone:        make(chan struct{}),
+	}
+	loop.groups = []*group{
+		loop.newGroup(Group{
+			Name:       defaultGroupName,
+			Priorities: []*godirwalk.Dirent{},
+			Swaps:      []Swap{},
+		}),
 	}
 	// Define the actions for the loop. Perhaps this should be defined
 	// in main and we should provide a `WithActions(...)` setter function.
 	actions := map[rune]action{
+		'h': {
+			Description: "dump the recent output of the swap process whose output is shown",
+			F:           loop.dumpHistory,
+		},
+		'l': {
+			Description: "list the state of every swap process",
+			F:           loop.listSwaps,
+		},
 		's': {
 			Description: "switch console output of swap processes",
 			F:           loop.switchOutput,
@@ -92,55 +138,180 @@ func NewLoop() Loop {
 
 // WithActionsEnabled enables or disables actions.
 func (l *loop) WithActionsEnabled(actionsEnabled bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
 	l.actionsEnabled = actionsEnabled
 }
 
+// WithDryRun enables or disables dry run. In a dry run the loop logs every
+// swap it would start or stop and every priority script it would run, but
+// doesn't actually do any of it.
+func (l *loop) WithDryRun(dryRun bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.dryRun = dryRun
+	for _, g := range l.groups {
+		g.dryRun = dryRun
+	}
+}
+
+// WithGroups sets the groups for the loop, replacing any existing groups.
+func (l *loop) WithGroups(groups []Group) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.groups = []*group{}
+	for _, g := range groups {
+		l.groups = append(l.groups, l.newGroup(g))
+	}
+}
+
+// newGroup returns a new group that inherits the loop's settings.
+func (l *loop) newGroup(def Group) *group {
+	g := newGroup(def, &l.mu)
+	g.dryRun = l.dryRun
+
+	return g
+}
+
 // WithLimit sets a limit on the loop.
 func (l *loop) WithLimit(limit int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
 	l.limit = limit
 }
 
 // WithPollInterval sets the poll interval on the loop.
 func (l *loop) WithPollInterval(pollInterval int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
 	l.pollInterval = pollInterval
 }
 
-// WithPriorities sets the priority processes for the loop.
+// WithPriorities sets the priority processes for the loop's default group.
 func (l *loop) WithPriorities(priorities []*godirwalk.Dirent) {
-	l.priorities = priorities
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.defaultGroup().Priorities = priorities
 }
 
-// WithPriorityScript sets the priority script for the loop.
+// WithPriorityScript sets the priority script for the loop's default group.
 func (l *loop) WithPriorityScript(priorityScript string) {
-	l.priorityScript = priorityScript
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.defaultGroup().PriorityScript = priorityScript
+}
+
+// WithInspector sets what looks up the paths and command lines of running processes.
+func (l *loop) WithInspector(inspector Inspector) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inspector = inspector
 }
 
 // WithPs sets the package that will list windows processes.
 func (l *loop) WithPs(ps ps.Ps) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
 	l.ps = ps
 }
 
-// WithSwaps sets the swap scripts/executables for the loop.
+// WithRules sets the priority rules for the loop's default group.
+func (l *loop) WithRules(rules []Rule) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.defaultGroup().Rules = rules
+}
+
+// WithSwaps sets the swap scripts/executables for the loop's default group.
 func (l *loop) WithSwaps(swaps []Swap) {
-	l.swaps = swaps
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.defaultGroup().Swaps = swaps
+}
+
+// defaultGroup returns the default group, adding it to the loop if
+// it has been replaced by named groups.
+func (l *loop) defaultGroup() *group {
+	for _, g := range l.groups {
+		if g.Name == defaultGroupName {
+			return g
+		}
+	}
+
+	g := l.newGroup(Group{Name: defaultGroupName})
+	l.groups = append([]*group{g}, l.groups...)
+
+	return g
+}
+
+// runningSwaps returns the running swaps of every group. Swaps that have
+// exited since the last poll are left out.
+func (l *loop) runningSwaps() []Swap {
+	runningSwaps := []Swap{}
+
+	for _, g := range l.groups {
+		for _, s := range g.runningSwaps {
+			if !s.State().done() {
+				runningSwaps = append(runningSwaps, s)
+			}
+		}
+	}
+
+	return runningSwaps
+}
+
+// listSwaps logs the state of every swap of every group, along with the
+// resource usage of running swaps that have a cgroup of their own.
+func (l *loop) listSwaps() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, g := range l.groups {
+		for _, s := range g.Swaps {
+			state := describeState(s)
+
+			if s.State() != SwapStateNew && !s.State().done() {
+				if stats, err := s.Stats(); err == nil {
+					state += ", " + stats.String()
+				}
+			}
+
+			logInfo(fmt.Sprintf("%s %s%s %s", aurora.Magenta("action"), g.prefix(), aurora.Bold(s.Path()), state))
+		}
+	}
 }
 
 // switchOutput switches the output of running swaps to std out.
 func (l *loop) switchOutput() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	runningSwaps := l.runningSwaps()
 	// If there are no currently running swaps, just log this and return.
-	if len(l.runningSwaps) == 0 {
+	if len(runningSwaps) == 0 {
 		logInfo(fmt.Sprintf("%s no running swaps; ignoring", aurora.Magenta("action")))
 
 		return
 	}
 	// Hide all outputs.
-	for _, swap := range l.runningSwaps {
+	for _, swap := range runningSwaps {
 		swap.ShowOutput(false)
 	}
 	// Increase the current swap output index.
 	currentSwapOutputIndex++
 	// If we've gone through all the swap outputs, just hide all outputs.
-	if len(l.runningSwaps) <= currentSwapOutputIndex {
+	if len(runningSwaps) <= currentSwapOutputIndex {
 		logInfo(fmt.Sprintf("%s hiding all swap output", aurora.Magenta("action")))
 		// Reset index to procswap.
 		currentSwapOutputIndex = -1
@@ -148,26 +319,107 @@ func (l *loop) switchOutput() {
 		return
 	}
 	// Get the output for the current swap output index.
-	swap := l.runningSwaps[currentSwapOutputIndex]
+	swap := runningSwaps[currentSwapOutputIndex]
 	// Let the user know we're showing output for this particular swap.
 	logInfo(fmt.Sprintf("%s showing output for %s", aurora.Magenta("action"), aurora.Bold(swap.Path())))
 
 	swap.ShowOutput(true)
 }
 
+// dumpHistory prints the recent output of the swap picked with switchOutput
+// again, say after scrolling away from it.
+func (l *loop) dumpHistory() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	runningSwaps := l.runningSwaps()
+	if currentSwapOutputIndex < 0 || len(runningSwaps) <= currentSwapOutputIndex {
+		logInfo(fmt.Sprintf("%s no swap output shown, press s to pick a swap; ignoring", aurora.Magenta("action")))
+
+		return
+	}
+
+	swap := runningSwaps[currentSwapOutputIndex]
+	history := swap.History()
+
+	logInfo(fmt.Sprintf("%s last %d lines of output for %s", aurora.Magenta("action"), len(history), aurora.Bold(swap.Path())))
+
+	for _, line := range history {
+		printLine(line)
+	}
+}
+
+// Reload replaces the groups of the loop while it is running. Groups are
+// matched by name: removed groups have their running swaps stopped, new groups
+// are started on the next poll and existing groups reconcile their swaps,
+// leaving any swap that is in both the old and new group running.
+func (l *loop) Reload(groups []Group) {
+	l.mu.Lock()
+
+	current := map[string]*group{}
+	for _, g := range l.groups {
+		current[g.Name] = g
+	}
+
+	reloaded := []*group{}
+	blocking := []func(){}
+
+	for _, def := range groups {
+		g, ok := current[def.Name]
+		if !ok {
+			reloaded = append(reloaded, l.newGroup(def))
+
+			continue
+		}
+
+		delete(current, def.Name)
+
+		if work := g.reload(def); work != nil {
+			blocking = append(blocking, work)
+		}
+
+		reloaded = append(reloaded, g)
+	}
+
+	// Anything left over has been removed.
+	for _, g := range l.groups {
+		if _, ok := current[g.Name]; ok {
+			blocking = append(blocking, g.stopSwaps())
+		}
+	}
+
+	l.groups = reloaded
+	l.mu.Unlock()
+	// Stopping swaps can take a while, so do it without holding the lock,
+	// the same as run does.
+	for _, work := range blocking {
+		work()
+	}
+}
+
 // Run runs the main loop. It gathers all "priority processes" and runs any swap processes
 // when any of these priority processes are not running.
 //
 // If any priority process starts, all swap processes are killed. When all priority processes
 // stop, all swap processes are kicked off again.
-func (l *loop) Run() {
+//
+// Run returns 0 once the loop has run its limit. When a shutdown signal is
+// received it stops every swap and returns the exit code for the signal.
+func (l *loop) Run() int {
+	signal.Notify(l.signals, l.shutdownSignals...)
+	defer signal.Stop(l.signals)
+
 	if l.actionsEnabled {
 		// Inform the user of the inputs allowed.
 		l.printInputDescriptions()
 		// Listen for key input in the background.
 		go l.listenForKeyInput()
+	} else {
+		close(l.keysDone)
 	}
 
+	// Adopt the swaps a previous procswap left running.
+	l.adoptSwaps()
 	// Main loop.
 	for {
 		if l.done() {
@@ -175,8 +427,13 @@ func (l *loop) Run() {
 		}
 
 		l.run()
-		l.wait()
+
+		if sig := l.wait(); sig != nil {
+			return l.shutdown(sig)
+		}
 	}
+
+	return 0
 }
 
 func (l *loop) printInputDescriptions() {
@@ -185,14 +442,34 @@ func (l *loop) printInputDescriptions() {
 	}
 }
 
-// listenForKeyInput listens for any key input forever.
+// listenForKeyInput listens for any key input until the loop shuts down.
 // If the input is mapped to some action, procwap will perform this action.
 func (l *loop) listenForKeyInput() {
-	// Loop forever.
+	defer close(l.keysDone)
+
 	for {
+		select {
+		case <-l.quit:
+			return
+		default:
+		}
 		// Get key input, for example user has pressed 's'.
-		char, _, err := keyboard.GetSingleKey()
+		char, key, err := keyboard.GetSingleKey()
+		// The terminal is in raw mode while a key is read, so Ctrl+C is a
+		// key rather than a signal.
+		if key == keyboard.KeyCtrlC {
+			l.interrupt()
+
+			return
+		}
+
 		if err != nil {
+			// Reading the key was cancelled to shut down.
+			select {
+			case <-l.quit:
+				return
+			default:
+			}
 			// Show a warning that there was an error getting key input.
 			logWarn(fmt.Sprintf("error getting key input: %s", err.Error()))
 			// Continue so this is non-blocking.
@@ -205,33 +482,35 @@ func (l *loop) listenForKeyInput() {
 	}
 }
 
-// run runs the main loop. Swap running processes for priority executables or
-// start the swap processes if no priority process is running and they have not
-// already been started.
+// run runs the main loop. It lists the running processes once and lets each
+// group swap its processes accordingly.
 func (l *loop) run() {
 	defer l.incCount()
 
-	// List running priorities from the current processes running.
-	runningPriorities := l.listRunningPriorities()
+	table, err := l.listProcesses()
+	if err != nil {
+		logError(fmt.Sprintf("error listing currently running processes: %s", err.Error()))
+
+		return
+	}
 
-	switch {
-	case len(runningPriorities) > 0 && !l.started && l.loopCount == 0:
-		// It is our first loop and priority processes are already running so log this.
-		logWarn(fmt.Sprintf("not starting swap processes, priority processes already running: %s",
-			aurora.Bold(strings.Join(runningPriorities, ", "))))
-	case len(runningPriorities) > 0 && l.started:
-		// Do this if there are any priorities started and we need to stop all running swap processes.
-		logInfo(fmt.Sprintf("%s %s", aurora.Yellow("priority"), aurora.Bold(strings.Join(runningPriorities, ", "))))
+	l.mu.Lock()
+
+	blocking := []func(){}
+
+	for _, g := range l.groups {
+		if work := g.run(table, l.loopCount == 0); work != nil {
+			blocking = append(blocking, work)
+		}
+	}
 
-		// It might make sense to set swap scripts to either started or not inside their functions,
-		// but I think ths is more explicit.
-		l.stop()
-		l.stopSwaps()
-		l.startPriorityScript()
-	case len(runningPriorities) == 0 && !l.started:
-		// Do this when there are no priorities started and we need to start all the swap processes.
-		l.start()
-		l.startSwaps()
+	l.saveState()
+	l.mu.Unlock()
+	// Stopping swaps and running priority scripts can take a while, so do it
+	// without holding the lock to keep key actions, reloads and status
+	// queries responsive in the meantime.
+	for _, work := range blocking {
+		work()
 	}
 }
 
@@ -239,125 +518,121 @@ func (l *loop) incCount() {
 	l.loopCount++
 }
 
-// listRunningPriorities takes in a list of currently running
-// priorities and makes a list of any user-defined priorities that are
-// running.
-func (l *loop) listRunningPriorities() []string {
+// listProcesses returns a table of all running processes.
+func (l *loop) listProcesses() (*processTable, error) {
 	// This seems to be a fairly cheap call to check the running processes.
 	// It would be nice to just have a watch.
 	processes, err := l.ps.Processes()
 	if err != nil {
-		logError(fmt.Sprintf("error listing currently running processes: %s", err.Error()))
-
-		return nil
+		return nil, err
 	}
 
+	return newProcessTable(processes, l.inspector), nil
+}
+
+// processTable is a snapshot of the running processes for a single poll. The
+// paths and command lines of processes are only looked up when a group
+// needs them, then cached.
+type processTable struct {
+	processes []ps.Process
+	// names is a set of the executable names of the processes.
+	names map[string]bool
+	// fullNames is names along with the untruncated names of the processes
+	// whose name may have been truncated, it is filled in on first use.
+	fullNames map[string]bool
+	inspector Inspector
+	paths     map[int]string
+	cmdlines  map[int]string
+}
+
+func newProcessTable(processes []ps.Process, inspector Inspector) *processTable {
 	// Make a map of the processes so the lookup is O(1).
-	processMap := map[string]bool{}
+	names := map[string]bool{}
 	for _, process := range processes {
-		processMap[process.Executable()] = true
+		names[process.Executable()] = true
 	}
 
-	prioritiesMap := map[string]bool{}
-	// Check if an executable has started that we want to take priority over
-	// our swap processes.
-	for _, priority := range l.priorities {
-		if processMap[priority.Name()] {
-			prioritiesMap[priority.Name()] = true
-		}
+	return &processTable{
+		processes: processes,
+		names:     names,
+		inspector: inspector,
+		paths:     map[int]string{},
+		cmdlines:  map[int]string{},
 	}
+}
 
-	// Generate a slice of currently running priorities.
-	priorities := make([]string, 0, len(prioritiesMap))
-	for k := range prioritiesMap {
-		priorities = append(priorities, k)
+// allNames returns the set of executable names of the processes. Linux
+// truncates the name of a process to 15 characters, so a process whose name is
+// that long is also listed by the name of the executable its path points to.
+func (t *processTable) allNames() map[string]bool {
+	if t.fullNames != nil {
+		return t.fullNames
 	}
 
-	sort.Strings(priorities)
+	t.fullNames = map[string]bool{}
 
-	return priorities
-}
+	for _, process := range t.processes {
+		name := process.Executable()
+		t.fullNames[name] = true
 
-func (l *loop) wait() {
-	time.Sleep(time.Duration(l.pollInterval) * time.Second)
-}
-
-func (l *loop) stop() {
-	l.started = false
-}
-
-func (l *loop) start() {
-	l.started = true
-}
+		if len(name) < truncatedNameLength {
+			continue
+		}
 
-func (l *loop) done() bool {
-	if l.limit < 1 {
-		return false
+		if path := t.path(process); path != "" {
+			t.fullNames[filepath.Base(path)] = true
+		}
 	}
 
-	return l.limit == l.loopCount
+	return t.fullNames
 }
 
-func (l *loop) startSwaps() {
-	for _, s := range l.swaps {
-		// Print this without a newline at the end since we'll be printing the status later.
-		logInfo(fmt.Sprintf("%s %s...", aurora.Green("start"), aurora.Bold(s.Path())), false)
+// path returns the full path of the process' executable. It returns an empty
+// string if the path can't be read, which is common for processes of other
+// users.
+func (t *processTable) path(process ps.Process) string {
+	if path, ok := t.paths[process.Pid()]; ok {
+		return path
+	}
 
-		err := s.Start()
-		if err != nil {
-			logFailed()
-			logError(fmt.Sprintf("error starting swap process %s: %s", s.Path(), err.Error()))
+	path, _ := t.inspector.Path(process.Pid())
+	t.paths[process.Pid()] = path
 
-			continue
-		}
-
-		logOK()
+	return path
+}
 
-		l.runningSwaps = append(l.runningSwaps, s)
+// cmdline returns the command line of the process, or an empty string if it
+// can't be read.
+func (t *processTable) cmdline(process ps.Process) string {
+	if cmdline, ok := t.cmdlines[process.Pid()]; ok {
+		return cmdline
 	}
-}
 
-// stopSwaps kills all running swap processes. It finds any child processes
-// started by the swap process and attempts to kill those, then kills the main
-// process.
-//
-// We should really build a process ID tree here, but for now the killing of child
-// processes is pretty simple.
-func (l *loop) stopSwaps() {
-	// Loop through and kill the running swaps.
-	for _, swap := range l.runningSwaps {
-		logInfo(fmt.Sprintf("%s %s...", aurora.Red("stop"), aurora.Bold(swap.Path())), false)
-
-		err := swap.Kill()
-		if err != nil {
-			logFailed()
-			logError(err.Error())
+	cmdline, _ := t.inspector.Cmdline(process.Pid())
+	t.cmdlines[process.Pid()] = cmdline
 
-			continue
-		}
+	return cmdline
+}
 
-		logOK()
+// wait waits for the poll interval, returning early with the signal if a
+// shutdown signal is received.
+func (l *loop) wait() os.Signal {
+	l.mu.Lock()
+	pollInterval := l.pollInterval
+	l.mu.Unlock()
+
+	select {
+	case sig := <-l.signals:
+		return sig
+	case <-time.After(time.Duration(pollInterval) * time.Second):
+		return nil
 	}
-	// Since we're shutting down everything, reset the currently running commands.
-	l.runningSwaps = []Swap{}
 }
 
-// startPriorityScript starts a given priority script. It waits for the command to complete, which
-// is different than swaps which are started then stopped if a priority process begins running.
-func (l *loop) startPriorityScript() {
-	// If no priority script is set, just return.
-	if l.priorityScript == "" {
-		return
+func (l *loop) done() bool {
+	if l.limit < 1 {
+		return false
 	}
 
-	logInfo(fmt.Sprintf("%s %s...", aurora.Magenta("priority script"), aurora.Bold(l.priorityScript)), false)
-
-	cmd := exec.Command(l.priorityScript)
-	if err := cmd.Run(); err != nil {
-		// If there is an error running the priority script, just log it and let the loop continue.
-		logFailed()
-		logError(err.Error())
-	} else {
-		logOK()
-	}
+	return l.limit == l.loopCount
 }
@@ -2,8 +2,14 @@ package procswap_test
 
 import (
 	"errors"
+	"fmt"
+	"io/ioutil"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
+	"strconv"
+	"syscall"
 	"time"
 
 	"github.com/google/uuid"
@@ -29,6 +35,7 @@ var _ = Describe("Loop", func() {
 		fakePs         *gopsfakes.FakePs
 		fakeProcess    *gopsfakes.FakeProcess
 		fakeSwap       *internalfakes.FakeSwap
+		exitCode       int
 		prioritiesPath string
 		ignored        []string
 		err            error
@@ -53,7 +60,7 @@ var _ = Describe("Loop", func() {
 		prioritiesPath = filepath.FromSlash(currentDir() + "/test/priorities")
 
 		ignored = []string{}
-		execs, err := ProcessList(prioritiesPath, ignored)
+		execs, err := ProcessList(prioritiesPath, ignored, Discovery{})
 		Expect(err).To(BeNil())
 
 		loop.WithPriorities(execs)
@@ -74,7 +81,7 @@ var _ = Describe("Loop", func() {
 	})
 
 	JustBeforeEach(func() {
-		loop.Run()
+		exitCode = loop.Run()
 	})
 
 	AfterEach(func() {
@@ -109,6 +116,134 @@ var _ = Describe("Loop", func() {
 			})
 		})
 
+		Context("when a priority rule matches a running process", func() {
+			BeforeEach(func() {
+				loop.WithPriorities(nil)
+				glob, err := NewGlobRule("*-WIN64-Shipping.exe")
+				Expect(err).To(BeNil())
+				regex, err := NewRegexRule("^eldenring")
+				Expect(err).To(BeNil())
+				loop.WithRules([]Rule{glob, regex})
+
+				eldenRing := &gopsfakes.FakeProcess{}
+				eldenRing.ExecutableReturns("EldenRing.exe")
+				fakeProcess.ExecutableReturns("Game-Win64-Shipping.exe")
+				fakePs.ProcessesReturns([]ps.Process{fakeProcess, eldenRing}, nil)
+			})
+
+			It("treats the process as a running priority, ignoring case", func() {
+				Eventually(buffer).Should(Say(fmtWarnLog + `not starting swap processes, priority processes already running: .*EldenRing.exe, Game-Win64-Shipping.exe`))
+				Expect(fakeSwap.StartCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when a priority rule matches a process whose name is truncated", func() {
+			var fakeInspector *internalfakes.FakeInspector
+
+			BeforeEach(func() {
+				loop.WithPriorities(nil)
+				glob, err := NewGlobRule("*-Win64-Shipping.exe")
+				Expect(err).To(BeNil())
+				loop.WithRules([]Rule{glob})
+
+				fakeInspector = &internalfakes.FakeInspector{}
+				fakeInspector.PathReturns(filepath.FromSlash("/games/Game-Win64-Shipping.exe"), nil)
+				loop.WithInspector(fakeInspector)
+				// Linux truncates the names of processes to 15 characters.
+				fakeProcess.ExecutableReturns("Game-Win64-Ship")
+			})
+
+			It("matches the name of the process' executable", func() {
+				Eventually(buffer).Should(Say(fmtWarnLog + `not starting swap processes, priority processes already running: .*Game-Win64-Shipping.exe`))
+				Expect(fakeSwap.StartCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when a priority rule does not match any running process", func() {
+			BeforeEach(func() {
+				loop.WithPriorities(nil)
+				glob, err := NewGlobRule("*-Win64-Shipping.exe")
+				Expect(err).To(BeNil())
+				loop.WithRules([]Rule{glob})
+
+				fakeProcess.ExecutableReturns("explorer.exe")
+			})
+
+			It("starts the swaps", func() {
+				Eventually(buffer).Should(Say(fmtInfoLog + `.*start.* .*` + swapFilePath() + `.*\.\.\. .*OK.*`))
+				Expect(fakeSwap.StartCallCount()).To(Equal(1))
+			})
+		})
+
+		Context("when matching priorities by path", func() {
+			var (
+				fakeInspector *internalfakes.FakeInspector
+				match         string
+			)
+
+			BeforeEach(func() {
+				match = MatchPath
+				fakeInspector = &internalfakes.FakeInspector{}
+				loop.WithInspector(fakeInspector)
+
+				fakeProcess.PidReturns(1234)
+				fakeProcess.ExecutableReturns(priorityFile())
+			})
+
+			When("a process with the same name runs from somewhere else", func() {
+				BeforeEach(func() {
+					fakeInspector.PathReturns(filepath.FromSlash("/somewhere/else/"+priorityFile()), nil)
+					loop.WithGroups([]Group{{
+						Name:          "default",
+						Match:         match,
+						PriorityPaths: []string{priorityFilePath()},
+						Swaps:         []Swap{fakeSwap},
+					}})
+				})
+
+				It("starts the swaps", func() {
+					Eventually(buffer).Should(Say(fmtInfoLog + `.*start.* .*` + swapFilePath() + `.*\.\.\. .*OK.*`))
+					Expect(fakeInspector.PathArgsForCall(0)).To(Equal(1234))
+					Expect(fakeSwap.StartCallCount()).To(Equal(1))
+				})
+			})
+
+			When("the priority itself is running", func() {
+				BeforeEach(func() {
+					fakeInspector.PathReturns(priorityFilePath(), nil)
+					loop.WithGroups([]Group{{
+						Name:          "default",
+						Match:         match,
+						PriorityPaths: []string{priorityFilePath()},
+						Swaps:         []Swap{fakeSwap},
+					}})
+				})
+
+				It("does not start the swaps", func() {
+					Eventually(buffer).Should(Say(fmtWarnLog + `not starting swap processes, priority processes already running: .*` + priorityFile()))
+					Expect(fakeSwap.StartCallCount()).To(Equal(0))
+				})
+			})
+
+			When("matching by command line and a process' command line contains the priority", func() {
+				BeforeEach(func() {
+					fakeInspector.PathReturns(filepath.FromSlash("/usr/bin/wine"), nil)
+					fakeInspector.CmdlineReturns("wine "+priorityFilePath()+" -windowed", nil)
+					loop.WithGroups([]Group{{
+						Name:          "default",
+						Match:         MatchCmdline,
+						PriorityPaths: []string{priorityFilePath()},
+						Swaps:         []Swap{fakeSwap},
+					}})
+				})
+
+				It("does not start the swaps", func() {
+					Eventually(buffer).Should(Say(fmtWarnLog + `not starting swap processes, priority processes already running: .*` + priorityFile()))
+					Expect(fakeSwap.StartCallCount()).To(Equal(0))
+				})
+			})
+		})
+
 		Context("when the swaps have started and then a priority is started", func() {
 			BeforeEach(func() {
 				loop.WithLimit(2)
@@ -160,9 +295,38 @@ var _ = Describe("Loop", func() {
 				})
 			})
 
+			When("stopping a swap process takes a while", func() {
+				var took chan time.Duration
+
+				BeforeEach(func() {
+					fakeSwap.StopStub = func() ([]string, error) {
+						time.Sleep(2 * time.Second)
+
+						return nil, nil
+					}
+
+					took = make(chan time.Duration, 1)
+					l, s := loop, fakeSwap
+
+					go func() {
+						for s.StopCallCount() == 0 {
+							time.Sleep(10 * time.Millisecond)
+						}
+
+						started := time.Now()
+						l.WithLeaveSwapsRunning(false)
+						took <- time.Since(started)
+					}()
+				})
+
+				It("doesn't hold up anything else in the meantime", func() {
+					Eventually(took, 5*time.Second).Should(Receive(BeNumerically("<", time.Second)))
+				})
+			})
+
 			When("stopping a swap process fails", func() {
 				BeforeEach(func() {
-					fakeSwap.KillReturns(errors.New("error stopping swap"))
+					fakeSwap.StopReturns(nil, errors.New("error stopping swap"))
 				})
 
 				It("logs the error", func() {
@@ -184,6 +348,608 @@ var _ = Describe("Loop", func() {
 			})
 		})
 
+		Context("when procswap receives a shutdown signal", func() {
+			BeforeEach(func() {
+				if runtime.GOOS == "windows" {
+					Skip("signals can't be sent to a process on windows")
+				}
+
+				loop.WithLimit(0)
+				loop.WithPollInterval(1)
+				// Ginkgo handles SIGINT and SIGTERM itself.
+				loop.WithShutdownSignals(syscall.SIGHUP)
+
+				swap := fakeSwap
+
+				go func() {
+					// The loop listens for signals once it has started.
+					for swap.StartCallCount() == 0 {
+						time.Sleep(10 * time.Millisecond)
+					}
+
+					p, _ := os.FindProcess(os.Getpid())
+					p.Signal(syscall.SIGHUP)
+				}()
+			})
+
+			It("stops the swaps and exits with the code for the signal", func() {
+				Eventually(buffer).Should(Say(fmtWarnLog + `.*shutdown.* received hangup`))
+				Eventually(buffer).Should(Say(fmtInfoLog + `.*stop.* .*` + swapFilePath() + `.*\.\.\. .*OK.*`))
+				Expect(fakeSwap.StopCallCount()).To(Equal(1))
+				Expect(exitCode).To(Equal(129))
+			})
+
+			When("swaps are left running", func() {
+				BeforeEach(func() {
+					loop.WithLeaveSwapsRunning(true)
+				})
+
+				It("leaves them running", func() {
+					Eventually(buffer).Should(Say(fmtWarnLog + `.*shutdown.* leaving swaps running`))
+					Expect(fakeSwap.StopCallCount()).To(Equal(0))
+					Expect(exitCode).To(Equal(129))
+				})
+			})
+		})
+
+		Context("when procswap is stopped leaving swaps running while they are paused", func() {
+			BeforeEach(func() {
+				if runtime.GOOS == "windows" {
+					Skip("signals can't be sent to a process on windows")
+				}
+
+				loop.WithLimit(0)
+				loop.WithPollInterval(1)
+				loop.WithLeaveSwapsRunning(true)
+				// Ginkgo handles SIGINT and SIGTERM itself.
+				loop.WithShutdownSignals(syscall.SIGHUP)
+				fakeSwap.ModeReturns(SwapModePause)
+
+				swap, p, process := fakeSwap, fakePs, fakeProcess
+
+				go func() {
+					for swap.StartCallCount() == 0 {
+						time.Sleep(10 * time.Millisecond)
+					}
+
+					process.ExecutableReturns(priorityFile())
+					p.ProcessesReturns([]ps.Process{process}, nil)
+
+					for swap.PauseCallCount() == 0 {
+						time.Sleep(10 * time.Millisecond)
+					}
+
+					self, _ := os.FindProcess(os.Getpid())
+					self.Signal(syscall.SIGHUP)
+				}()
+			})
+
+			It("resumes them before exiting", func() {
+				Eventually(buffer).Should(Say(fmtWarnLog + `.*shutdown.* leaving swaps running`))
+				Eventually(buffer).Should(Say(fmtInfoLog + `.*resume.* .*` + swapFilePath() + `.*\.\.\. .*OK.*`))
+				Expect(fakeSwap.ResumeCallCount()).To(Equal(1))
+				Expect(fakeSwap.StopCallCount()).To(Equal(0))
+				Expect(exitCode).To(Equal(129))
+			})
+		})
+
+		Context("when there is a state file", func() {
+			var (
+				cmd       *exec.Cmd
+				stateFile string
+			)
+
+			BeforeEach(func() {
+				if runtime.GOOS != "linux" {
+					Skip("the start time of the process is read from /proc")
+				}
+
+				cmd = exec.Command(waitFilePath())
+				Expect(cmd.Start()).To(Succeed())
+
+				stateFile = filepath.Join(os.TempDir(), uuid.New().String(), "state.json")
+				loop.WithStateFile(stateFile)
+
+				fakeSwap.NameReturns("swap")
+				fakeSwap.PIDReturns(cmd.Process.Pid)
+				fakeSwap.StateReturns(SwapStateRunning)
+			})
+
+			AfterEach(func() {
+				cmd.Process.Kill()
+				cmd.Wait()
+				os.RemoveAll(filepath.Dir(stateFile))
+			})
+
+			When("a swap is still running from before", func() {
+				BeforeEach(func() {
+					writeStateFile(stateFile, "default", "swap", swapFilePath(), cmd.Process.Pid, processStartTime(cmd.Process.Pid))
+				})
+
+				It("adopts it instead of starting it", func() {
+					Eventually(buffer).Should(Say(fmtInfoLog + `.*adopt.* .*` + swapFilePath() + `.* \(pid ` + strconv.Itoa(cmd.Process.Pid) + `\)\.\.\. .*OK.*`))
+					Expect(fakeSwap.AdoptCallCount()).To(Equal(1))
+					Expect(fakeSwap.AdoptArgsForCall(0)).To(Equal(cmd.Process.Pid))
+					Expect(fakeSwap.StartCallCount()).To(Equal(0))
+				})
+			})
+
+			When("the swap's PID belongs to another process", func() {
+				BeforeEach(func() {
+					writeStateFile(stateFile, "default", "swap", swapFilePath(), cmd.Process.Pid, processStartTime(cmd.Process.Pid)+1)
+				})
+
+				It("starts the swap", func() {
+					Eventually(buffer).Should(Say(fmtInfoLog + `.*adopt.* .*` + swapFilePath() + `.* \(pid ` + strconv.Itoa(cmd.Process.Pid) + `\) is no longer running`))
+					Eventually(buffer).Should(Say(fmtInfoLog + `.*start.* .*` + swapFilePath() + `.*\.\.\. .*OK.*`))
+					Expect(fakeSwap.AdoptCallCount()).To(Equal(0))
+					Expect(fakeSwap.StartCallCount()).To(Equal(1))
+				})
+			})
+
+			When("the state file is for a swap that is no longer configured", func() {
+				BeforeEach(func() {
+					writeStateFile(stateFile, "default", "old", "/opt/old", cmd.Process.Pid, processStartTime(cmd.Process.Pid))
+				})
+
+				It("leaves the process alone", func() {
+					Eventually(buffer).Should(Say(fmtWarnLog + `.*adopt.* .*/opt/old.* \(pid ` + strconv.Itoa(cmd.Process.Pid) + `\) is no longer a swap, leaving it alone`))
+					Expect(fakeSwap.AdoptCallCount()).To(Equal(0))
+					Expect(processRunning(cmd.Process.Pid)).To(BeTrue())
+				})
+			})
+
+			It("writes the running swaps to the state file", func() {
+				b, err := ioutil.ReadFile(stateFile)
+				Expect(err).To(BeNil())
+				Expect(string(b)).To(ContainSubstring(`"pid": ` + strconv.Itoa(cmd.Process.Pid)))
+				Expect(string(b)).To(ContainSubstring(`"name": "swap"`))
+			})
+		})
+
+		Context("when a swap is no longer running on a later poll", func() {
+			BeforeEach(func() {
+				loop.WithLimit(3)
+				loop.WithPollInterval(1)
+
+				fakeSwap.StateReturns(SwapStateExited)
+				fakeSwap.ExitCodeReturns(3)
+
+				priority := &gopsfakes.FakeProcess{}
+				priority.ExecutableReturns(priorityFile())
+				fakePs.ProcessesReturnsOnCall(0, []ps.Process{fakeProcess}, nil)
+				fakePs.ProcessesReturnsOnCall(1, []ps.Process{fakeProcess}, nil)
+				fakePs.ProcessesReturnsOnCall(2, []ps.Process{fakeProcess, priority}, nil)
+			})
+
+			It("logs its exit and forgets it, so it isn't stopped", func() {
+				Eventually(buffer).Should(Say(fmtWarnLog + `.*exit.* .*` + swapFilePath() + `.* exited with code 3`))
+				Eventually(buffer).Should(Say(fmtInfoLog + `.*priority.* .*`))
+				Expect(fakeSwap.StopCallCount()).To(Equal(0))
+			})
+
+			When("it was killed by a signal", func() {
+				BeforeEach(func() {
+					fakeSwap.StateReturns(SwapStateKilled)
+				})
+
+				It("logs that it was killed", func() {
+					Eventually(buffer).Should(Say(fmtWarnLog + `.*exit.* .*` + swapFilePath() + `.* was killed by a signal`))
+				})
+			})
+		})
+
+		Context("when a swap exits on its own", func() {
+			BeforeEach(func() {
+				loop.WithLimit(2)
+				loop.WithPollInterval(1)
+
+				exited := make(chan struct{})
+				close(exited)
+				fakeSwap.ExitedReturns(exited)
+				fakeSwap.ExitCodeReturns(1)
+			})
+
+			When("the swap has no restart policy", func() {
+				It("is not restarted", func() {
+					Eventually(buffer).Should(Say(fmtWarnLog + `.*exit.* .*` + swapFilePath() + `.* exited with code 1`))
+					Consistently(fakeSwap.StartCallCount, "50ms").Should(Equal(1))
+				})
+			})
+
+			When("the swap restarts on failure", func() {
+				BeforeEach(func() {
+					fakeSwap.RestartReturns(RestartPolicy{
+						Policy:     RestartOnFailure,
+						MaxRetries: 2,
+						Backoff:    time.Millisecond,
+					})
+				})
+
+				It("restarts the swap until it runs out of retries", func() {
+					Eventually(buffer).Should(Say(fmtInfoLog + `.*restart.* .*` + swapFilePath() + `.* in 1ms \(retry 1\)`))
+					Eventually(buffer).Should(Say(fmtInfoLog + `.*restart.* .*` + swapFilePath() + `.*\.\.\. .*OK.*`))
+					Eventually(buffer).Should(Say(fmtInfoLog + `.*restart.* .*` + swapFilePath() + `.* in 2ms \(retry 2\)`))
+					Eventually(buffer).Should(Say(fmtErrorLog + `.*restart.* .*` + swapFilePath() + `.* gave up after 2 retries`))
+					Expect(fakeSwap.StartCallCount()).To(Equal(3))
+				})
+			})
+
+			When("the swap exits successfully", func() {
+				BeforeEach(func() {
+					fakeSwap.ExitCodeReturns(0)
+					fakeSwap.RestartReturns(RestartPolicy{Policy: RestartOnFailure, Backoff: time.Millisecond})
+				})
+
+				It("is not restarted", func() {
+					Eventually(buffer).Should(Say(fmtWarnLog + `.*exit.* .*` + swapFilePath() + `.* exited with code 0`))
+					Consistently(fakeSwap.StartCallCount, "50ms").Should(Equal(1))
+				})
+			})
+		})
+
+		Context("when a swap fails its health probe", func() {
+			BeforeEach(func() {
+				loop.WithLimit(2)
+				loop.WithPollInterval(1)
+
+				fakeSwap.HealthReturns(HealthProbe{
+					TCP:              "127.0.0.1:3333",
+					Interval:         10 * time.Millisecond,
+					FailureThreshold: 2,
+				})
+				fakeSwap.CheckReturnsOnCall(0, errors.New("connection refused"))
+				fakeSwap.CheckReturnsOnCall(1, errors.New("connection refused"))
+			})
+
+			It("restarts the swap once it reaches the failure threshold", func() {
+				Eventually(buffer).Should(Say(fmtWarnLog + `.*health.* .*` + swapFilePath() + `.* failed probe tcp 127.0.0.1:3333 \(1/2\): connection refused`))
+				Eventually(buffer).Should(Say(fmtWarnLog + `.*health.* .*` + swapFilePath() + `.* failed probe tcp 127.0.0.1:3333 \(2/2\): connection refused`))
+				Eventually(buffer).Should(Say(fmtErrorLog + `.*health.* .*` + swapFilePath() + ` unhealthy after 2 failed probes, restarting`))
+				Eventually(buffer).Should(Say(fmtInfoLog + `.*stop.* .*` + swapFilePath() + `.*\.\.\. .*OK.*`))
+				Eventually(buffer).Should(Say(fmtInfoLog + `.*start.* .*` + swapFilePath() + `.*\.\.\. .*OK.*`))
+				Expect(fakeSwap.StopCallCount()).To(Equal(1))
+				Expect(fakeSwap.StartCallCount()).To(Equal(2))
+			})
+
+			When("stopping the swap takes a while", func() {
+				var took chan time.Duration
+
+				BeforeEach(func() {
+					fakeSwap.StopStub = func() ([]string, error) {
+						time.Sleep(2 * time.Second)
+
+						return nil, nil
+					}
+
+					took = make(chan time.Duration, 1)
+					l, s := loop, fakeSwap
+
+					go func() {
+						for s.StopCallCount() == 0 {
+							time.Sleep(10 * time.Millisecond)
+						}
+
+						started := time.Now()
+						l.WithLeaveSwapsRunning(false)
+						took <- time.Since(started)
+					}()
+				})
+
+				It("doesn't hold up anything else in the meantime, then starts it again", func() {
+					Eventually(took, 5*time.Second).Should(Receive(BeNumerically("<", time.Second)))
+					Eventually(fakeSwap.StartCallCount, 5*time.Second).Should(Equal(2))
+				})
+			})
+		})
+
+		Context("when swaps start after each other", func() {
+			var proxy, miner *internalfakes.FakeSwap
+
+			BeforeEach(func() {
+				ready := make(chan struct{})
+				close(ready)
+
+				proxy = &internalfakes.FakeSwap{}
+				proxy.NameReturns("proxy")
+				proxy.PathReturns(swapFilePath())
+				proxy.ReadyReturns(ready)
+				proxy.StartupReturns(Startup{Ready: ReadyCondition{TCP: "127.0.0.1:3333"}})
+
+				miner = &internalfakes.FakeSwap{}
+				miner.NameReturns("miner")
+				miner.PathReturns(waitFilePath())
+				miner.StartupReturns(Startup{After: []string{"proxy"}, Delay: 10 * time.Millisecond})
+
+				loop.WithSwaps([]Swap{miner, proxy})
+			})
+
+			It("starts them in dependency order", func() {
+				Eventually(buffer).Should(Say(fmtInfoLog + `.*start.* .*` + swapFilePath() + `.*\.\.\. .*OK.*`))
+				Eventually(buffer).Should(Say(fmtInfoLog + `.*start.* .*` + waitFilePath() + `.* in 10ms`))
+				Eventually(buffer).Should(Say(fmtInfoLog + `.*start.* .*` + waitFilePath() + `.*\.\.\. .*OK.*`))
+				Expect(proxy.StartCallCount()).To(Equal(1))
+				Expect(miner.StartCallCount()).To(Equal(1))
+			})
+
+			When("a priority starts", func() {
+				BeforeEach(func() {
+					loop.WithLimit(2)
+					loop.WithPollInterval(1)
+
+					priority := &gopsfakes.FakeProcess{}
+					priority.ExecutableReturns(priorityFile())
+					fakePs.ProcessesReturnsOnCall(0, []ps.Process{fakeProcess}, nil)
+					fakePs.ProcessesReturnsOnCall(1, []ps.Process{fakeProcess, priority}, nil)
+				})
+
+				It("stops them in reverse order", func() {
+					Eventually(buffer).Should(Say(fmtInfoLog + `.*start.* .*` + waitFilePath() + `.*\.\.\. .*OK.*`))
+					Eventually(buffer).Should(Say(fmtInfoLog + `.*stop.* .*` + waitFilePath() + `.*\.\.\. .*OK.*`))
+					Eventually(buffer).Should(Say(fmtInfoLog + `.*stop.* .*` + swapFilePath() + `.*\.\.\. .*OK.*`))
+				})
+			})
+		})
+
+		Context("when a swap is paused while priorities run", func() {
+			BeforeEach(func() {
+				loop.WithLimit(3)
+				loop.WithPollInterval(1)
+
+				fakeSwap.ModeReturns(SwapModePause)
+
+				priority := &gopsfakes.FakeProcess{}
+				priority.ExecutableReturns(priorityFile())
+				fakePs.ProcessesReturnsOnCall(0, []ps.Process{fakeProcess}, nil)
+				fakePs.ProcessesReturnsOnCall(1, []ps.Process{fakeProcess, priority}, nil)
+				fakePs.ProcessesReturnsOnCall(2, []ps.Process{fakeProcess}, nil)
+			})
+
+			When("it succeeds", func() {
+				It("pauses the swap and resumes it once priorities stop", func() {
+					Eventually(buffer).Should(Say(fmtInfoLog + `.*start.* .*` + swapFilePath() + `.*\.\.\. .*OK.*`))
+					Eventually(buffer).Should(Say(fmtInfoLog + `.*pause.* .*` + swapFilePath() + `.*\.\.\. .*OK.*`))
+					Eventually(buffer).Should(Say(fmtInfoLog + `.*resume.* .*` + swapFilePath() + `.*\.\.\. .*OK.*`))
+					Expect(fakeSwap.StartCallCount()).To(Equal(1))
+					Expect(fakeSwap.StopCallCount()).To(Equal(0))
+					Expect(fakeSwap.PauseCallCount()).To(Equal(1))
+					Expect(fakeSwap.ResumeCallCount()).To(Equal(1))
+				})
+			})
+
+			When("pausing the swap fails", func() {
+				BeforeEach(func() {
+					fakeSwap.PauseReturns(errors.New("error pausing swap"))
+				})
+
+				It("stops the swap instead and starts it again once priorities stop", func() {
+					Eventually(buffer).Should(Say(fmtInfoLog + `.*pause.* .*` + swapFilePath() + `.*\.\.\. .*FAILED.*`))
+					Eventually(buffer).Should(Say(fmtErrorLog + `error pausing swap process .*: error pausing swap, stopping it instead`))
+					Eventually(buffer).Should(Say(fmtInfoLog + `.*stop.* .*` + swapFilePath() + `.*\.\.\. .*OK.*`))
+					Eventually(buffer).Should(Say(fmtInfoLog + `.*start.* .*` + swapFilePath() + `.*\.\.\. .*OK.*`))
+					Expect(fakeSwap.StartCallCount()).To(Equal(2))
+					Expect(fakeSwap.ResumeCallCount()).To(Equal(0))
+				})
+			})
+		})
+
+		Context("when a swap is throttled while priorities run", func() {
+			BeforeEach(func() {
+				loop.WithLimit(3)
+				loop.WithPollInterval(1)
+
+				fakeSwap.ModeReturns(SwapModeThrottle)
+
+				priority := &gopsfakes.FakeProcess{}
+				priority.ExecutableReturns(priorityFile())
+				fakePs.ProcessesReturnsOnCall(0, []ps.Process{fakeProcess}, nil)
+				fakePs.ProcessesReturnsOnCall(1, []ps.Process{fakeProcess, priority}, nil)
+				fakePs.ProcessesReturnsOnCall(2, []ps.Process{fakeProcess}, nil)
+			})
+
+			When("it succeeds", func() {
+				It("throttles the swap and unthrottles it once priorities stop", func() {
+					Eventually(buffer).Should(Say(fmtInfoLog + `.*start.* .*` + swapFilePath() + `.*\.\.\. .*OK.*`))
+					Eventually(buffer).Should(Say(fmtInfoLog + `.*throttle.* .*` + swapFilePath() + `.*\.\.\. .*OK.*`))
+					Eventually(buffer).Should(Say(fmtInfoLog + `.*unthrottle.* .*` + swapFilePath() + `.*\.\.\. .*OK.*`))
+					Expect(fakeSwap.StartCallCount()).To(Equal(1))
+					Expect(fakeSwap.StopCallCount()).To(Equal(0))
+					Expect(fakeSwap.ThrottleCallCount()).To(Equal(1))
+					Expect(fakeSwap.UnthrottleCallCount()).To(Equal(1))
+					Expect(fakeSwap.PauseCallCount()).To(Equal(0))
+				})
+			})
+
+			When("unthrottling the swap fails", func() {
+				BeforeEach(func() {
+					fakeSwap.UnthrottleReturns(errors.New("error unthrottling swap"))
+				})
+
+				It("stops the swap and starts it again", func() {
+					Eventually(buffer).Should(Say(fmtInfoLog + `.*unthrottle.* .*` + swapFilePath() + `.*\.\.\. .*FAILED.*`))
+					Eventually(buffer).Should(Say(fmtErrorLog + `error unthrottling swap process .*: error unthrottling swap`))
+					Eventually(buffer).Should(Say(fmtInfoLog + `.*stop.* .*` + swapFilePath() + `.*\.\.\. .*OK.*`))
+					Eventually(buffer).Should(Say(fmtInfoLog + `.*start.* .*` + swapFilePath() + `.*\.\.\. .*OK.*`))
+					Expect(fakeSwap.StartCallCount()).To(Equal(2))
+				})
+			})
+		})
+
+		Context("when the loop is reloaded", func() {
+			var newSwap *internalfakes.FakeSwap
+
+			BeforeEach(func() {
+				loop.WithLimit(2)
+				loop.WithPollInterval(1)
+
+				newSwap = &internalfakes.FakeSwap{}
+				newSwap.PathReturns(waitFilePath())
+			})
+
+			When("swaps are started", func() {
+				BeforeEach(func() {
+					go func() {
+						time.Sleep(500 * time.Millisecond)
+						execs, _ := ProcessList(prioritiesPath, ignored, Discovery{})
+						loop.Reload([]Group{{Name: "default", Priorities: execs, Swaps: []Swap{newSwap}}})
+					}()
+				})
+
+				It("stops removed swaps and starts new swaps", func() {
+					Eventually(buffer).Should(Say(fmtInfoLog + `.*start.* .*` + swapFilePath() + `.*\.\.\. .*OK.*`))
+					Eventually(buffer).Should(Say(fmtInfoLog + `.*stop.* .*` + swapFilePath() + `.*\.\.\. .*OK.*`))
+					Eventually(buffer).Should(Say(fmtInfoLog + `.*start.* .*` + waitFilePath() + `.*\.\.\. .*OK.*`))
+					Expect(fakeSwap.StopCallCount()).To(Equal(1))
+					Expect(newSwap.StartCallCount()).To(Equal(1))
+				})
+			})
+
+			When("stopping a removed swap takes a while", func() {
+				var (
+					took             chan time.Duration
+					startedWhileStop chan int
+				)
+
+				BeforeEach(func() {
+					took = make(chan time.Duration, 1)
+					startedWhileStop = make(chan int, 1)
+					l, s, n := loop, fakeSwap, newSwap
+
+					fakeSwap.StopStub = func() ([]string, error) {
+						time.Sleep(2 * time.Second)
+						startedWhileStop <- n.StartCallCount()
+
+						return nil, nil
+					}
+
+					go func() {
+						time.Sleep(500 * time.Millisecond)
+						execs, _ := ProcessList(prioritiesPath, ignored, Discovery{})
+						l.Reload([]Group{{Name: "default", Priorities: execs, Swaps: []Swap{n}}})
+					}()
+
+					go func() {
+						for s.StopCallCount() == 0 {
+							time.Sleep(10 * time.Millisecond)
+						}
+
+						started := time.Now()
+						l.WithLeaveSwapsRunning(false)
+						took <- time.Since(started)
+					}()
+				})
+
+				It("doesn't hold up anything else in the meantime, then starts the new swaps", func() {
+					Eventually(took, 5*time.Second).Should(Receive(BeNumerically("<", time.Second)))
+					Eventually(startedWhileStop, 5*time.Second).Should(Receive(Equal(0)))
+					Eventually(newSwap.StartCallCount, 5*time.Second).Should(Equal(1))
+				})
+			})
+
+			When("unchanged swaps are passed in again", func() {
+				BeforeEach(func() {
+					go func() {
+						time.Sleep(500 * time.Millisecond)
+						execs, _ := ProcessList(prioritiesPath, ignored, Discovery{})
+						loop.Reload([]Group{{Name: "default", Priorities: execs, Swaps: []Swap{fakeSwap, newSwap}}})
+					}()
+				})
+
+				It("leaves them running", func() {
+					Eventually(buffer).Should(Say(fmtInfoLog + `.*start.* .*` + swapFilePath() + `.*\.\.\. .*OK.*`))
+					Eventually(buffer).Should(Say(fmtInfoLog + `.*start.* .*` + waitFilePath() + `.*\.\.\. .*OK.*`))
+					Expect(fakeSwap.StartCallCount()).To(Equal(1))
+					Expect(fakeSwap.StopCallCount()).To(Equal(0))
+				})
+			})
+
+			When("priorities are running", func() {
+				BeforeEach(func() {
+					fakeProcess.ExecutableReturns(priorityFile())
+					fakePs.ProcessesReturns([]ps.Process{fakeProcess}, nil)
+
+					go func() {
+						time.Sleep(500 * time.Millisecond)
+						execs, _ := ProcessList(prioritiesPath, ignored, Discovery{})
+						loop.Reload([]Group{{Name: "default", Priorities: execs, Swaps: []Swap{newSwap}}})
+					}()
+				})
+
+				It("does not start new swaps", func() {
+					Eventually(buffer).Should(Say(fmtWarnLog + `not starting swap processes, priority processes already running: .*`))
+					Expect(newSwap.StartCallCount()).To(Equal(0))
+				})
+			})
+		})
+
+		Context("when it is a dry run", func() {
+			BeforeEach(func() {
+				loop.WithDryRun(true)
+				loop.WithPriorityScript(priorityScriptPath())
+				loop.WithLimit(2)
+				loop.WithPollInterval(1)
+
+				go func() {
+					time.Sleep(500 * time.Millisecond)
+					fakeProcess.ExecutableReturns(priorityFile())
+					fakePs.ProcessesReturns([]ps.Process{fakeProcess}, nil)
+				}()
+			})
+
+			It("logs what it would do without doing it", func() {
+				Eventually(buffer).Should(Say(fmtInfoLog + `.*dry-run.* would .*start.* .*` + swapFilePath()))
+				Eventually(buffer).Should(Say(fmtInfoLog + `.*priority.* .*` + priorityFile() + `.*`))
+				Eventually(buffer).Should(Say(fmtInfoLog + `.*dry-run.* would .*stop.* .*` + swapFilePath()))
+				Eventually(buffer).Should(Say(fmtInfoLog + `.*dry-run.* would run .*priority script.* .*` + priorityScriptPath()))
+				Expect(fakeSwap.StartCallCount()).To(Equal(0))
+				Expect(fakeSwap.StopCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when there are named groups", func() {
+			var otherSwap *internalfakes.FakeSwap
+
+			BeforeEach(func() {
+				otherSwap = &internalfakes.FakeSwap{}
+				otherSwap.PathReturns(waitFilePath())
+
+				test1, err := ProcessList(filepath.FromSlash(prioritiesPath+"/test1.exe"), ignored, Discovery{})
+				Expect(err).To(BeNil())
+				execs, err := ProcessList(prioritiesPath, ignored, Discovery{})
+				Expect(err).To(BeNil())
+
+				loop.WithGroups([]Group{
+					{
+						Name:       "cpu-miner",
+						Priorities: test1,
+						Swaps:      []Swap{fakeSwap},
+					},
+					{
+						Name:       "gpu-miner",
+						Priorities: execs,
+						Swaps:      []Swap{otherSwap},
+					},
+				})
+				loop.WithLimit(2)
+				loop.WithPollInterval(1)
+
+				go func() {
+					time.Sleep(500 * time.Millisecond)
+					fakeProcess.ExecutableReturns(priorityFile())
+					fakePs.ProcessesReturns([]ps.Process{fakeProcess}, nil)
+				}()
+			})
+
+			It("only stops the swaps of groups whose priorities are running", func() {
+				Eventually(buffer).Should(Say(fmtInfoLog + `.*start.* \(cpu-miner\) .*` + swapFilePath() + `.*\.\.\. .*OK.*`))
+				Eventually(buffer).Should(Say(fmtInfoLog + `.*start.* \(gpu-miner\) .*` + waitFilePath() + `.*\.\.\. .*OK.*`))
+				Eventually(buffer).Should(Say(fmtInfoLog + `.*priority.* \(gpu-miner\) .*` + priorityFile() + `.*`))
+				Eventually(buffer).Should(Say(fmtInfoLog + `.*stop.* \(gpu-miner\) .*` + waitFilePath() + `.*\.\.\. .*OK.*`))
+				Expect(otherSwap.StopCallCount()).To(Equal(1))
+				Expect(fakeSwap.StopCallCount()).To(Equal(0))
+			})
+		})
+
 		Context("when there are no running priorities and swap processes have not been started", func() {
 			When("you pass in a swap file that doesn't exist", func() {
 				BeforeEach(func() {
@@ -207,3 +973,11 @@ var _ = Describe("Loop", func() {
 		})
 	})
 })
+
+// writeStateFile writes a state file with a single swap in it.
+func writeStateFile(path, group, name, swap string, pid int, startTime uint64) {
+	Expect(os.MkdirAll(filepath.Dir(path), 0o755)).To(Succeed())
+
+	b := fmt.Sprintf(`{"swaps":[{"group":%q,"name":%q,"path":%q,"pid":%d,"start_time":%d}]}`, group, name, swap, pid, startTime)
+	Expect(ioutil.WriteFile(path, []byte(b), 0o644)).To(Succeed())
+}
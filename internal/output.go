@@ -0,0 +1,134 @@
+package procswap
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/logrusorgru/aurora"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Streams a swap writes its output to.
+const (
+	streamStdout = "stdout"
+	streamStderr = "stderr"
+)
+
+// OutputLog is a file a swap's output is written to. The file is rotated
+// once it gets too big, and old files are removed once there are too many
+// of them or they get too old. The zero value writes nothing.
+type OutputLog struct {
+	// Path is the path of the log file.
+	Path string
+	// MaxSize is the size in megabytes the file can grow to before it is
+	// rotated, it defaults to 100.
+	MaxSize int
+	// MaxAge is the number of days rotated files are kept (0 = forever).
+	MaxAge int
+	// MaxBackups is the number of rotated files kept (0 = all).
+	MaxBackups int
+	// Compress gzips rotated files.
+	Compress bool
+}
+
+// validate returns an error if any value is negative.
+func (o OutputLog) validate() error {
+	if o.MaxSize < 0 || o.MaxAge < 0 || o.MaxBackups < 0 {
+		return errors.New("log max size, max age and max backups can't be negative")
+	}
+
+	return nil
+}
+
+// writer returns a writer that rotates the log file, or nil if no path is set.
+func (o OutputLog) writer() io.WriteCloser {
+	if o.Path == "" {
+		return nil
+	}
+
+	return &lumberjack.Logger{
+		Filename:   o.Path,
+		MaxSize:    o.MaxSize,
+		MaxAge:     o.MaxAge,
+		MaxBackups: o.MaxBackups,
+		Compress:   o.Compress,
+		LocalTime:  true,
+	}
+}
+
+// logOutput writes a line of the swap's output to its log file, if it has one.
+func (s *swap) logOutput(line string) {
+	s.outputMu.Lock()
+	defer s.outputMu.Unlock()
+
+	if s.output == nil {
+		return
+	}
+
+	fmt.Fprintln(s.output, line)
+}
+
+// CloseOutputLog closes the swap's log file once the swap is no longer used.
+// Nothing the swap writes after that is logged, as writing would open the
+// file again.
+func (s *swap) CloseOutputLog() error {
+	s.outputMu.Lock()
+	output := s.output
+	s.output = nil
+	s.outputMu.Unlock()
+
+	if output == nil {
+		return nil
+	}
+
+	return output.Close()
+}
+
+// logEvent writes a line marking something that happened to the swap to its
+// log file, so runs of the swap can be told apart when reading it.
+func (s *swap) logEvent(event string) {
+	s.logOutput(fmt.Sprintf("--- procswap %s %s ---", time.Now().Format(logTimeFormat), event))
+}
+
+// readOutput reads the lines the swap writes to one of its streams until
+// the stream is closed, then closes its end of the stream.
+func (s *swap) readOutput(r io.ReadCloser, stream string) {
+	defer r.Close()
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		s.writeOutput(stream, scanner.Text())
+	}
+	// A line too long to scan ends the scan early, keep reading so the swap
+	// isn't blocked writing to a full pipe.
+	_, _ = io.Copy(ioutil.Discard, r)
+}
+
+// writeOutput labels a line the swap wrote with the time, the swap's name and
+// the stream, then logs it and keeps it in the swap's history. Lines written
+// to stderr are always shown as warnings, lines written to stdout only when
+// the swap's output is shown.
+func (s *swap) writeOutput(stream, text string) {
+	line := fmt.Sprintf("%s | %s %s | %s", time.Now().Format(logTimeFormat), s.name, stream, text)
+	s.logOutput(line)
+
+	s.outputMu.Lock()
+	defer s.outputMu.Unlock()
+
+	s.history.add(line)
+
+	if s.readyOutput != nil && s.readyOutput.MatchString(text) {
+		s.markReady()
+	}
+
+	switch {
+	case stream == streamStderr:
+		logWarn(fmt.Sprintf("%s %s %s", aurora.Bold(s.name), aurora.Yellow(stream), text))
+	case s.showOutput:
+		printLine(line)
+	}
+}
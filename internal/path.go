@@ -3,16 +3,72 @@ package procswap
 import (
 	"fmt"
 	"os"
-	"regexp"
 	"strings"
 
 	"github.com/karrick/godirwalk"
 	"github.com/logrusorgru/aurora"
 )
 
-// ProcessList lists all .exe files in a given directory.
-func ProcessList(path string, ignored []string) ([]*godirwalk.Dirent, error) {
-	files := []*godirwalk.Dirent{}
+// executable is an executable found in a priority path.
+type executable struct {
+	dirent *godirwalk.Dirent
+	// path is the full path of the executable.
+	path string
+	// ignored is true if the executable is in the list of ignored priorities.
+	ignored bool
+}
+
+// ProcessList lists all executables in a given directory, the discovery
+// decides which files are executables.
+func ProcessList(path string, ignored []string, discovery Discovery) ([]*godirwalk.Dirent, error) {
+	execs, err := processList(path, ignored, discovery)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]*godirwalk.Dirent, 0, len(execs))
+	for _, e := range execs {
+		files = append(files, e.dirent)
+	}
+
+	return files, nil
+}
+
+// processList lists all executables in a given directory that are not
+// ignored, along with their paths.
+func processList(path string, ignored []string, discovery Discovery) ([]executable, error) {
+	files := []executable{}
+
+	execs, err := findExecutables(path, ignored, discovery, func() {
+		logInfo(fmt.Sprintf("%s searching %s for executables", aurora.Cyan("setup"), path))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range execs {
+		if e.ignored {
+			logInfo(fmt.Sprintf("%s ignoring priority %s", aurora.Cyan("setup"), aurora.Bold(e.dirent.Name())))
+
+			continue
+		}
+
+		files = append(files, e)
+	}
+
+	return files, nil
+}
+
+// findExecutables finds all executables in a given directory, marking the ones
+// that are ignored by name or full path. searching is called before the
+// directory is walked.
+func findExecutables(path string, ignored []string, discovery Discovery, searching func()) ([]executable, error) {
+	execs := []executable{}
+
+	matches, err := discovery.matcher()
+	if err != nil {
+		return nil, err
+	}
 
 	// Check to make sure it exists first.
 	info, err := os.Stat(path)
@@ -23,7 +79,7 @@ func ProcessList(path string, ignored []string) ([]*godirwalk.Dirent, error) {
 
 		return nil, fmt.Errorf("error checking if %s exists: %w", path, err)
 	}
-	// Don't check if it's a .exe if the user has passed in a
+	// Don't check if it's an executable if the user has passed in a
 	// single file as priority.
 	if !info.IsDir() {
 		de, err := godirwalk.NewDirent(path)
@@ -31,24 +87,22 @@ func ProcessList(path string, ignored []string) ([]*godirwalk.Dirent, error) {
 			return nil, fmt.Errorf("error creating new dirent for priority file %s: %w", path, err)
 		}
 
-		files = append(files, de)
+		execs = append(execs, executable{dirent: de, path: path})
 
-		return files, nil
+		return execs, nil
 	}
 
-	logInfo(fmt.Sprintf("%s searching %s for executables", aurora.Cyan("setup"), path))
-
-	// Only list files that end in '.exe'.
-	libRegEx := regexp.MustCompile("^.*.exe$")
+	searching()
 
 	err = godirwalk.Walk(path, &godirwalk.Options{
 		Callback: func(osPathname string, de *godirwalk.Dirent) error {
-			if libRegEx.MatchString(de.Name()) {
-				if contains(ignored, de.Name()) {
-					logInfo(fmt.Sprintf("%s ignoring priority %s", aurora.Cyan("setup"), aurora.Bold(de.Name())))
-				} else {
-					files = append(files, de)
-				}
+			// Only list files the discovery finds to be executables.
+			if !de.IsDir() && matches(osPathname, de.Name()) {
+				execs = append(execs, executable{
+					dirent:  de,
+					path:    osPathname,
+					ignored: contains(ignored, de.Name()) || contains(ignored, osPathname),
+				})
 			}
 
 			return nil
@@ -56,10 +110,10 @@ func ProcessList(path string, ignored []string) ([]*godirwalk.Dirent, error) {
 		Unsorted: true,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("error walking %s searching for .exes: %w", path, err)
+		return nil, fmt.Errorf("error walking %s searching for executables: %w", path, err)
 	}
 
-	return files, nil
+	return execs, nil
 }
 
 // contains returns true if slice s contains element e, ignoring case.
@@ -3,6 +3,7 @@ package procswap_test
 import (
 	"os"
 	"path/filepath"
+	"runtime"
 
 	"github.com/google/uuid"
 	"github.com/karrick/godirwalk"
@@ -14,11 +15,12 @@ import (
 
 var _ = Describe("Path", func() {
 	var (
-		infos   []*godirwalk.Dirent
-		ignored []string
-		path    string
-		err     error
-		rescue  *os.File
+		infos     []*godirwalk.Dirent
+		ignored   []string
+		discovery Discovery
+		path      string
+		err       error
+		rescue    *os.File
 	)
 
 	BeforeEach(func() {
@@ -28,6 +30,7 @@ var _ = Describe("Path", func() {
 		rescue = os.Stdout
 		os.Stdout = os.NewFile(0, os.DevNull)
 		ignored = []string{}
+		discovery = Discovery{}
 	})
 
 	AfterEach(func() {
@@ -35,7 +38,7 @@ var _ = Describe("Path", func() {
 	})
 
 	JustBeforeEach(func() {
-		infos, err = ProcessList(path, ignored)
+		infos, err = ProcessList(path, ignored, discovery)
 	})
 
 	Describe("#ProcessList", func() {
@@ -68,8 +71,133 @@ var _ = Describe("Path", func() {
 
 			It("walks the directory returning all .exe files", func() {
 				Expect(err).To(BeNil())
-				Expect(infos).ToNot(HaveLen(0))
+				Expect(names(infos)).To(ConsistOf("ignore_me.exe", "test1.exe", "test2.exe", "wait.exe", "wait_linux.exe"))
+			})
+		})
+
+		When("a priority is ignored by its full path", func() {
+			BeforeEach(func() {
+				path = filepath.FromSlash(path + "/test/priorities")
+				ignored = []string{filepath.Join(path, "test1.exe")}
+			})
+
+			It("leaves it out", func() {
+				Expect(err).To(BeNil())
+				Expect(names(infos)).To(ConsistOf("ignore_me.exe", "test2.exe", "wait.exe", "wait_linux.exe"))
+			})
+		})
+
+		When("the discovery mode is unknown", func() {
+			BeforeEach(func() {
+				discovery = Discovery{Mode: "magic"}
+			})
+
+			It("returns an error", func() {
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(Equal("unknown discovery mode magic, use one of exe, glob, regex, executable, header"))
+			})
+		})
+
+		When("the discovery mode is glob", func() {
+			BeforeEach(func() {
+				path = filepath.FromSlash(path + "/test/priorities")
+				discovery = Discovery{Mode: DiscoveryGlob, Patterns: []string{"TEST*", "*_linux*"}}
+			})
+
+			It("returns files matching any pattern, ignoring case", func() {
+				Expect(err).To(BeNil())
+				Expect(names(infos)).To(ConsistOf("test1.exe", "test2.exe", "wait_linux.exe"))
+			})
+
+			When("there are no patterns", func() {
+				BeforeEach(func() {
+					discovery.Patterns = nil
+				})
+
+				It("returns an error", func() {
+					Expect(err).ToNot(BeNil())
+					Expect(err.Error()).To(Equal("discovery mode glob needs at least one pattern"))
+				})
+			})
+
+			When("a pattern is malformed", func() {
+				BeforeEach(func() {
+					discovery.Patterns = []string{"["}
+				})
+
+				It("returns an error", func() {
+					Expect(err).ToNot(BeNil())
+					Expect(err.Error()).To(HavePrefix("invalid glob pattern ["))
+				})
+			})
+		})
+
+		When("the discovery mode is regex", func() {
+			BeforeEach(func() {
+				path = filepath.FromSlash(path + "/test/priorities")
+				discovery = Discovery{Mode: DiscoveryRegex, Patterns: []string{`^test\d\.EXE$`}}
+			})
+
+			It("returns files matching any regular expression, ignoring case", func() {
+				Expect(err).To(BeNil())
+				Expect(names(infos)).To(ConsistOf("test1.exe", "test2.exe"))
+			})
+
+			When("a regular expression is invalid", func() {
+				BeforeEach(func() {
+					discovery.Patterns = []string{"("}
+				})
+
+				It("returns an error", func() {
+					Expect(err).ToNot(BeNil())
+					Expect(err.Error()).To(HavePrefix("invalid regular expression ("))
+				})
+			})
+		})
+
+		When("the discovery mode is executable", func() {
+			BeforeEach(func() {
+				if runtime.GOOS == "windows" {
+					Skip("executable bits are not used on windows")
+				}
+
+				path = filepath.FromSlash(path + "/test")
+				discovery = Discovery{Mode: DiscoveryExecutable}
+			})
+
+			It("returns files with an executable bit set, including scripts without an extension", func() {
+				Expect(err).To(BeNil())
+				Expect(names(infos)).To(ContainElement("priority-script"))
+				Expect(names(infos)).To(ContainElement("swap"))
+				Expect(names(infos)).ToNot(ContainElement("file"))
+				Expect(names(infos)).ToNot(ContainElement("procswap.yml"))
+			})
+		})
+
+		When("the discovery mode is header", func() {
+			BeforeEach(func() {
+				path = filepath.FromSlash(path + "/test")
+				discovery = Discovery{Mode: DiscoveryHeader}
+			})
+
+			It("returns ELF and PE binaries", func() {
+				Expect(err).To(BeNil())
+				Expect(names(infos)).To(ContainElement("swap"))
+				Expect(names(infos)).To(ContainElement("swap.exe"))
+				Expect(names(infos)).To(ContainElement("wait_linux.exe"))
+				Expect(names(infos)).ToNot(ContainElement("file"))
+				Expect(names(infos)).ToNot(ContainElement("procswap.yml"))
 			})
 		})
 	})
 })
+
+// names returns the name of each dirent.
+func names(dirents []*godirwalk.Dirent) []string {
+	n := make([]string, 0, len(dirents))
+	for _, de := range dirents {
+		n = append(n, de.Name())
+	}
+
+	return n
+}
@@ -0,0 +1,101 @@
+package procswap
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Swap modes decide what happens to a swap when a priority starts.
+const (
+	// SwapModeStop stops the swap and starts it again once priorities stop.
+	// This is the default.
+	SwapModeStop = "stop"
+	// SwapModePause freezes the swap's process tree and resumes it once
+	// priorities stop, so it doesn't have to start from scratch.
+	SwapModePause = "pause"
+	// SwapModeThrottle keeps the swap running at a lower priority while
+	// priorities run and restores it once they stop.
+	SwapModeThrottle = "throttle"
+)
+
+// swapModes is every swap mode.
+var swapModes = []string{SwapModeStop, SwapModePause, SwapModeThrottle}
+
+// validateSwapMode returns an error if mode is not a swap mode.
+func validateSwapMode(mode string) error {
+	if mode != "" && !contains(swapModes, mode) {
+		return fmt.Errorf("unknown swap mode %s, use one of %s", mode, strings.Join(swapModes, ", "))
+	}
+
+	return nil
+}
+
+// Pause freezes the swap's whole process tree, so it stops using the CPU
+// and GPU but keeps its memory.
+func (s *swap) Pause() error {
+	if err := s.freeze(true); err != nil {
+		return err
+	}
+
+	s.paused = true
+
+	return nil
+}
+
+// Resume resumes the swap's process tree after it was paused.
+func (s *swap) Resume() error {
+	if err := s.freeze(false); err != nil {
+		return err
+	}
+
+	s.paused = false
+
+	return nil
+}
+
+// Paused returns true if the swap is paused.
+func (s *swap) Paused() bool {
+	return s.paused
+}
+
+// checkRunning returns an error if the swap has not been started or has
+// exited, so there is no process to action.
+func (s *swap) checkRunning(action string) error {
+	if s.cmd == nil {
+		return fmt.Errorf("no command to %s", action)
+	}
+
+	select {
+	case <-s.exited:
+		return errors.New("swap has exited")
+	default:
+	}
+
+	return nil
+}
+
+// freeze freezes, or resumes, the swap's process group, then every process
+// of its tree, as some may have left the group, then the swap itself.
+func (s *swap) freeze(freeze bool) error {
+	if err := s.checkRunning("pause or resume"); err != nil {
+		return err
+	}
+
+	tree, err := s.processTree()
+	if err != nil {
+		return fmt.Errorf("error listing child processes for %s: %w", s.path, err)
+	}
+
+	if err := freezeProcessGroup(s.cmd.Process.Pid, freeze); err != nil {
+		return fmt.Errorf("error freezing process group: %w", err)
+	}
+
+	for _, p := range tree {
+		if err := freezeProcess(p.Pid(), freeze); err != nil {
+			return fmt.Errorf("error freezing process %s (%d): %w", p.Executable(), p.Pid(), err)
+		}
+	}
+
+	return freezeProcess(s.cmd.Process.Pid, freeze)
+}
@@ -0,0 +1,308 @@
+package procswap
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/karrick/godirwalk"
+	"github.com/logrusorgru/aurora"
+	"github.com/urfave/cli/v2"
+)
+
+// reloadDebounce is how long to wait after the config file changes before
+// reloading. Editors tend to write a file in more than one step.
+const reloadDebounce = 500 * time.Millisecond
+
+// reloader reloads the configuration of a running loop, either when its
+// config file changes or when procswap receives a SIGHUP.
+type reloader struct {
+	// c is the CLI context, flags set on it still override the config file.
+	c *cli.Context
+	// loop is the running loop to reload.
+	loop Loop
+	// swaps holds the current swaps by key.
+	swaps map[string]Swap
+	// configs holds the config each of the current swaps was created from by key.
+	configs map[string]swapConfig
+	// watcher watches the config file, it is nil when there is no config file.
+	watcher *fsnotify.Watcher
+	// signals receives SIGHUP.
+	signals chan os.Signal
+	// done stops the reloader.
+	done chan struct{}
+}
+
+func newReloader(c *cli.Context, loop Loop) *reloader {
+	return &reloader{
+		c:       c,
+		loop:    loop,
+		swaps:   map[string]Swap{},
+		configs: map[string]swapConfig{},
+		signals: make(chan os.Signal, 1),
+		done:    make(chan struct{}),
+	}
+}
+
+// groupsFor scans the priorities and creates the swaps of every group in the
+// config, logging what it found under the given stage. Swaps whose config has
+// not changed since the last call are reused so they keep running, any other
+// swap is created new. If any group has a swap that is also one of its
+// priorities an error is returned and nothing is changed.
+func (r *reloader) groupsFor(cfg *config, stage string) ([]Group, error) {
+	groups := []Group{}
+	swaps := map[string]Swap{}
+	configs := map[string]swapConfig{}
+
+	for _, gc := range cfg.groups() {
+		// Setup priority executables.
+		execs := listExecutables(gc.Priorities, gc.Ignore)
+		pe := make([]*godirwalk.Dirent, 0, len(execs))
+		paths := make([]string, 0, len(execs))
+
+		for _, e := range execs {
+			pe = append(pe, e.dirent)
+			paths = append(paths, absPath(e.path))
+		}
+
+		if err := intersect(pe, gc.swapPaths()); err != nil {
+			return nil, err
+		}
+		// Setup priority rules, these match running processes by name.
+		rules, err := gc.rules()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := intersectRules(rules, gc.swapPaths()); err != nil {
+			return nil, err
+		}
+
+		switch {
+		case len(pe) > 0:
+			count := strconv.Itoa(len(pe))
+			logInfo(fmt.Sprintf("%s found %s priority executables%s", aurora.Cyan(stage), aurora.Bold(count), gc.logSuffix()))
+		case len(rules) == 0:
+			logWarn(fmt.Sprintf("%s found no priority executables%s - swap processes will run indefinitely",
+				aurora.Cyan(stage), gc.logSuffix()))
+		}
+
+		if len(rules) > 0 {
+			ruleCount := strconv.Itoa(len(rules))
+			logInfo(fmt.Sprintf("%s registered %s priority rules%s", aurora.Cyan(stage), aurora.Bold(ruleCount), gc.logSuffix()))
+		}
+
+		// Setup swap scripts.
+		// At least one swap is required, so there's no need to check if no swap processes
+		// were passed in.
+		group := Group{
+			Name:          gc.Name,
+			Priorities:    pe,
+			PriorityPaths: paths,
+			Match:         gc.Match,
+			Rules:         rules,
+			Swaps:         []Swap{},
+		}
+
+		for i, key := range swapKeys(gc.Name, gc.Swaps) {
+			sc := gc.Swaps[i]
+
+			s, ok := r.swaps[key]
+			if !ok || !reflect.DeepEqual(r.configs[key], sc) {
+				s = sc.newSwap()
+			}
+
+			group.Swaps = append(group.Swaps, s)
+			swaps[key] = s
+			configs[key] = sc
+		}
+
+		swapCount := strconv.Itoa(len(gc.Swaps))
+		logInfo(fmt.Sprintf("%s registered %s swap processes%s", aurora.Cyan(stage), aurora.Bold(swapCount), gc.logSuffix()))
+		// Setup priority script.
+		// This will run once any priority starts. We wait for completion of the script.
+		if gc.PriorityScript != "" {
+			group.PriorityScript = gc.PriorityScript
+			logInfo(fmt.Sprintf("%s registered priority script %s%s", aurora.Cyan(stage), aurora.Bold(gc.PriorityScript), gc.logSuffix()))
+		}
+
+		groups = append(groups, group)
+	}
+
+	r.swaps = swaps
+	r.configs = configs
+
+	return groups, nil
+}
+
+// absPath returns the absolute form of path, or path itself if it can't be
+// made absolute.
+func absPath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+
+	return abs
+}
+
+// swapKeys returns a key identifying each swap config of a group. Swaps are
+// identified by their group and name or path, a swap listed more than once
+// gets its occurrence appended.
+func swapKeys(group string, swaps []swapConfig) []string {
+	keys := make([]string, 0, len(swaps))
+	seen := map[string]int{}
+
+	for _, s := range swaps {
+		key := group + "/" + s.key()
+
+		seen[key]++
+		if seen[key] > 1 {
+			key += "#" + strconv.Itoa(seen[key])
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// start begins listening for SIGHUP and, if a config file is set, for changes
+// to the config file.
+func (r *reloader) start() {
+	signal.Notify(r.signals, syscall.SIGHUP)
+
+	if path := r.c.String(flagConfigName); path != "" {
+		watcher, err := fsnotify.NewWatcher()
+		if err != nil {
+			logWarn(fmt.Sprintf("%s unable to watch config file %s: %s", aurora.Cyan("reload"), path, err.Error()))
+		} else if err := watcher.Add(filepath.Dir(path)); err != nil {
+			// Watch the directory instead of the file itself since editors
+			// often replace the file on save, which would end the watch.
+			logWarn(fmt.Sprintf("%s unable to watch config file %s: %s", aurora.Cyan("reload"), path, err.Error()))
+			watcher.Close()
+		} else {
+			r.watcher = watcher
+		}
+	}
+
+	go r.listen()
+}
+
+// stop stops listening for reloads.
+func (r *reloader) stop() {
+	signal.Stop(r.signals)
+	close(r.done)
+
+	if r.watcher != nil {
+		r.watcher.Close()
+	}
+}
+
+// listen reloads the loop on SIGHUP or once the config file has settled after a change.
+func (r *reloader) listen() {
+	var (
+		events  chan fsnotify.Event
+		errs    chan error
+		timer   *time.Timer
+		settled <-chan time.Time
+	)
+
+	if r.watcher != nil {
+		events = r.watcher.Events
+		errs = r.watcher.Errors
+	}
+
+	path := filepath.Clean(r.c.String(flagConfigName))
+
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-r.signals:
+			logInfo(fmt.Sprintf("%s received SIGHUP", aurora.Cyan("reload")))
+			r.reload()
+		case event, ok := <-events:
+			if !ok {
+				events = nil
+
+				continue
+			}
+
+			if filepath.Clean(event.Name) != path || event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 {
+				continue
+			}
+
+			if timer == nil {
+				timer = time.NewTimer(reloadDebounce)
+			} else {
+				timer.Reset(reloadDebounce)
+			}
+
+			settled = timer.C
+		case <-settled:
+			settled = nil
+
+			logInfo(fmt.Sprintf("%s config file %s changed", aurora.Cyan("reload"), aurora.Bold(path)))
+			r.reload()
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+
+				continue
+			}
+
+			logWarn(fmt.Sprintf("%s error watching config file: %s", aurora.Cyan("reload"), err.Error()))
+		}
+	}
+}
+
+// reload reloads the config, rescans the priorities and reconciles the
+// running loop. If anything is wrong with the new config the loop is left
+// as it is.
+func (r *reloader) reload() {
+	cfg, err := configFromContext(r.c)
+	if err != nil {
+		logError(fmt.Sprintf("%s %s; keeping current configuration", aurora.Cyan("reload"), err.Error()))
+
+		return
+	}
+
+	previous := r.swaps
+
+	groups, err := r.groupsFor(cfg, "reload")
+	if err != nil {
+		logError(fmt.Sprintf("%s %s; keeping current configuration", aurora.Cyan("reload"), err.Error()))
+
+		return
+	}
+
+	if cfg.PollInterval > 0 {
+		r.loop.WithPollInterval(cfg.PollInterval)
+	}
+
+	r.loop.Reload(groups)
+	r.closeDropped(previous)
+}
+
+// closeDropped closes the log files of the previous swaps that were removed
+// or replaced by a reload. The loop has stopped them by now, so their files
+// aren't left open, or rotated by two swaps at once when a replacement logs
+// to the same file.
+func (r *reloader) closeDropped(previous map[string]Swap) {
+	for key, s := range previous {
+		if r.swaps[key] == s {
+			continue
+		}
+
+		if err := s.CloseOutputLog(); err != nil {
+			logWarn(fmt.Sprintf("%s error closing log file of %s: %s", aurora.Cyan("reload"), s.Path(), err.Error()))
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package procswap
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Restart policies decide if a swap is restarted when it exits on its own.
+const (
+	// RestartNever leaves a swap that exits stopped until the next time swaps
+	// are started. This is the default.
+	RestartNever = "never"
+	// RestartOnFailure restarts a swap that exits with a non-zero exit code
+	// or is killed by a signal.
+	RestartOnFailure = "on-failure"
+	// RestartAlways restarts a swap whenever it exits.
+	RestartAlways = "always"
+)
+
+const (
+	// defaultRestartBackoff is how long to wait before the first restart when
+	// no backoff is set.
+	defaultRestartBackoff = time.Second
+	// defaultRestartMaxBackoff is the longest wait between restarts when no
+	// maximum is set.
+	defaultRestartMaxBackoff = 5 * time.Minute
+	// restartResetAfter is how long a swap has to run for its retries to be
+	// counted from zero again.
+	restartResetAfter = time.Minute
+)
+
+// restartPolicies is every restart policy.
+var restartPolicies = []string{RestartNever, RestartOnFailure, RestartAlways}
+
+// RestartPolicy decides if and when a swap that exits on its own is
+// restarted. The wait between restarts doubles after each attempt.
+type RestartPolicy struct {
+	// Policy is one of the restart policies, it defaults to RestartNever.
+	Policy string
+	// MaxRetries is the number of restarts in a row before giving up (0 = infinite).
+	MaxRetries int
+	// Backoff is the wait before the first restart.
+	Backoff time.Duration
+	// MaxBackoff is the longest wait between restarts.
+	MaxBackoff time.Duration
+}
+
+// validate returns an error if the policy is unknown or any value is negative.
+func (r RestartPolicy) validate() error {
+	if r.Policy != "" && !contains(restartPolicies, r.Policy) {
+		return fmt.Errorf("unknown restart policy %s, use one of %s", r.Policy, strings.Join(restartPolicies, ", "))
+	}
+
+	if r.MaxRetries < 0 || r.Backoff < 0 || r.MaxBackoff < 0 {
+		return errors.New("restart max retries and backoff can't be negative")
+	}
+
+	return nil
+}
+
+// shouldRestart returns true if a swap that exited with exitCode should be
+// restarted after retries restarts in a row.
+func (r RestartPolicy) shouldRestart(exitCode, retries int) bool {
+	if r.MaxRetries > 0 && retries >= r.MaxRetries {
+		return false
+	}
+
+	switch r.Policy {
+	case RestartAlways:
+		return true
+	case RestartOnFailure:
+		return exitCode != 0
+	default:
+		return false
+	}
+}
+
+// backoff returns how long to wait before the given restart attempt,
+// starting at 1.
+func (r RestartPolicy) backoff(attempt int) time.Duration {
+	backoff := r.Backoff
+	if backoff == 0 {
+		backoff = defaultRestartBackoff
+	}
+
+	maxBackoff := r.MaxBackoff
+	if maxBackoff == 0 {
+		maxBackoff = defaultRestartMaxBackoff
+	}
+
+	for i := 1; i < attempt && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+
+	if backoff > maxBackoff {
+		return maxBackoff
+	}
+
+	return backoff
+}
@@ -0,0 +1,69 @@
+package procswap
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RestartPolicy", func() {
+	var policy RestartPolicy
+
+	BeforeEach(func() {
+		policy = RestartPolicy{}
+	})
+
+	Describe("#shouldRestart", func() {
+		When("there is no policy", func() {
+			It("never restarts", func() {
+				Expect(policy.shouldRestart(1, 0)).To(BeFalse())
+			})
+		})
+
+		When("the policy is on-failure", func() {
+			BeforeEach(func() {
+				policy = RestartPolicy{Policy: RestartOnFailure, MaxRetries: 3}
+			})
+
+			It("restarts on a non-zero exit code until it runs out of retries", func() {
+				Expect(policy.shouldRestart(0, 0)).To(BeFalse())
+				Expect(policy.shouldRestart(1, 2)).To(BeTrue())
+				Expect(policy.shouldRestart(1, 3)).To(BeFalse())
+			})
+		})
+
+		When("the policy is always", func() {
+			BeforeEach(func() {
+				policy = RestartPolicy{Policy: RestartAlways}
+			})
+
+			It("restarts forever", func() {
+				Expect(policy.shouldRestart(0, 1000)).To(BeTrue())
+			})
+		})
+	})
+
+	Describe("#backoff", func() {
+		BeforeEach(func() {
+			policy = RestartPolicy{Backoff: time.Second, MaxBackoff: 5 * time.Second}
+		})
+
+		It("doubles up to the max backoff", func() {
+			Expect(policy.backoff(1)).To(Equal(time.Second))
+			Expect(policy.backoff(2)).To(Equal(2 * time.Second))
+			Expect(policy.backoff(3)).To(Equal(4 * time.Second))
+			Expect(policy.backoff(4)).To(Equal(5 * time.Second))
+		})
+	})
+
+	Describe("#validate", func() {
+		BeforeEach(func() {
+			policy = RestartPolicy{Policy: "sometimes"}
+		})
+
+		It("returns an error for an unknown policy", func() {
+			Expect(policy.validate()).To(MatchError("unknown restart policy sometimes, use one of never, on-failure, always"))
+		})
+	})
+})
@@ -0,0 +1,78 @@
+package procswap
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Rule is a priority declared as a pattern. It is matched against the names of
+// running processes instead of against executables found in a directory.
+type Rule interface {
+	Match(string) bool
+	String() string
+}
+
+type globRule struct {
+	pattern string
+	lower   string
+}
+
+// NewGlobRule returns a Rule that matches process names against a glob
+// pattern, ignoring case.
+func NewGlobRule(pattern string) (Rule, error) {
+	lower := strings.ToLower(pattern)
+	if err := validPattern(lower); err != nil {
+		return nil, fmt.Errorf("invalid priority glob %s: %w", pattern, err)
+	}
+
+	return &globRule{pattern: pattern, lower: lower}, nil
+}
+
+// validPattern returns an error if a glob pattern is malformed. Match only
+// reports a malformed pattern when it gets that far, so patterns are checked
+// up front.
+func validPattern(pattern string) error {
+	_, err := filepath.Match(pattern, "")
+
+	return err
+}
+
+// Match returns true if name matches the glob pattern.
+func (r *globRule) Match(name string) bool {
+	ok, _ := filepath.Match(r.lower, strings.ToLower(name))
+
+	return ok
+}
+
+// String returns the glob pattern.
+func (r *globRule) String() string {
+	return r.pattern
+}
+
+type regexRule struct {
+	pattern string
+	regex   *regexp.Regexp
+}
+
+// NewRegexRule returns a Rule that matches process names against a regular
+// expression, ignoring case.
+func NewRegexRule(pattern string) (Rule, error) {
+	regex, err := regexp.Compile("(?i)" + pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid priority regex %s: %w", pattern, err)
+	}
+
+	return &regexRule{pattern: pattern, regex: regex}, nil
+}
+
+// Match returns true if name matches the regular expression.
+func (r *regexRule) Match(name string) bool {
+	return r.regex.MatchString(name)
+}
+
+// String returns the regular expression.
+func (r *regexRule) String() string {
+	return r.pattern
+}
@@ -0,0 +1,68 @@
+package procswap_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/billiford/procswap/internal"
+)
+
+var _ = Describe("Rule", func() {
+	var (
+		rule Rule
+		err  error
+	)
+
+	Describe("#NewGlobRule", func() {
+		When("the pattern is malformed", func() {
+			BeforeEach(func() {
+				rule, err = NewGlobRule("[")
+			})
+
+			It("returns an error", func() {
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(HavePrefix("invalid priority glob ["))
+			})
+		})
+
+		When("it succeeds", func() {
+			BeforeEach(func() {
+				rule, err = NewGlobRule("*-Win64-Shipping.exe")
+			})
+
+			It("matches process names ignoring case", func() {
+				Expect(err).To(BeNil())
+				Expect(rule.String()).To(Equal("*-Win64-Shipping.exe"))
+				Expect(rule.Match("Game-Win64-Shipping.exe")).To(BeTrue())
+				Expect(rule.Match("game-win64-shipping.EXE")).To(BeTrue())
+				Expect(rule.Match("Game-Win64-Shipping.exe.bak")).To(BeFalse())
+			})
+		})
+	})
+
+	Describe("#NewRegexRule", func() {
+		When("the regular expression is invalid", func() {
+			BeforeEach(func() {
+				rule, err = NewRegexRule("(")
+			})
+
+			It("returns an error", func() {
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(HavePrefix("invalid priority regex ("))
+			})
+		})
+
+		When("it succeeds", func() {
+			BeforeEach(func() {
+				rule, err = NewRegexRule("^eldenring")
+			})
+
+			It("matches process names ignoring case", func() {
+				Expect(err).To(BeNil())
+				Expect(rule.String()).To(Equal("^eldenring"))
+				Expect(rule.Match("EldenRing.exe")).To(BeTrue())
+				Expect(rule.Match("start_protected_game.exe")).To(BeFalse())
+			})
+		})
+	})
+})
@@ -0,0 +1,33 @@
+package procswap
+
+import "errors"
+
+// RunAs is the user and group a swap runs as. The zero value runs the swap
+// as the user procswap runs as.
+type RunAs struct {
+	// User is the name or ID of the user.
+	User string
+	// Group is the name or ID of the group, it defaults to the user's
+	// primary group. It can't be set without a user.
+	Group string
+}
+
+// isZero returns true if neither the user nor the group is set.
+func (r RunAs) isZero() bool {
+	return r.User == "" && r.Group == ""
+}
+
+// validate returns an error if a group is set without a user. The swap would
+// run as procswap's user, often root, with only its group changed.
+func (r RunAs) validate() error {
+	if r.User == "" && r.Group != "" {
+		return errors.New("a group to run as needs a user to run as too")
+	}
+
+	return nil
+}
+
+// WithRunAs sets the user and group the swap runs as.
+func (s *swap) WithRunAs(runAs RunAs) {
+	s.runAs = runAs
+}
@@ -0,0 +1,224 @@
+//go:build !windows
+// +build !windows
+
+package procswap
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"syscall"
+)
+
+// account is the user a swap runs as, along with the group and the
+// supplementary groups it runs with.
+type account struct {
+	name   string
+	home   string
+	uid    uint32
+	gid    uint32
+	groups []uint32
+}
+
+// lookupAccount looks up the user and group a swap runs as.
+func lookupAccount(r RunAs) (account, error) {
+	if err := r.validate(); err != nil {
+		return account{}, err
+	}
+
+	u, err := lookupUser(r.User)
+	if err != nil {
+		return account{}, err
+	}
+
+	uid, err := parseID(u.Uid)
+	if err != nil {
+		return account{}, fmt.Errorf("invalid uid of user %s: %w", u.Username, err)
+	}
+
+	gid, err := parseID(u.Gid)
+	if err != nil {
+		return account{}, fmt.Errorf("invalid gid of user %s: %w", u.Username, err)
+	}
+
+	if r.Group != "" {
+		g, err := lookupGroup(r.Group)
+		if err != nil {
+			return account{}, err
+		}
+
+		if gid, err = parseID(g.Gid); err != nil {
+			return account{}, fmt.Errorf("invalid gid of group %s: %w", g.Name, err)
+		}
+	}
+
+	ids, err := u.GroupIds()
+	if err != nil {
+		return account{}, fmt.Errorf("error listing the groups of user %s: %w", u.Username, err)
+	}
+
+	groups := make([]uint32, 0, len(ids))
+
+	for _, id := range ids {
+		group, err := parseID(id)
+		if err != nil {
+			return account{}, fmt.Errorf("invalid gid of a group of user %s: %w", u.Username, err)
+		}
+
+		groups = append(groups, group)
+	}
+
+	return account{name: u.Username, home: u.HomeDir, uid: uid, gid: gid, groups: groups}, nil
+}
+
+// lookupUser looks up a user by name, then by ID.
+func lookupUser(name string) (*user.User, error) {
+	u, err := user.Lookup(name)
+	if err == nil {
+		return u, nil
+	}
+
+	if u, err := user.LookupId(name); err == nil {
+		return u, nil
+	}
+
+	return nil, fmt.Errorf("user %s does not exist", name)
+}
+
+// lookupGroup looks up a group by name, then by ID.
+func lookupGroup(name string) (*user.Group, error) {
+	g, err := user.LookupGroup(name)
+	if err == nil {
+		return g, nil
+	}
+
+	if g, err := user.LookupGroupId(name); err == nil {
+		return g, nil
+	}
+
+	return nil, fmt.Errorf("group %s does not exist", name)
+}
+
+// parseID parses a user or group ID.
+func parseID(id string) (uint32, error) {
+	n, err := strconv.ParseUint(id, 10, 32)
+
+	return uint32(n), err
+}
+
+// setRunAs makes the command run as the user and group a swap runs as, with
+// the user's supplementary groups. HOME, USER and LOGNAME are set to the
+// user's, as programs that keep files in their home directory would
+// otherwise look in procswap's. Changing the user needs root.
+func setRunAs(cmd *exec.Cmd, r RunAs) error {
+	if r.isZero() {
+		return nil
+	}
+
+	a, err := lookupAccount(r)
+	if err != nil {
+		return err
+	}
+
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+
+	cmd.SysProcAttr.Credential = &syscall.Credential{Uid: a.uid, Gid: a.gid, Groups: a.groups}
+	cmd.Env = append(os.Environ(), "HOME="+a.home, "USER="+a.name, "LOGNAME="+a.name)
+
+	return nil
+}
+
+// checkRunAs returns an error if the user or group a swap runs as does not
+// exist, or the user can't execute path because of its permissions or those
+// of a directory it is in.
+func checkRunAs(r RunAs, path string) error {
+	a, err := lookupAccount(r)
+	if err != nil {
+		return err
+	}
+
+	if filepath.Base(path) == path {
+		if path, err = exec.LookPath(path); err != nil {
+			return err
+		}
+	}
+
+	path, err = filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+
+	dirs := []string{}
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		dirs = append([]string{dir}, dirs...)
+
+		if dir == filepath.Dir(dir) {
+			break
+		}
+	}
+
+	for _, dir := range dirs {
+		info, err := os.Stat(dir)
+		if err != nil {
+			return err
+		}
+
+		if !a.canExecute(info) {
+			return fmt.Errorf("user %s can't search directory %s", a.name, dir)
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	if !a.canExecute(info) {
+		return fmt.Errorf("user %s can't execute %s", a.name, path)
+	}
+
+	return nil
+}
+
+// canExecute returns true if the account can execute a file, or search a
+// directory, going by its permission bits. Root can search any directory
+// and execute any file with an execute bit set.
+func (a account) canExecute(info os.FileInfo) bool {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return true
+	}
+
+	perm := info.Mode().Perm()
+
+	switch {
+	case a.uid == 0:
+		return info.IsDir() || perm&0o111 != 0
+	case stat.Uid == a.uid:
+		return perm&0o100 != 0
+	case a.inGroup(stat.Gid):
+		return perm&0o010 != 0
+	default:
+		return perm&0o001 != 0
+	}
+}
+
+// inGroup returns true if the account runs with the group.
+func (a account) inGroup(gid uint32) bool {
+	if a.gid == gid {
+		return true
+	}
+
+	for _, group := range a.groups {
+		if group == gid {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,21 @@
+package procswap
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// errRunAsNotSupported is returned when a swap is set to run as another user on Windows.
+var errRunAsNotSupported = errors.New("running a swap as another user is not supported on windows")
+
+func setRunAs(cmd *exec.Cmd, r RunAs) error {
+	if r.isZero() {
+		return nil
+	}
+
+	return errRunAsNotSupported
+}
+
+func checkRunAs(r RunAs, path string) error {
+	return errRunAsNotSupported
+}
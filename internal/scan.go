@@ -0,0 +1,139 @@
+package procswap
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	outputCSV   = "csv"
+	outputJSON  = "json"
+	outputTable = "table"
+)
+
+// scanResult is an executable found by the scan command.
+type scanResult struct {
+	// Group is the group the priority path belongs to.
+	Group string `json:"group"`
+	// Name is the file name of the executable.
+	Name string `json:"name"`
+	// Path is the full path of the executable.
+	Path string `json:"path"`
+	// Source is the priority path the executable was found in.
+	Source string `json:"source"`
+	// Ignored is true if the executable is ignored.
+	Ignored bool `json:"ignored"`
+}
+
+// scan walks every priority path and prints each executable found, including
+// the ones that are ignored. Nothing is logged so the output can be parsed.
+func scan(c *cli.Context) error {
+	format := c.String(flagOutputName)
+	if format != outputTable && format != outputJSON && format != outputCSV {
+		return fmt.Errorf("unknown output format %s, use %s, %s or %s", format, outputTable, outputJSON, outputCSV)
+	}
+
+	cfg, err := readConfig(c)
+	if err != nil {
+		return err
+	}
+
+	groups := cfg.Groups
+	if len(cfg.Priorities) > 0 {
+		groups = append([]groupConfig{cfg.defaultGroup()}, groups...)
+	}
+
+	if len(groups) == 0 {
+		return errors.New("no priorities to scan, pass --priority or set priorities in the config file")
+	}
+
+	results := []scanResult{}
+
+	for _, gc := range groups {
+		for _, p := range gc.Priorities {
+			path := p.Path
+
+			execs, err := findExecutables(path, gc.Ignore, p.discovery(), func() {})
+			if err != nil {
+				return fmt.Errorf("error scanning %s: %w", path, err)
+			}
+
+			source, err := filepath.Abs(path)
+			if err != nil {
+				return fmt.Errorf("error getting absolute path of %s: %w", path, err)
+			}
+
+			sort.Slice(execs, func(i, j int) bool {
+				return execs[i].path < execs[j].path
+			})
+
+			for _, e := range execs {
+				// The path is under source, which is already absolute.
+				p, _ := filepath.Abs(e.path)
+
+				results = append(results, scanResult{
+					Group:   gc.Name,
+					Name:    e.dirent.Name(),
+					Path:    p,
+					Source:  source,
+					Ignored: e.ignored,
+				})
+			}
+		}
+	}
+
+	switch format {
+	case outputJSON:
+		return writeScanJSON(c.App.Writer, results)
+	case outputCSV:
+		return writeScanCSV(c.App.Writer, results)
+	default:
+		return writeScanTable(c.App.Writer, results)
+	}
+}
+
+func writeScanJSON(w io.Writer, results []scanResult) error {
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+
+	return e.Encode(results)
+}
+
+func writeScanCSV(w io.Writer, results []scanResult) error {
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write([]string{"group", "name", "path", "source", "ignored"}); err != nil {
+		return err
+	}
+
+	for _, r := range results {
+		if err := cw.Write([]string{r.Group, r.Name, r.Path, r.Source, strconv.FormatBool(r.Ignored)}); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
+
+func writeScanTable(w io.Writer, results []scanResult) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "GROUP\tNAME\tPATH\tSOURCE\tIGNORED")
+
+	for _, r := range results {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%t\n", r.Group, r.Name, r.Path, r.Source, r.Ignored)
+	}
+
+	return tw.Flush()
+}
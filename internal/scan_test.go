@@ -0,0 +1,117 @@
+package procswap_test
+
+import (
+	"encoding/json"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+
+	"github.com/urfave/cli/v2"
+
+	. "github.com/billiford/procswap/internal"
+)
+
+var _ = Describe("Scan", func() {
+	var (
+		err    error
+		app    *cli.App
+		args   []string
+		buffer *Buffer
+	)
+
+	BeforeEach(func() {
+		app = NewApp()
+		buffer = NewBuffer()
+		app.Writer = buffer
+		args = []string{procswapFilename(), "scan",
+			"-p", priorityFileDir(),
+			"-i", "ignore_me.exe",
+		}
+	})
+
+	JustBeforeEach(func() {
+		err = app.Run(args)
+	})
+
+	When("there are no priorities", func() {
+		BeforeEach(func() {
+			args = []string{procswapFilename(), "scan"}
+		})
+
+		It("returns an error", func() {
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(Equal("no priorities to scan, pass --priority or set priorities in the config file"))
+		})
+	})
+
+	When("the output format is unknown", func() {
+		BeforeEach(func() {
+			args = append(args, "-o", "xml")
+		})
+
+		It("returns an error", func() {
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(Equal("unknown output format xml, use table, json or csv"))
+		})
+	})
+
+	When("the output format is json", func() {
+		BeforeEach(func() {
+			args = append(args, "-o", "json")
+		})
+
+		It("prints every executable as json", func() {
+			Expect(err).To(BeNil())
+
+			results := []map[string]interface{}{}
+			Expect(json.Unmarshal(buffer.Contents(), &results)).To(Succeed())
+			Expect(results).To(HaveLen(5))
+			Expect(results[0]).To(Equal(map[string]interface{}{
+				"group":   "default",
+				"name":    "ignore_me.exe",
+				"path":    filepath.Join(priorityFileDir(), "ignore_me.exe"),
+				"source":  priorityFileDir(),
+				"ignored": true,
+			}))
+			Expect(results[1]["ignored"]).To(BeFalse())
+		})
+	})
+
+	When("the output format is csv", func() {
+		BeforeEach(func() {
+			args = append(args, "-o", "csv")
+		})
+
+		It("prints every executable as csv", func() {
+			Expect(err).To(BeNil())
+			Expect(buffer).To(Say(`group,name,path,source,ignored\n`))
+			Expect(buffer).To(Say(`default,ignore_me.exe,.*ignore_me.exe,.*,true\n`))
+			Expect(buffer).To(Say(`default,test1.exe,.*test1.exe,.*,false\n`))
+		})
+	})
+
+	When("a discovery mode is passed in", func() {
+		BeforeEach(func() {
+			args = append(args, "-o", "csv", "--discovery", "glob", "--discovery-pattern", "test*")
+		})
+
+		It("only prints the executables it finds", func() {
+			Expect(err).To(BeNil())
+			Expect(buffer).To(Say(`group,name,path,source,ignored\n`))
+			Expect(buffer).To(Say(`default,test1.exe,.*test1.exe,.*,false\n`))
+			Expect(buffer).To(Say(`default,test2.exe,.*test2.exe,.*,false\n`))
+			Expect(buffer).ToNot(Say(`.`))
+		})
+	})
+
+	When("it succeeds", func() {
+		It("prints every executable in a table", func() {
+			Expect(err).To(BeNil())
+			Expect(buffer).To(Say(`GROUP\s+NAME\s+PATH\s+SOURCE\s+IGNORED\n`))
+			Expect(buffer).To(Say(`default\s+ignore_me.exe\s+.*ignore_me.exe\s+.*\s+true\n`))
+			Expect(buffer).To(Say(`default\s+test1.exe\s+.*test1.exe\s+.*\s+false\n`))
+		})
+	})
+})
@@ -0,0 +1,115 @@
+package procswap
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/eiannone/keyboard"
+	"github.com/logrusorgru/aurora"
+)
+
+// keyInputStopTimeout is how long to wait for key input to stop being read,
+// and the terminal to be restored, when shutting down.
+const keyInputStopTimeout = time.Second
+
+// WithLeaveSwapsRunning sets if the swaps are left running when the loop
+// exits on a shutdown signal, instead of being stopped.
+func (l *loop) WithLeaveSwapsRunning(leaveSwapsRunning bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.leaveSwapsRunning = leaveSwapsRunning
+}
+
+// WithShutdownSignals sets the signals that make the loop stop its swaps and
+// exit, by default SIGINT and SIGTERM.
+func (l *loop) WithShutdownSignals(signals ...os.Signal) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.shutdownSignals = signals
+}
+
+// interrupt shuts the loop down as if it received SIGINT.
+func (l *loop) interrupt() {
+	select {
+	case l.signals <- os.Interrupt:
+	default:
+	}
+}
+
+// shutdown stops reading key input, then stops every swap unless swaps are
+// left running, and returns the exit code for the signal. Swaps that are left
+// running are resumed or unthrottled first, as nothing undoes pausing or
+// throttling them once procswap is gone.
+func (l *loop) shutdown(sig os.Signal) int {
+	logWarn(fmt.Sprintf("%s received %s", aurora.Cyan("shutdown"), sig))
+
+	l.stopKeyInput()
+	// Stopping swaps can take a while, so a second signal exits right away.
+	go func() {
+		sig := <-l.signals
+		logWarn(fmt.Sprintf("%s received %s again, exiting without waiting for swaps", aurora.Cyan("shutdown"), sig))
+		os.Exit(exitCode(sig))
+	}()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.leaveSwapsRunning {
+		logWarn(fmt.Sprintf("%s leaving swaps running", aurora.Cyan("shutdown")))
+
+		for _, g := range l.groups {
+			g.resumeSwaps()
+		}
+	} else {
+		// The lock is held while the swaps stop, so nothing starts them again.
+		for _, g := range l.groups {
+			g.stopSwaps()()
+		}
+	}
+
+	l.saveState()
+
+	return exitCode(sig)
+}
+
+// stopKeyInput stops reading key input and waits for the terminal to be
+// restored, so procswap doesn't leave it in raw mode.
+func (l *loop) stopKeyInput() {
+	close(l.quit)
+
+	timeout := time.After(keyInputStopTimeout)
+
+	for {
+		select {
+		case <-l.keysDone:
+			return
+		default:
+		}
+		// Cancels a key being read, which restores the terminal.
+		_ = keyboard.Close()
+
+		select {
+		case <-l.keysDone:
+			return
+		case <-timeout:
+			logWarn(fmt.Sprintf("%s key input did not stop, the terminal may need a reset", aurora.Cyan("shutdown")))
+
+			return
+		case <-time.After(treePollInterval):
+		}
+	}
+}
+
+// exitCode returns the conventional exit code of a process ended by sig,
+// 128 plus the signal number.
+func exitCode(sig os.Signal) int {
+	if s, ok := sig.(syscall.Signal); ok {
+		return 128 + int(s)
+	}
+
+	return 1
+}
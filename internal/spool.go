@@ -0,0 +1,177 @@
+package procswap
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// spoolPollInterval is how often a spool file is checked for more output
+	// once everything in it has been read.
+	spoolPollInterval = 100 * time.Millisecond
+	// spoolMaxSize is the size in bytes a spool file grows to before it is
+	// emptied, once everything in it has been read.
+	spoolMaxSize = 1 << 20
+)
+
+// spoolPathFor returns the path, without the stream, of the files in dir a
+// swap's output is spooled to. The key tells apart swaps with the same name.
+func spoolPathFor(dir, key string) string {
+	if dir == "" {
+		return ""
+	}
+
+	return filepath.Join(dir, nameUnsafe.ReplaceAllString(key, "_"))
+}
+
+// WithSpool sets the files the swap writes its output to, instead of pipes
+// procswap holds the other end of. A swap writing to a pipe is killed by
+// SIGPIPE once procswap exits, so swaps that are meant to outlive procswap
+// spool their output. A path of os.DevNull discards it.
+func (s *swap) WithSpool(path string) {
+	s.spool = path
+}
+
+// openSpools points the command's stdout and stderr at the swap's spool
+// files, emptying them. The caller closes the files once the command has
+// started, the swap keeps its own copies.
+func (s *swap) openSpools(cmd *exec.Cmd) ([]*os.File, error) {
+	if s.spool == os.DevNull {
+		null, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		cmd.Stdout = null
+		cmd.Stderr = null
+
+		return []*os.File{null}, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.spool), 0o755); err != nil {
+		return nil, err
+	}
+
+	stdout, err := os.OpenFile(s.spool+"."+streamStdout, os.O_WRONLY|os.O_CREATE|os.O_TRUNC|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	stderr, err := os.OpenFile(s.spool+"."+streamStderr, os.O_WRONLY|os.O_CREATE|os.O_TRUNC|os.O_APPEND, 0o644)
+	if err != nil {
+		stdout.Close()
+
+		return nil, err
+	}
+
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	return []*os.File{stdout, stderr}, nil
+}
+
+// followSpools reads the output the swap writes to its spool files until it
+// exits. An adopted swap is followed from the end of its files, as what is in
+// them was read by the procswap that started it. Output written to the null
+// device is gone.
+func (s *swap) followSpools(exited <-chan struct{}, fromEnd bool) {
+	if s.spool == os.DevNull {
+		return
+	}
+
+	for _, stream := range []string{streamStdout, streamStderr} {
+		r, err := followSpool(s.spool+"."+stream, exited, fromEnd)
+		if err != nil {
+			s.logEvent("error reading " + stream + ": " + err.Error())
+
+			continue
+		}
+
+		go s.readOutput(r, stream)
+	}
+}
+
+// spoolReader reads a spool file as the swap writes to it. It waits for
+// more output at the end of the file, until the swap exits.
+type spoolReader struct {
+	f      *os.File
+	exited <-chan struct{}
+	offset int64
+	done   bool
+}
+
+func followSpool(path string, exited <-chan struct{}, fromEnd bool) (*spoolReader, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &spoolReader{f: f, exited: exited}
+
+	if fromEnd {
+		if r.offset, err = f.Seek(0, io.SeekEnd); err != nil {
+			f.Close()
+
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// Read reads what the swap has written since the last read, waiting for it
+// to write more if it hasn't. It returns io.EOF once the swap has exited and
+// everything it wrote has been read.
+func (r *spoolReader) Read(p []byte) (int, error) {
+	for {
+		n, err := r.f.Read(p)
+		r.offset += int64(n)
+
+		if n > 0 {
+			return n, nil
+		}
+
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+
+		if r.done {
+			return 0, io.EOF
+		}
+
+		r.rewind()
+
+		select {
+		case <-r.exited:
+			// Read what was written right before the swap exited, then stop.
+			r.done = true
+		case <-time.After(spoolPollInterval):
+		}
+	}
+}
+
+// rewind empties the spool file once it has grown too big and everything in
+// it has been read. The swap appends to the file, so its next write lands at
+// the start. Output written between the last read and emptying the file is
+// lost, which is rare enough to not be worth locking the file for.
+func (r *spoolReader) rewind() {
+	if r.offset < spoolMaxSize {
+		return
+	}
+
+	if err := r.f.Truncate(0); err != nil {
+		return
+	}
+
+	if _, err := r.f.Seek(0, io.SeekStart); err == nil {
+		r.offset = 0
+	}
+}
+
+// Close closes the spool file.
+func (r *spoolReader) Close() error {
+	return r.f.Close()
+}
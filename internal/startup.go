@@ -0,0 +1,173 @@
+package procswap
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultReadyTimeout is how long swaps wait for the swaps they start
+	// after to be ready when no timeout is set.
+	defaultReadyTimeout = time.Minute
+	// readyPollInterval is how often a swap's port is checked while waiting
+	// for it to be ready.
+	readyPollInterval = 500 * time.Millisecond
+)
+
+// Startup decides when a swap starts relative to the other swaps of its
+// group. The zero value starts the swap straight away.
+type Startup struct {
+	// Delay is how long to wait before starting the swap, once the swaps it
+	// starts after are ready.
+	Delay time.Duration
+	// After are the names of the swaps of the same group that have to be
+	// ready before the swap starts.
+	After []string
+	// Ready is when the swap is ready for the swaps that start after it.
+	Ready ReadyCondition
+}
+
+// ReadyCondition decides when a started swap is ready. The zero value is
+// ready as soon as the swap starts.
+type ReadyCondition struct {
+	// TCP is an address, like 127.0.0.1:3333, that has to accept a connection.
+	TCP string
+	// Output is a regular expression a line of the swap's output has to match.
+	Output string
+	// Timeout is how long swaps wait for the swap to be ready before
+	// starting anyway.
+	Timeout time.Duration
+}
+
+// isZero returns true if the swap starts straight away.
+func (s Startup) isZero() bool {
+	return s.Delay == 0 && len(s.After) == 0 && s.Ready == ReadyCondition{}
+}
+
+// validate returns an error if the ready condition is malformed or any
+// value is negative.
+func (s Startup) validate() error {
+	if s.Delay < 0 || s.Ready.Timeout < 0 {
+		return errors.New("start delay and ready timeout can't be negative")
+	}
+
+	if s.Ready.TCP != "" && s.Ready.Output != "" {
+		return errors.New("ready condition can only set one of tcp or output")
+	}
+
+	if _, err := regexp.Compile(s.Ready.Output); err != nil {
+		return fmt.Errorf("invalid ready output %s: %w", s.Ready.Output, err)
+	}
+
+	return nil
+}
+
+// timeout returns the ready timeout, or the default timeout if it is not set.
+func (r ReadyCondition) timeout() time.Duration {
+	if r.Timeout == 0 {
+		return defaultReadyTimeout
+	}
+
+	return r.Timeout
+}
+
+// startOrder returns the indexes of names in the order they have to start
+// in, so each name comes after the names in its after list. Names in an
+// after list that aren't in names are ignored, and otherwise names keep
+// their order.
+func startOrder(names []string, after [][]string) ([]int, error) {
+	order := make([]int, 0, len(names))
+	placed := make([]bool, len(names))
+
+	for len(order) < len(names) {
+		progress := false
+
+		for i := range names {
+			if placed[i] || !dependenciesPlaced(names, after[i], placed) {
+				continue
+			}
+
+			order = append(order, i)
+			placed[i] = true
+			progress = true
+
+			break
+		}
+
+		if !progress {
+			cycle := []string{}
+
+			for i, name := range names {
+				if !placed[i] {
+					cycle = append(cycle, name)
+				}
+			}
+
+			return nil, fmt.Errorf("swaps %s start after each other", strings.Join(cycle, ", "))
+		}
+	}
+
+	return order, nil
+}
+
+// dependenciesPlaced returns true if every name in after that is in names
+// has been placed.
+func dependenciesPlaced(names, after []string, placed []bool) bool {
+	for _, dependency := range after {
+		for i, name := range names {
+			if name == dependency && !placed[i] {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// sortSwaps returns swaps in the order they have to start in, so each swap
+// starts after the swaps it depends on.
+func sortSwaps(swaps []Swap) ([]Swap, error) {
+	names := make([]string, len(swaps))
+	after := make([][]string, len(swaps))
+
+	for i, s := range swaps {
+		names[i] = s.Name()
+		after[i] = s.Startup().After
+	}
+
+	order, err := startOrder(names, after)
+	if err != nil {
+		return nil, err
+	}
+
+	sorted := make([]Swap, 0, len(swaps))
+	for _, i := range order {
+		sorted = append(sorted, swaps[i])
+	}
+
+	return sorted, nil
+}
+
+// waitForPort calls ready once addr accepts a connection, or returns once
+// exited is closed.
+func waitForPort(addr string, ready func(), exited <-chan struct{}) {
+	for {
+		conn, err := net.DialTimeout("tcp", addr, readyPollInterval)
+		if err == nil {
+			conn.Close()
+			ready()
+
+			return
+		}
+
+		select {
+		case <-exited:
+			return
+		case <-time.After(readyPollInterval):
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package procswap
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Startup", func() {
+	Describe("#startOrder", func() {
+		var (
+			names []string
+			after [][]string
+			order []int
+			err   error
+		)
+
+		JustBeforeEach(func() {
+			order, err = startOrder(names, after)
+		})
+
+		When("no swap starts after another", func() {
+			BeforeEach(func() {
+				names = []string{"xmrig", "t-rex"}
+				after = [][]string{nil, nil}
+			})
+
+			It("keeps the order", func() {
+				Expect(err).To(BeNil())
+				Expect(order).To(Equal([]int{0, 1}))
+			})
+		})
+
+		When("swaps start after others", func() {
+			BeforeEach(func() {
+				names = []string{"xmrig", "t-rex", "proxy"}
+				after = [][]string{{"proxy"}, {"xmrig", "unknown"}, nil}
+			})
+
+			It("starts them after their dependencies", func() {
+				Expect(err).To(BeNil())
+				Expect(order).To(Equal([]int{2, 0, 1}))
+			})
+		})
+
+		When("swaps start after each other", func() {
+			BeforeEach(func() {
+				names = []string{"proxy", "xmrig", "t-rex"}
+				after = [][]string{nil, {"t-rex"}, {"xmrig"}}
+			})
+
+			It("returns an error", func() {
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(Equal("swaps xmrig, t-rex start after each other"))
+			})
+		})
+	})
+
+	Describe("#validate", func() {
+		It("returns an error for a malformed ready output", func() {
+			err := Startup{Ready: ReadyCondition{Output: "("}}.validate()
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(HavePrefix("invalid ready output ("))
+		})
+	})
+})
@@ -0,0 +1,130 @@
+package procswap
+
+import "fmt"
+
+// SwapState is where a swap is in its lifecycle.
+type SwapState string
+
+// Swap states.
+const (
+	// SwapStateNew is a swap that has never been started.
+	SwapStateNew SwapState = "new"
+	// SwapStateStarting is a swap that has started but is not yet ready.
+	SwapStateStarting SwapState = "starting"
+	// SwapStateRunning is a swap that is running and ready.
+	SwapStateRunning SwapState = "running"
+	// SwapStateStopping is a swap procswap is stopping.
+	SwapStateStopping SwapState = "stopping"
+	// SwapStateStopped is a swap that exited after procswap stopped it.
+	SwapStateStopped SwapState = "stopped"
+	// SwapStateExited is a swap that exited on its own.
+	SwapStateExited SwapState = "exited"
+	// SwapStateKilled is a swap that was killed by a signal procswap didn't send.
+	SwapStateKilled SwapState = "killed"
+	// SwapStateFailed is a swap that failed to start.
+	SwapStateFailed SwapState = "failed"
+)
+
+// done returns true if the swap's process is no longer running.
+func (s SwapState) done() bool {
+	switch s {
+	case SwapStateStopped, SwapStateExited, SwapStateKilled, SwapStateFailed:
+		return true
+	default:
+		return false
+	}
+}
+
+// State returns where the swap is in its lifecycle.
+func (s *swap) State() SwapState {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+
+	if s.state == "" {
+		return SwapStateNew
+	}
+
+	return s.state
+}
+
+// setState moves the swap to a new state.
+func (s *swap) setState(state SwapState) {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+
+	s.state = state
+}
+
+// transition moves the swap to a new state, but only if it is in the state from.
+func (s *swap) transition(from, to SwapState) {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+
+	if s.state == from {
+		s.state = to
+	}
+}
+
+// stopping marks the swap as being stopped by procswap, unless it has
+// already exited.
+func (s *swap) stopping() {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+
+	if !s.state.done() {
+		s.state = SwapStateStopping
+	}
+}
+
+// exit moves the swap to the state it ended in once its process has exited:
+// stopped if procswap was stopping it, killed if a signal killed it and
+// exited otherwise.
+func (s *swap) exit(signaled bool) {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+
+	switch {
+	case s.state == SwapStateStopping:
+		s.state = SwapStateStopped
+	case signaled:
+		s.state = SwapStateKilled
+	default:
+		s.state = SwapStateExited
+	}
+}
+
+// describeExit describes how a swap that is no longer running ended.
+func describeExit(s Swap) string {
+	switch s.State() {
+	case SwapStateStopped:
+		return "was stopped"
+	case SwapStateKilled:
+		return "was killed by a signal"
+	case SwapStateFailed:
+		return "failed to start"
+	}
+	// The exit code of an adopted swap is unknown.
+	if s.ExitCode() < 0 {
+		return "exited"
+	}
+
+	return fmt.Sprintf("exited with code %d", s.ExitCode())
+}
+
+// describeState describes where a swap is in its lifecycle.
+func describeState(s Swap) string {
+	state := s.State()
+
+	switch {
+	case state.done():
+		return describeExit(s)
+	case s.Paused():
+		return fmt.Sprintf("is paused (pid %d)", s.PID())
+	case s.Throttled():
+		return fmt.Sprintf("is throttled (pid %d)", s.PID())
+	case state == SwapStateNew:
+		return "has not been started"
+	default:
+		return fmt.Sprintf("is %s (pid %d)", state, s.PID())
+	}
+}
@@ -0,0 +1,128 @@
+package procswap
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// defaultStopTimeout is how long to wait for a swap to exit after a stop
+// step when no timeout is set.
+const defaultStopTimeout = 10 * time.Second
+
+// signals are the signals that can be sent to stop a swap, by name.
+var signals = map[string]syscall.Signal{
+	"SIGHUP":  syscall.SIGHUP,
+	"SIGINT":  syscall.SIGINT,
+	"SIGKILL": syscall.SIGKILL,
+	"SIGQUIT": syscall.SIGQUIT,
+	"SIGTERM": syscall.SIGTERM,
+}
+
+// StopStrategy decides how a swap is stopped. The zero value kills the swap
+// straight away.
+//
+// If a command is set it runs first. Then each signal is sent in turn until
+// the swap exits. If the swap is still running after every step it is killed.
+type StopStrategy struct {
+	// Command is run to stop the swap, it gets the PID of the swap in the
+	// PROCSWAP_SWAP_PID environment variable.
+	Command string
+	// Args are the arguments passed to the command.
+	Args []string
+	// Timeout is how long to wait for the swap to exit after the command runs.
+	Timeout time.Duration
+	// Signals are sent one after the other until the swap exits.
+	Signals []StopSignal
+}
+
+// StopSignal is a signal sent to stop a swap and how long to wait for the
+// swap to exit after sending it.
+type StopSignal struct {
+	// Signal is the name of the signal, like SIGINT.
+	Signal string
+	// Timeout is how long to wait for the swap to exit.
+	Timeout time.Duration
+}
+
+// isZero returns true if the strategy has no steps.
+func (s StopStrategy) isZero() bool {
+	return s.Command == "" && len(s.Signals) == 0
+}
+
+// validate returns an error if any signal is unknown or any timeout is negative.
+func (s StopStrategy) validate() error {
+	if s.Timeout < 0 {
+		return fmt.Errorf("invalid stop timeout %s", s.Timeout)
+	}
+
+	for _, sig := range s.Signals {
+		if _, err := parseSignal(sig.Signal); err != nil {
+			return err
+		}
+
+		if sig.Timeout < 0 {
+			return fmt.Errorf("invalid stop timeout %s for %s", sig.Timeout, sig.Signal)
+		}
+	}
+
+	return nil
+}
+
+// parseSignal returns the signal with the given name, with or without
+// the SIG prefix and ignoring case.
+func parseSignal(name string) (syscall.Signal, error) {
+	key := strings.ToUpper(name)
+	if !strings.HasPrefix(key, "SIG") {
+		key = "SIG" + key
+	}
+
+	sig, ok := signals[key]
+	if !ok {
+		names := make([]string, 0, len(signals))
+		for n := range signals {
+			names = append(names, n)
+		}
+
+		sort.Strings(names)
+
+		return 0, fmt.Errorf("unknown stop signal %s, use one of %s", name, strings.Join(names, ", "))
+	}
+
+	return sig, nil
+}
+
+// stopTimeout returns timeout, or the default timeout if it is not set.
+func stopTimeout(timeout time.Duration) time.Duration {
+	if timeout == 0 {
+		return defaultStopTimeout
+	}
+
+	return timeout
+}
+
+// waitForExit waits up to timeout for exited to be closed, returning true
+// if it was.
+func waitForExit(exited <-chan struct{}, timeout time.Duration) bool {
+	select {
+	case <-exited:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// stopCommand builds the stop command of the swap. It runs in the same
+// directory as the swap.
+func (s *swap) stopCommand() *exec.Cmd {
+	cmd := exec.Command(s.stop.Command, s.stop.Args...)
+	cmd.Dir = s.cmd.Dir
+	cmd.Env = append(append(os.Environ(), s.env...), "PROCSWAP_SWAP_PID="+strconv.Itoa(s.cmd.Process.Pid))
+
+	return cmd
+}
@@ -1,42 +1,299 @@
 package procswap
 
 import (
-	"bufio"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/billiford/go-ps"
 )
 
 //go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 . Swap
 
+const (
+	// treeExitTimeout is how long to wait for a swap's process tree to exit
+	// after it has been killed.
+	treeExitTimeout = 5 * time.Second
+	// treePollInterval is how often to check if a swap's process tree has exited.
+	treePollInterval = 100 * time.Millisecond
+)
+
 // Swap holds functions to implement starting and stopping of batch files.
 type Swap interface {
+	Adopt(int) error
+	Name() string
 	Path() string
 	PID() int
 	Start() error
+	Stop() ([]string, error)
+	Pause() error
+	Resume() error
+	Paused() bool
+	Throttle() error
+	Unthrottle() error
+	Throttled() bool
+	Check() error
+	CloseOutputLog() error
 	Kill() error
 	Cmd() *exec.Cmd
+	Exited() <-chan struct{}
+	ExitCode() int
+	Health() HealthProbe
+	Mode() string
+	History() []string
+	Ready() <-chan struct{}
+	Restart() RestartPolicy
 	ShowOutput(bool)
+	Startup() Startup
+	State() SwapState
+	Stats() (SwapStats, error)
+	WithArgs([]string)
+	WithCgroup(Cgroup)
+	WithDir(string)
+	WithEnv([]string)
+	WithHealth(HealthProbe)
+	WithHistorySize(int)
+	WithMode(string)
+	WithName(string)
+	WithOutputLog(OutputLog)
+	WithRestart(RestartPolicy)
+	WithRunAs(RunAs)
+	WithSpool(string)
+	WithStartup(Startup)
+	WithStop(StopStrategy)
+	WithThrottle(Throttle)
 }
 
 type swap struct {
-	cmd        *exec.Cmd
-	path       string
-	ps         ps.Ps
+	// args are the arguments passed to the swap.
+	args []string
+	cmd  *exec.Cmd
+	// dir is the working directory of the swap. When empty it defaults to
+	// the directory the swap lives in.
+	dir string
+	// env holds extra environment variables in the form "KEY=value" that are
+	// added to procswap's own environment.
+	env []string
+	// name labels the swap's output, it defaults to the file name of the swap.
+	name string
+	path string
+	ps   ps.Ps
+	// outputMu guards showing the swap's output, so no line is missed or
+	// shown twice when its history is shown before its live output. It
+	// guards the log file too, which is closed when the swap is dropped.
+	outputMu   sync.Mutex
 	showOutput bool
+	// history holds the most recent lines of the swap's output.
+	history *history
+	// startup decides when the swap starts relative to the other swaps of its group.
+	startup Startup
+	// readyOutput matches the line of output that makes the swap ready.
+	readyOutput *regexp.Regexp
+	// ready is closed once the process started by the last call to Start is
+	// ready, and markReady closes it.
+	ready     chan struct{}
+	markReady func()
+	// stop is how the swap is stopped.
+	stop StopStrategy
+	// mode decides if the swap is stopped, paused or throttled when a priority starts.
+	mode string
+	// paused is true while the swap's process tree is frozen.
+	paused bool
+	// throttle is how the swap is slowed down in throttle mode.
+	throttle Throttle
+	// throttled holds the original settings of each process of the swap's
+	// tree while it is throttled, it is nil otherwise.
+	throttled map[int]processSettings
+	// cgroupWeight is the original CPU weight of the swap's cgroup while it is throttled.
+	cgroupWeight int
+	// cgroup is the cgroup v2 the swap runs in.
+	cgroup Cgroup
+	// runAs is the user and group the swap runs as.
+	runAs RunAs
+	// restart decides if the swap is restarted when it exits on its own.
+	restart RestartPolicy
+	// health checks that the running swap is healthy.
+	health HealthProbe
+	// output is where the swap's output is logged, it is nil when the output
+	// is not logged.
+	output io.WriteCloser
+	// spool is the path, without the stream, of the files the swap writes
+	// its output to. When empty the swap writes to pipes.
+	spool string
+	// exited is closed once the process started by the last call to Start exits.
+	exited chan struct{}
+	// processState is the state of the exited process, it is set before
+	// exited is closed.
+	processState *os.ProcessState
+	// stateMu guards state, which the goroutine waiting on the process sets.
+	stateMu sync.Mutex
+	// state is where the swap is in its lifecycle.
+	state SwapState
 }
 
 // NewSwap returns and implementation of Swap.
 func NewSwap(path string) Swap {
 	return &swap{
-		ps:   ps.New(),
-		path: path,
+		ps:      ps.New(),
+		name:    filepath.Base(path),
+		path:    path,
+		history: newHistory(defaultHistorySize),
 	}
 }
 
+// WithArgs sets the arguments passed to the swap.
+func (s *swap) WithArgs(args []string) {
+	s.args = args
+}
+
+// WithDir sets the working directory of the swap.
+func (s *swap) WithDir(dir string) {
+	s.dir = dir
+}
+
+// WithEnv sets extra environment variables, in the form "KEY=value", for the swap.
+func (s *swap) WithEnv(env []string) {
+	s.env = env
+}
+
+// WithHealth sets how the running swap is checked to be healthy.
+func (s *swap) WithHealth(health HealthProbe) {
+	s.health = health
+}
+
+// Health returns the swap's health probe.
+func (s *swap) Health() HealthProbe {
+	return s.health
+}
+
+// WithName sets the name the swap's output is labelled with.
+func (s *swap) WithName(name string) {
+	s.name = name
+}
+
+// Name returns the name the swap's output is labelled with.
+func (s *swap) Name() string {
+	return s.name
+}
+
+// WithStartup sets when the swap starts relative to the other swaps of its group.
+func (s *swap) WithStartup(startup Startup) {
+	s.outputMu.Lock()
+	defer s.outputMu.Unlock()
+
+	s.startup = startup
+	s.readyOutput = nil
+
+	if startup.Ready.Output != "" {
+		// The config makes sure the expression compiles.
+		s.readyOutput, _ = regexp.Compile(startup.Ready.Output)
+	}
+}
+
+// Startup returns when the swap starts relative to the other swaps of its group.
+func (s *swap) Startup() Startup {
+	return s.startup
+}
+
+// Ready returns a channel that is closed once the process started by the
+// last call to Start is ready. It returns nil if the swap was never started.
+func (s *swap) Ready() <-chan struct{} {
+	s.outputMu.Lock()
+	defer s.outputMu.Unlock()
+
+	return s.ready
+}
+
+// WithHistorySize sets the number of lines of output kept in the swap's
+// history. Any lines already kept are dropped.
+func (s *swap) WithHistorySize(size int) {
+	s.outputMu.Lock()
+	defer s.outputMu.Unlock()
+
+	s.history = newHistory(size)
+}
+
+// History returns the most recent lines of the swap's output, oldest first.
+func (s *swap) History() []string {
+	s.outputMu.Lock()
+	defer s.outputMu.Unlock()
+
+	return s.history.snapshot()
+}
+
+// WithOutputLog sets the file the swap's output is written to.
+func (s *swap) WithOutputLog(log OutputLog) {
+	s.output = log.writer()
+}
+
+// WithRestart sets if the swap is restarted when it exits on its own.
+func (s *swap) WithRestart(restart RestartPolicy) {
+	s.restart = restart
+}
+
+// Restart returns the swap's restart policy.
+func (s *swap) Restart() RestartPolicy {
+	return s.restart
+}
+
+// Exited returns a channel that is closed once the process started by the
+// last call to Start exits. It returns nil if the swap was never started.
+func (s *swap) Exited() <-chan struct{} {
+	return s.exited
+}
+
+// ExitCode returns the exit code of the swap's process, or -1 if it is
+// still running, was never started or was killed by a signal.
+func (s *swap) ExitCode() int {
+	if s.exited == nil {
+		return -1
+	}
+
+	select {
+	case <-s.exited:
+	default:
+		return -1
+	}
+
+	if s.processState == nil {
+		return -1
+	}
+
+	return s.processState.ExitCode()
+}
+
+// WithMode sets if the swap is stopped, paused or throttled when a priority starts.
+func (s *swap) WithMode(mode string) {
+	s.mode = mode
+}
+
+// Mode returns if the swap is stopped, paused or throttled when a priority starts.
+func (s *swap) Mode() string {
+	if s.mode == "" {
+		return SwapModeStop
+	}
+
+	return s.mode
+}
+
+// WithThrottle sets how the swap is slowed down in throttle mode.
+func (s *swap) WithThrottle(throttle Throttle) {
+	s.throttle = throttle
+}
+
+// WithStop sets how the swap is stopped.
+func (s *swap) WithStop(stop StopStrategy) {
+	s.stop = stop
+}
+
 // CMD returns the underlying cmd.
 func (s *swap) Cmd() *exec.Cmd {
 	return s.cmd
@@ -59,88 +316,482 @@ func (s *swap) PID() int {
 
 // Start starts a given command.
 func (s *swap) Start() error {
-	cmd := exec.Command(s.path)
-	s.cmd = cmd
-	// Get the command's stdout pipe so we can show the command's output
-	// if requested.
-	r, err := s.cmd.StdoutPipe()
+	if err := s.startCgroup(); err != nil {
+		s.setState(SwapStateFailed)
+
+		return err
+	}
+
+	s.paused = false
+	s.throttled = nil
+	s.setState(SwapStateStarting)
+
+	ready := make(chan struct{})
+	once := &sync.Once{}
+
+	s.outputMu.Lock()
+	s.ready = ready
+	s.markReady = func() {
+		once.Do(func() {
+			s.transition(SwapStateStarting, SwapStateRunning)
+			close(ready)
+		})
+	}
+	s.outputMu.Unlock()
+
+	p, err := s.spawn(true)
+	if err != nil && p.inCgroup && cgroupFDFailed(err) {
+		// The kernel can't start the swap inside its cgroup, so start it
+		// outside and move it in once started.
+		p, err = s.spawn(false)
+	}
+
 	if err != nil {
+		s.cmd = nil
+		s.setState(SwapStateFailed)
+
 		return err
 	}
-	// Set the command error to be it's stdout.
-	cmd.Stderr = cmd.Stdout
-	// Create a scanner which scans r in a line-by-line fashion.
-	scanner := bufio.NewScanner(r)
-	// Run a thread that is constantly reading the output of a swap until
-	// there is no more output :).
-	go func(s *swap) {
-		// Read line by line and process it.
-		for scanner.Scan() {
-			line := scanner.Text()
-			// Only show the output if the user has requested it.
-			if s.showOutput {
-				fmt.Println(line)
+
+	cmd := p.cmd
+	s.cmd = cmd
+	// Read the output of the swap until there is no more output :).
+	if s.spool == "" {
+		go s.readOutput(p.stdout, streamStdout)
+		go s.readOutput(p.stderr, streamStderr)
+	}
+
+	if !p.inCgroup {
+		if err := s.joinCgroup(); err != nil {
+			s.setState(SwapStateFailed)
+
+			return err
+		}
+	}
+
+	s.logEvent(fmt.Sprintf("started %s (pid %d)", s.path, cmd.Process.Pid))
+
+	exited := make(chan struct{})
+	s.exited = exited
+	// Wait on the process so it is noticed when it exits, whether it was
+	// stopped or crashed. Waiting on the process rather than the command
+	// leaves the output pipes alone, which anything left of the process tree
+	// may still hold open. They are closed once read to the end.
+	go func() {
+		state, err := cmd.Process.Wait()
+		s.processState = state
+
+		signaled := false
+
+		if err == nil {
+			s.logEvent(fmt.Sprintf("pid %d exited, %s", state.Pid(), state))
+
+			if ws, ok := state.Sys().(syscall.WaitStatus); ok {
+				signaled = ws.Signaled()
 			}
 		}
-	}(s)
-	// Start the command.
-	return cmd.Start()
+
+		s.exit(signaled)
+
+		close(exited)
+	}()
+
+	if s.spool != "" {
+		s.followSpools(exited, false)
+	}
+
+	switch {
+	case s.startup.Ready.TCP != "":
+		go waitForPort(s.startup.Ready.TCP, s.markReady, exited)
+	case s.readyOutput == nil:
+		s.markReady()
+	}
+
+	return nil
+}
+
+// spawned is a swap's process right after it started.
+type spawned struct {
+	cmd *exec.Cmd
+	// stdout and stderr are the pipes the swap's output is read from, they
+	// are nil when its output is spooled.
+	stdout, stderr io.ReadCloser
+	// inCgroup is true if the process was started inside its cgroup.
+	inCgroup bool
+}
+
+// spawn builds the swap's command and starts it. Stdout and stderr are
+// captured separately so their lines can be labelled. A swap that spools its
+// output writes to files instead of pipes, which are read once it has
+// started. With intoCgroup, a swap with a cgroup is started inside it where
+// the kernel can do that.
+func (s *swap) spawn(intoCgroup bool) (spawned, error) {
+	cmd, err := s.command()
+	if err != nil {
+		return spawned{}, err
+	}
+
+	p := spawned{cmd: cmd}
+	spools := []*os.File{}
+
+	if s.spool == "" {
+		if p.stdout, err = cmd.StdoutPipe(); err != nil {
+			return spawned{}, err
+		}
+
+		if p.stderr, err = cmd.StderrPipe(); err != nil {
+			p.stdout.Close()
+
+			return spawned{}, err
+		}
+	} else if spools, err = s.openSpools(cmd); err != nil {
+		return spawned{}, fmt.Errorf("error opening spool files: %w", err)
+	}
+
+	var cgroupDir *os.File
+	if intoCgroup {
+		cgroupDir = s.openCgroupFor(cmd)
+		p.inCgroup = cgroupDir != nil
+	}
+
+	err = cmd.Start()
+	// The swap has its own copies of the spool files and of its cgroup.
+	for _, f := range spools {
+		f.Close()
+	}
+
+	if cgroupDir != nil {
+		cgroupDir.Close()
+	}
+
+	return p, err
 }
 
-// Kill kills all direct child processes of the PID passed in,
-// then attempts to kill the PID itself.
+// command builds the command for the swap from its path, arguments, working
+// directory, environment and the user it runs as.
+//
+// BAT files and shell scripts usually assume they are run from their own
+// directory, so unless a working directory is set the swap runs in the
+// directory it lives in. Swaps that are just a name are looked up in the
+// PATH and run in procswap's working directory.
+func (s *swap) command() (*exec.Cmd, error) {
+	path := s.path
+	dir := s.dir
+
+	if filepath.Base(path) != path {
+		// Once the working directory changes a relative path would be
+		// resolved against it, so make it absolute first.
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return nil, fmt.Errorf("error getting absolute path of %s: %w", path, err)
+		}
+
+		path = abs
+
+		if dir == "" {
+			dir = filepath.Dir(path)
+		}
+	}
+
+	cmd := exec.Command(path, s.args...)
+	cmd.Dir = dir
+	// Start the swap in its own process group so everything it starts can be
+	// stopped along with it.
+	setProcessGroup(cmd)
+
+	if err := setRunAs(cmd, s.runAs); err != nil {
+		return nil, fmt.Errorf("error setting the user %s runs as: %w", path, err)
+	}
+
+	if len(s.env) > 0 {
+		if cmd.Env == nil {
+			cmd.Env = os.Environ()
+		}
+
+		cmd.Env = append(cmd.Env, s.env...)
+	}
+
+	return cmd, nil
+}
+
+// Stop stops the swap using its stop strategy and returns the outcome of
+// each step taken. Without a strategy the swap is killed.
+func (s *swap) Stop() ([]string, error) {
+	if s.stop.isZero() {
+		return nil, s.Kill()
+	}
+
+	if s.cmd == nil {
+		return nil, errors.New("no command to stop")
+	}
+
+	s.stopping()
+
+	// Find the process tree before anything exits, once the swap exits its
+	// children can no longer be found through it.
+	tree, err := s.processTree()
+	if err != nil {
+		return nil, fmt.Errorf("error listing child processes for %s: %w", s.path, err)
+	}
+
+	exited := s.exited
+	steps := []string{}
+	start := time.Now()
+
+	if s.stop.Command != "" {
+		if err := s.stopCommand().Run(); err != nil {
+			steps = append(steps, fmt.Sprintf("stop command %s failed: %s", s.stop.Command, err.Error()))
+		} else {
+			steps = append(steps, fmt.Sprintf("ran stop command %s", s.stop.Command))
+		}
+
+		timeout := stopTimeout(s.stop.Timeout)
+		if waitForExit(exited, timeout) {
+			return append(steps, fmt.Sprintf("exited after %s", since(start))), s.killTree(tree)
+		}
+
+		steps = append(steps, fmt.Sprintf("still running %s after stop command", timeout))
+	}
+
+	for _, sig := range s.stop.Signals {
+		// Pick up anything started since the tree was last listed.
+		tree = s.updateProcessTree(tree)
+
+		if err := s.signal(tree, sig.Signal); err != nil {
+			steps = append(steps, fmt.Sprintf("error sending %s: %s", sig.Signal, err.Error()))
+
+			continue
+		}
+
+		timeout := stopTimeout(sig.Timeout)
+		if waitForExit(exited, timeout) {
+			return append(steps, fmt.Sprintf("sent %s, exited after %s", sig.Signal, since(start))), s.killTree(tree)
+		}
+
+		steps = append(steps, fmt.Sprintf("sent %s, still running after %s", sig.Signal, timeout))
+	}
+
+	// Nothing worked, so kill it.
+	tree = s.updateProcessTree(tree)
+	if err := s.signalTree(tree, syscall.SIGKILL); err != nil {
+		return steps, fmt.Errorf("error killing processes %s: %w", s.path, err)
+	}
+
+	<-exited
+
+	return append(steps, fmt.Sprintf("killed after %s", since(start))), s.waitForTree(tree)
+}
+
+// since returns the time since start rounded for logging.
+func since(start time.Time) time.Duration {
+	return time.Since(start).Round(100 * time.Millisecond)
+}
+
+// signal sends the named signal to the swap's process tree.
+func (s *swap) signal(tree []ps.Process, name string) error {
+	sig, err := parseSignal(name)
+	if err != nil {
+		return err
+	}
+
+	return s.signalTree(tree, sig)
+}
+
+// Kill kills the swap's whole process tree and waits for every process in
+// it to exit.
 func (s *swap) Kill() error {
 	if s.cmd == nil {
 		return errors.New("no command to kill")
 	}
 
-	// Kill all child processes.
-	err := s.killChildProcesses()
+	tree, err := s.processTree()
 	if err != nil {
-		return fmt.Errorf("error killing child processes for %s: %w", s.path, err)
+		return fmt.Errorf("error listing child processes for %s: %w", s.path, err)
+	}
+	// The swap itself is gone, but anything left of its process group isn't.
+	if s.State().done() {
+		return s.killTree(tree)
 	}
 
-	// Kill the process.
-	err = s.cmd.Process.Kill()
-	if err != nil {
+	s.stopping()
+
+	if err := s.signalTree(tree, syscall.SIGKILL); err != nil {
 		return fmt.Errorf("error killing processes %s: %w", s.path, err)
 	}
 
-	_, err = s.cmd.Process.Wait()
-	if err != nil {
-		return fmt.Errorf("error waiting on process to be killed %s: %w", s.path, err)
+	<-s.exited
+
+	return s.waitForTree(tree)
+}
+
+// killTree kills whatever is left of a process tree after the swap itself
+// has exited and waits for it to exit.
+func (s *swap) killTree(tree []ps.Process) error {
+	s.killCgroup()
+
+	if err := signalProcessGroup(s.cmd.Process.Pid, syscall.SIGKILL); err != nil {
+		return fmt.Errorf("error killing process group of %s: %w", s.path, err)
 	}
 
-	return nil
+	for _, p := range s.remaining(tree) {
+		if err := signalProcess(p.Pid(), syscall.SIGKILL); err != nil {
+			return fmt.Errorf("error killing process %s (%d): %w", p.Executable(), p.Pid(), err)
+		}
+	}
+
+	return s.waitForTree(tree)
+}
+
+// signalTree sends sig to the swap's process group, then to every process of
+// its tree, as some may have left the group, then to the swap itself. Killing
+// the swap's cgroup first kills anything the tree is missing.
+func (s *swap) signalTree(tree []ps.Process, sig syscall.Signal) error {
+	if sig == syscall.SIGKILL {
+		s.killCgroup()
+	}
+
+	if err := signalProcessGroup(s.cmd.Process.Pid, sig); err != nil {
+		return fmt.Errorf("error signaling process group: %w", err)
+	}
+
+	for _, p := range tree {
+		if err := signalProcess(p.Pid(), sig); err != nil {
+			return fmt.Errorf("error signaling process %s (%d): %w", p.Executable(), p.Pid(), err)
+		}
+	}
+
+	// The swap may already have exited and been waited on, so signal it by
+	// its PID rather than through cmd.Process.
+	return signalProcess(s.cmd.Process.Pid, sig)
 }
 
-// killChildProcesses kills all processes that have a parent process ID
-// of the process ID passed in.
-func (s *swap) killChildProcesses() error {
+// processTree returns every descendant of the swap's process, found by
+// following parent process IDs down from the swap, and every other process
+// in the swap's cgroup.
+func (s *swap) processTree() ([]ps.Process, error) {
 	// List all currently running processes.
 	processes, err := s.ps.Processes()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
+	children := map[int][]ps.Process{}
+	byPID := map[int]ps.Process{}
+
 	for _, process := range processes {
-		if process.PPid() == s.cmd.Process.Pid {
-			p, err := os.FindProcess(process.Pid())
-			if err != nil {
-				return fmt.Errorf("error finding process %s: %w", process.Executable(), err)
+		children[process.PPid()] = append(children[process.PPid()], process)
+		byPID[process.Pid()] = process
+	}
+
+	tree := []ps.Process{}
+	seen := map[int]bool{s.cmd.Process.Pid: true}
+	queue := []int{s.cmd.Process.Pid}
+
+	for len(queue) > 0 {
+		pid := queue[0]
+		queue = queue[1:]
+
+		for _, child := range children[pid] {
+			if seen[child.Pid()] {
+				continue
 			}
 
-			err = p.Kill()
-			if err != nil {
-				return fmt.Errorf("error killing process %s: %w", process.Executable(), err)
+			seen[child.Pid()] = true
+			tree = append(tree, child)
+			queue = append(queue, child.Pid())
+		}
+	}
+	// Everything in the swap's cgroup is part of its tree, including
+	// processes whose parent exited.
+	for _, pid := range s.cgroupPIDs() {
+		if p, ok := byPID[pid]; ok && !seen[pid] {
+			seen[pid] = true
+			tree = append(tree, p)
+		}
+	}
+
+	return tree, nil
+}
+
+// updateProcessTree adds any processes started since tree was listed. If the
+// tree can't be listed the old one is kept.
+func (s *swap) updateProcessTree(tree []ps.Process) []ps.Process {
+	current, err := s.processTree()
+	if err != nil {
+		return tree
+	}
+
+	known := map[int]bool{}
+	for _, p := range tree {
+		known[p.Pid()] = true
+	}
+
+	for _, p := range current {
+		if !known[p.Pid()] {
+			tree = append(tree, p)
+		}
+	}
+
+	return tree
+}
+
+// waitForTree waits for every process of tree to exit, returning an error
+// naming any process still running after treeExitTimeout. Once the tree has
+// exited the swap's cgroup is removed.
+func (s *swap) waitForTree(tree []ps.Process) error {
+	deadline := time.Now().Add(treeExitTimeout)
+
+	for {
+		remaining := s.remaining(tree)
+		if len(remaining) == 0 {
+			s.removeCgroup()
+
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			names := make([]string, 0, len(remaining))
+			for _, p := range remaining {
+				names = append(names, fmt.Sprintf("%s (%d)", p.Executable(), p.Pid()))
 			}
+
+			return fmt.Errorf("processes of %s still running after being killed: %s", s.path, strings.Join(names, ", "))
 		}
+
+		time.Sleep(treePollInterval)
 	}
+}
 
-	return nil
+// remaining returns the processes of tree that are still running. A PID
+// that now belongs to a different executable has been reused and does not
+// count.
+func (s *swap) remaining(tree []ps.Process) []ps.Process {
+	remaining := []ps.Process{}
+
+	for _, p := range tree {
+		current, err := s.ps.FindProcess(p.Pid())
+		if err != nil || current == nil || current.Executable() != p.Executable() || processZombie(p.Pid()) {
+			continue
+		}
+
+		remaining = append(remaining, p)
+	}
+
+	return remaining
 }
 
-// ShowOutput sets the showOutput boolean for this swap.
+// ShowOutput sets the showOutput boolean for this swap. When the output
+// starts being shown its history is printed first, so what led up to now
+// isn't lost.
 func (s *swap) ShowOutput(showOutput bool) {
+	s.outputMu.Lock()
+	defer s.outputMu.Unlock()
+
+	if showOutput && !s.showOutput {
+		for _, line := range s.history.snapshot() {
+			printLine(line)
+		}
+	}
+
 	s.showOutput = showOutput
 }
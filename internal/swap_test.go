@@ -1,9 +1,20 @@
 package procswap_test
 
 import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"os/exec"
+	"os/user"
 	"path/filepath"
+	"regexp"
 	"runtime"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	. "github.com/onsi/ginkgo"
@@ -37,6 +48,14 @@ var _ = Describe("Swap", func() {
 		})
 	})
 
+	Describe("#Name", func() {
+		When("no name is set", func() {
+			It("is the file name of the swap", func() {
+				Expect(swap.Name()).To(Equal(filepath.Base(path)))
+			})
+		})
+	})
+
 	Describe("#PID", func() {
 		When("there is no underlying command", func() {
 			JustBeforeEach(func() {
@@ -74,6 +93,802 @@ var _ = Describe("Swap", func() {
 		})
 	})
 
+	Describe("#WithArgs", func() {
+		JustBeforeEach(func() {
+			swap.Kill()
+			swap = NewSwap(path)
+			swap.WithArgs([]string{"--pool", "us1.ethermine.org:4444"})
+			err = swap.Start()
+		})
+
+		It("passes the arguments to the command", func() {
+			Expect(err).To(BeNil())
+			Expect(swap.Cmd().Args[1:]).To(Equal([]string{"--pool", "us1.ethermine.org:4444"}))
+		})
+	})
+
+	Describe("#WithDir", func() {
+		When("no working directory is set", func() {
+			It("runs in the swap's directory", func() {
+				Expect(err).To(BeNil())
+				Expect(swap.Cmd().Dir).To(Equal(filepath.Dir(path)))
+			})
+		})
+
+		When("a working directory is set", func() {
+			JustBeforeEach(func() {
+				swap.Kill()
+				swap = NewSwap(path)
+				swap.WithDir(currentDir())
+				err = swap.Start()
+			})
+
+			It("runs in the working directory", func() {
+				Expect(err).To(BeNil())
+				Expect(swap.Cmd().Dir).To(Equal(currentDir()))
+			})
+		})
+	})
+
+	Describe("#WithEnv", func() {
+		JustBeforeEach(func() {
+			swap.Kill()
+			swap = NewSwap(path)
+			swap.WithEnv([]string{"GPU_MAX_HEAP_SIZE=100"})
+			err = swap.Start()
+		})
+
+		It("adds the environment variables to procswap's environment", func() {
+			Expect(err).To(BeNil())
+			Expect(swap.Cmd().Env).To(ContainElement("GPU_MAX_HEAP_SIZE=100"))
+			Expect(len(swap.Cmd().Env)).To(Equal(len(os.Environ()) + 1))
+		})
+	})
+
+	Describe("#ExitCode", func() {
+		When("the swap is still running", func() {
+			BeforeEach(func() {
+				path = waitFilePath()
+			})
+
+			AfterEach(func() {
+				swap.Kill()
+			})
+
+			It("returns -1", func() {
+				Expect(err).To(BeNil())
+				Expect(swap.ExitCode()).To(Equal(-1))
+			})
+		})
+
+		When("the swap exits", func() {
+			It("closes the exited channel and returns the exit code", func() {
+				Expect(err).To(BeNil())
+				Eventually(swap.Exited()).Should(BeClosed())
+				Expect(swap.ExitCode()).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("#Check", func() {
+		var health HealthProbe
+
+		BeforeEach(func() {
+			path = waitFilePath()
+			health = HealthProbe{Timeout: time.Second}
+		})
+
+		JustBeforeEach(func() {
+			swap.WithHealth(health)
+			err = swap.Check()
+		})
+
+		AfterEach(func() {
+			swap.Kill()
+		})
+
+		When("there is no health probe", func() {
+			It("is healthy", func() {
+				Expect(err).To(BeNil())
+			})
+		})
+
+		When("the http probe gets an error status", func() {
+			var server *httptest.Server
+
+			BeforeEach(func() {
+				server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusInternalServerError)
+				}))
+				health.HTTP = server.URL
+			})
+
+			AfterEach(func() {
+				server.Close()
+			})
+
+			It("returns an error", func() {
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(Equal("unhealthy status 500 Internal Server Error"))
+			})
+		})
+
+		When("the http probe succeeds", func() {
+			var server *httptest.Server
+
+			BeforeEach(func() {
+				server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+				health.HTTP = server.URL
+			})
+
+			AfterEach(func() {
+				server.Close()
+			})
+
+			It("is healthy", func() {
+				Expect(err).To(BeNil())
+			})
+		})
+
+		When("the tcp probe can not connect", func() {
+			BeforeEach(func() {
+				listener, err := net.Listen("tcp", "127.0.0.1:0")
+				Expect(err).To(BeNil())
+				health.TCP = listener.Addr().String()
+				listener.Close()
+			})
+
+			It("returns an error", func() {
+				Expect(err).ToNot(BeNil())
+			})
+		})
+
+		When("the tcp probe connects", func() {
+			var listener net.Listener
+
+			BeforeEach(func() {
+				listener, err = net.Listen("tcp", "127.0.0.1:0")
+				Expect(err).To(BeNil())
+				health.TCP = listener.Addr().String()
+			})
+
+			AfterEach(func() {
+				listener.Close()
+			})
+
+			It("is healthy", func() {
+				Expect(err).To(BeNil())
+			})
+		})
+
+		When("the command probe fails", func() {
+			BeforeEach(func() {
+				health.Command = filepath.FromSlash(currentDir() + "/" + uuid.New().String())
+			})
+
+			It("returns an error", func() {
+				Expect(err).ToNot(BeNil())
+			})
+		})
+
+		When("the command probe succeeds", func() {
+			BeforeEach(func() {
+				health.Command = swapFilePath()
+			})
+
+			It("is healthy", func() {
+				Expect(err).To(BeNil())
+			})
+		})
+	})
+
+	Describe("#History", func() {
+		When("the swap has written output", func() {
+			It("keeps the output labelled with the swap's name and stream", func() {
+				Expect(err).To(BeNil())
+				Eventually(swap.History).Should(ConsistOf(
+					MatchRegexp(`^\d{4}/\d{2}/\d{2} - \d{2}:\d{2}:\d{2} \| ` + regexp.QuoteMeta(filepath.Base(path)) + ` stdout \| hello world$`),
+				))
+			})
+		})
+
+		When("the swap writes to stdout and stderr", func() {
+			BeforeEach(func() {
+				if runtime.GOOS == "windows" {
+					Skip("the test script is a shell script")
+				}
+
+				path = streamsScriptPath()
+			})
+
+			JustBeforeEach(func() {
+				swap = NewSwap(path)
+				swap.WithName("miner")
+				err = swap.Start()
+			})
+
+			It("keeps both streams apart", func() {
+				Expect(err).To(BeNil())
+				Eventually(swap.History).Should(ConsistOf(
+					HaveSuffix("| miner stdout | hashrate 100 MH/s"),
+					HaveSuffix("| miner stderr | pool disconnected"),
+				))
+			})
+		})
+	})
+
+	Describe("#WithSpool", func() {
+		var spool string
+
+		BeforeEach(func() {
+			if runtime.GOOS == "windows" {
+				Skip("the test script is a shell script")
+			}
+
+			spool = filepath.Join(os.TempDir(), uuid.New().String(), "miner")
+		})
+
+		JustBeforeEach(func() {
+			swap = NewSwap(streamsScriptPath())
+			swap.WithName("miner")
+			swap.WithSpool(spool)
+			err = swap.Start()
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(filepath.Dir(spool))
+		})
+
+		It("writes the swap's output to the spool files and reads it back", func() {
+			Expect(err).To(BeNil())
+			Eventually(swap.History).Should(ConsistOf(
+				HaveSuffix("| miner stdout | hashrate 100 MH/s"),
+				HaveSuffix("| miner stderr | pool disconnected"),
+			))
+			Expect(ioutil.ReadFile(spool + ".stdout")).To(Equal([]byte("hashrate 100 MH/s\n")))
+			Expect(ioutil.ReadFile(spool + ".stderr")).To(Equal([]byte("pool disconnected\n")))
+		})
+	})
+
+	Describe("#WithOutputLog", func() {
+		var logPath string
+
+		BeforeEach(func() {
+			logPath = filepath.Join(os.TempDir(), uuid.New().String(), "swap.log")
+		})
+
+		JustBeforeEach(func() {
+			swap = NewSwap(path)
+			swap.WithOutputLog(OutputLog{Path: logPath})
+			err = swap.Start()
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(filepath.Dir(logPath))
+		})
+
+		It("writes the swap's output and exit to the log file", func() {
+			Expect(err).To(BeNil())
+			Eventually(swap.Exited()).Should(BeClosed())
+			Eventually(func() string {
+				b, _ := ioutil.ReadFile(logPath)
+				return string(b)
+			}).Should(And(
+				MatchRegexp(`--- procswap .* started `+regexp.QuoteMeta(path)+` \(pid \d+\) ---`),
+				ContainSubstring("hello world"),
+				MatchRegexp(`--- procswap .* pid \d+ exited, exit status 0 ---`),
+			))
+		})
+
+		When("the log file is closed", func() {
+			It("logs nothing more", func() {
+				Expect(err).To(BeNil())
+				Eventually(swap.Exited()).Should(BeClosed())
+				Eventually(func() string {
+					b, _ := ioutil.ReadFile(logPath)
+					return string(b)
+				}).Should(MatchRegexp(`pid \d+ exited`))
+				Expect(swap.CloseOutputLog()).To(BeNil())
+				logged, _ := ioutil.ReadFile(logPath)
+
+				Expect(swap.Start()).To(BeNil())
+				Eventually(swap.Exited()).Should(BeClosed())
+				Consistently(func() string {
+					b, _ := ioutil.ReadFile(logPath)
+					return string(b)
+				}, 200*time.Millisecond).Should(Equal(string(logged)))
+			})
+		})
+	})
+
+	Describe("#Ready", func() {
+		When("there is no ready condition", func() {
+			It("is ready once started", func() {
+				Expect(err).To(BeNil())
+				Expect(swap.Ready()).To(BeClosed())
+			})
+		})
+
+		When("the swap is ready once a line of output matches", func() {
+			JustBeforeEach(func() {
+				swap = NewSwap(path)
+				swap.WithStartup(Startup{Ready: ReadyCondition{Output: "^hello"}})
+				err = swap.Start()
+			})
+
+			It("is ready after the line", func() {
+				Expect(err).To(BeNil())
+				Eventually(swap.Ready()).Should(BeClosed())
+			})
+		})
+
+		When("the swap is ready once a port is open", func() {
+			var listener net.Listener
+
+			BeforeEach(func() {
+				path = waitFilePath()
+				listener, err = net.Listen("tcp", "127.0.0.1:0")
+				Expect(err).To(BeNil())
+			})
+
+			JustBeforeEach(func() {
+				swap.Kill()
+				swap = NewSwap(path)
+				swap.WithStartup(Startup{Ready: ReadyCondition{TCP: listener.Addr().String()}})
+				err = swap.Start()
+			})
+
+			AfterEach(func() {
+				swap.Kill()
+				listener.Close()
+			})
+
+			It("is ready once it accepts a connection", func() {
+				Expect(err).To(BeNil())
+				Eventually(swap.Ready()).Should(BeClosed())
+			})
+		})
+	})
+
+	Describe("#Adopt", func() {
+		var cmd *exec.Cmd
+
+		BeforeEach(func() {
+			if runtime.GOOS != "linux" {
+				Skip("exited processes are told apart from running ones through /proc")
+			}
+
+			cmd = exec.Command(waitFilePath())
+			Expect(cmd.Start()).To(Succeed())
+		})
+
+		JustBeforeEach(func() {
+			swap = NewSwap(waitFilePath())
+			err = swap.Adopt(cmd.Process.Pid)
+		})
+
+		AfterEach(func() {
+			cmd.Process.Kill()
+			cmd.Wait()
+		})
+
+		It("tracks the running process", func() {
+			Expect(err).To(BeNil())
+			Expect(swap.PID()).To(Equal(cmd.Process.Pid))
+			Expect(swap.State()).To(Equal(SwapStateRunning))
+			Expect(swap.Ready()).To(BeClosed())
+			Consistently(swap.Exited(), "200ms").ShouldNot(BeClosed())
+		})
+
+		When("the adopted swap is killed", func() {
+			JustBeforeEach(func() {
+				err = swap.Kill()
+			})
+
+			It("exits", func() {
+				Expect(err).To(BeNil())
+				Expect(swap.Exited()).To(BeClosed())
+				Expect(swap.State()).To(Equal(SwapStateStopped))
+				Expect(swap.ExitCode()).To(Equal(-1))
+			})
+		})
+	})
+
+	Describe("#WithCgroup", func() {
+		var slice string
+
+		BeforeEach(func() {
+			if runtime.GOOS != "linux" || os.Geteuid() != 0 || cgroup2Root() == "" {
+				Skip("making a cgroup needs root and a cgroup v2 hierarchy")
+			}
+
+			slice = "procswap-test-" + uuid.New().String()
+		})
+
+		JustBeforeEach(func() {
+			swap = NewSwap(waitFilePath())
+			swap.WithCgroup(Cgroup{Path: slice + "/group/swap"})
+			err = swap.Start()
+		})
+
+		AfterEach(func() {
+			swap.Kill()
+			os.Remove(filepath.Join(cgroup2Root(), slice, "group"))
+			os.Remove(filepath.Join(cgroup2Root(), slice))
+		})
+
+		It("runs the swap in its own cgroup", func() {
+			Expect(err).To(BeNil())
+			Expect(processCgroup(swap.PID())).To(Equal("/" + slice + "/group/swap"))
+		})
+
+		It("reads the swap's stats from its cgroup", func() {
+			stats, err := swap.Stats()
+			Expect(err).To(BeNil())
+			Expect(stats.CPU).To(BeNumerically(">=", 0))
+		})
+
+		When("the slice has a process other than procswap in it", func() {
+			var other *exec.Cmd
+
+			BeforeEach(func() {
+				controllers, _ := ioutil.ReadFile(filepath.Join(cgroup2Root(), "cgroup.subtree_control"))
+				if !strings.Contains(string(controllers), "cpu") && !strings.Contains(string(controllers), "memory") {
+					Skip("the slice only has to be vacated when it gets the cpu or memory controller")
+				}
+
+				Expect(os.Mkdir(filepath.Join(cgroup2Root(), slice), 0o755)).To(Succeed())
+
+				other = exec.Command("sleep", "30")
+				Expect(other.Start()).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(cgroup2Root(), slice, "cgroup.procs"),
+					[]byte(strconv.Itoa(other.Process.Pid)), 0o644)).To(Succeed())
+			})
+
+			AfterEach(func() {
+				if other != nil {
+					_ = other.Process.Kill()
+					_ = other.Wait()
+				}
+			})
+
+			It("leaves the process alone and returns an error", func() {
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(ContainSubstring("has processes other than procswap in it (" + strconv.Itoa(other.Process.Pid) + ")"))
+				Expect(ioutil.ReadFile(filepath.Join(cgroup2Root(), slice, "cgroup.procs"))).To(ContainSubstring(strconv.Itoa(other.Process.Pid)))
+			})
+		})
+
+		When("the swap is killed", func() {
+			JustBeforeEach(func() {
+				err = swap.Kill()
+			})
+
+			It("removes its cgroup", func() {
+				Expect(err).To(BeNil())
+				Expect(filepath.Join(cgroup2Root(), slice, "group", "swap")).ToNot(BeADirectory())
+			})
+		})
+	})
+
+	Describe("#WithRunAs", func() {
+		var runAs RunAs
+
+		BeforeEach(func() {
+			if runtime.GOOS == "windows" {
+				Skip("swaps can't run as another user on windows")
+			}
+
+			runAs = RunAs{User: "procswap-" + uuid.New().String()[:8]}
+		})
+
+		JustBeforeEach(func() {
+			swap = NewSwap("sleep")
+			swap.WithArgs([]string{"5"})
+			swap.WithDir(os.TempDir())
+			swap.WithRunAs(runAs)
+			err = swap.Start()
+		})
+
+		AfterEach(func() {
+			swap.Kill()
+		})
+
+		When("the user does not exist", func() {
+			It("returns an error", func() {
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(Equal("error setting the user sleep runs as: user " + runAs.User + " does not exist"))
+				Expect(swap.State()).To(Equal(SwapStateFailed))
+			})
+		})
+
+		When("only a group is set", func() {
+			BeforeEach(func() {
+				runAs = RunAs{Group: "0"}
+			})
+
+			It("returns an error instead of running as procswap's user", func() {
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(Equal("error setting the user sleep runs as: a group to run as needs a user to run as too"))
+				Expect(swap.State()).To(Equal(SwapStateFailed))
+			})
+		})
+
+		When("the user exists", func() {
+			var uid int
+
+			BeforeEach(func() {
+				if runtime.GOOS != "linux" || os.Geteuid() != 0 {
+					Skip("running a swap as another user needs root and the user is read from /proc")
+				}
+
+				u, err := user.Lookup("nobody")
+				if err != nil {
+					Skip("there is no user nobody to run the swap as")
+				}
+
+				runAs = RunAs{User: u.Username}
+				uid, _ = strconv.Atoi(u.Uid)
+			})
+
+			It("runs the swap as the user", func() {
+				Expect(err).To(BeNil())
+				Expect(processUID(swap.PID())).To(Equal(uid))
+			})
+		})
+	})
+
+	Describe("#State", func() {
+		When("the swap has not been started", func() {
+			JustBeforeEach(func() {
+				swap = NewSwap(path)
+			})
+
+			It("is new", func() {
+				Expect(swap.State()).To(Equal(SwapStateNew))
+			})
+		})
+
+		When("the swap exits on its own", func() {
+			It("is exited", func() {
+				Expect(err).To(BeNil())
+				Eventually(swap.State).Should(Equal(SwapStateExited))
+				Expect(swap.ExitCode()).To(Equal(0))
+			})
+
+			When("it is killed afterwards", func() {
+				JustBeforeEach(func() {
+					Eventually(swap.Exited()).Should(BeClosed())
+					err = swap.Kill()
+				})
+
+				It("succeeds", func() {
+					Expect(err).To(BeNil())
+					Expect(swap.State()).To(Equal(SwapStateExited))
+				})
+			})
+		})
+
+		When("the swap is running", func() {
+			BeforeEach(func() {
+				path = waitFilePath()
+			})
+
+			AfterEach(func() {
+				swap.Kill()
+			})
+
+			It("is running", func() {
+				Expect(swap.State()).To(Equal(SwapStateRunning))
+			})
+
+			When("it is killed", func() {
+				JustBeforeEach(func() {
+					err = swap.Kill()
+				})
+
+				It("is stopped", func() {
+					Expect(err).To(BeNil())
+					Expect(swap.State()).To(Equal(SwapStateStopped))
+				})
+			})
+
+			When("something else kills it", func() {
+				BeforeEach(func() {
+					if runtime.GOOS == "windows" {
+						Skip("processes on windows are not killed by signals")
+					}
+				})
+
+				JustBeforeEach(func() {
+					swap.Cmd().Process.Kill()
+				})
+
+				It("is killed", func() {
+					Eventually(swap.State).Should(Equal(SwapStateKilled))
+					Expect(swap.ExitCode()).To(Equal(-1))
+				})
+			})
+		})
+
+		When("the swap fails to start", func() {
+			BeforeEach(func() {
+				path = filepath.Join(currentDir(), "test", "swaps", "missing")
+			})
+
+			It("is failed", func() {
+				Expect(err).ToNot(BeNil())
+				Expect(swap.State()).To(Equal(SwapStateFailed))
+			})
+		})
+	})
+
+	Describe("#Throttle", func() {
+		When("there is no underlying command", func() {
+			JustBeforeEach(func() {
+				swap.Kill()
+				swap = NewSwap(path)
+				err = swap.Throttle()
+			})
+
+			It("returns an error", func() {
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(Equal("no command to throttle"))
+			})
+		})
+
+		When("it succeeds", func() {
+			BeforeEach(func() {
+				if runtime.GOOS != "linux" {
+					Skip("the niceness of the process is read from /proc")
+				}
+
+				path = waitFilePath()
+			})
+
+			JustBeforeEach(func() {
+				swap.WithThrottle(Throttle{Nice: 10})
+				err = swap.Throttle()
+			})
+
+			AfterEach(func() {
+				swap.Kill()
+			})
+
+			It("renices the swap", func() {
+				Expect(err).To(BeNil())
+				Expect(swap.Throttled()).To(BeTrue())
+				Expect(processNice(swap.PID())).To(Equal(10))
+			})
+
+			When("the swap is unthrottled", func() {
+				BeforeEach(func() {
+					if os.Geteuid() != 0 {
+						Skip("lowering the niceness of a process needs root")
+					}
+				})
+
+				JustBeforeEach(func() {
+					err = swap.Unthrottle()
+				})
+
+				It("restores its niceness", func() {
+					Expect(err).To(BeNil())
+					Expect(swap.Throttled()).To(BeFalse())
+					Expect(processNice(swap.PID())).To(Equal(0))
+				})
+			})
+		})
+	})
+
+	Describe("#Pause", func() {
+		BeforeEach(func() {
+			if runtime.GOOS != "linux" {
+				Skip("the state of the process is read from /proc")
+			}
+
+			path = waitFilePath()
+		})
+
+		JustBeforeEach(func() {
+			err = swap.Pause()
+		})
+
+		AfterEach(func() {
+			swap.Kill()
+		})
+
+		It("freezes the swap", func() {
+			Expect(err).To(BeNil())
+			Expect(swap.Paused()).To(BeTrue())
+			Eventually(func() bool { return processStopped(swap.PID()) }).Should(BeTrue())
+		})
+
+		When("the swap is resumed", func() {
+			JustBeforeEach(func() {
+				Eventually(func() bool { return processStopped(swap.PID()) }).Should(BeTrue())
+				err = swap.Resume()
+			})
+
+			It("runs again", func() {
+				Expect(err).To(BeNil())
+				Expect(swap.Paused()).To(BeFalse())
+				Eventually(func() bool { return processStopped(swap.PID()) }).Should(BeFalse())
+			})
+		})
+
+		When("the swap is paused and then killed", func() {
+			JustBeforeEach(func() {
+				err = swap.Kill()
+			})
+
+			It("exits", func() {
+				Expect(err).To(BeNil())
+				Expect(swap.Exited()).To(BeClosed())
+			})
+		})
+	})
+
+	Describe("#Stop", func() {
+		var steps []string
+
+		BeforeEach(func() {
+			path = waitFilePath()
+		})
+
+		When("there is no stop strategy", func() {
+			JustBeforeEach(func() {
+				steps, err = swap.Stop()
+			})
+
+			It("kills the swap", func() {
+				Expect(err).To(BeNil())
+				Expect(steps).To(BeEmpty())
+			})
+		})
+
+		When("the swap exits after a signal", func() {
+			BeforeEach(func() {
+				if runtime.GOOS == "windows" {
+					Skip("signals other than kill can not be sent on windows")
+				}
+			})
+
+			JustBeforeEach(func() {
+				swap.WithStop(StopStrategy{
+					Signals: []StopSignal{{Signal: "SIGINT", Timeout: 5 * time.Second}},
+				})
+				steps, err = swap.Stop()
+			})
+
+			It("stops at that signal", func() {
+				Expect(err).To(BeNil())
+				Expect(steps).To(HaveLen(1))
+				Expect(steps[0]).To(HavePrefix("sent SIGINT, exited after"))
+			})
+		})
+
+		When("the swap is still running after every step", func() {
+			JustBeforeEach(func() {
+				swap.WithStop(StopStrategy{
+					Command: swapFilePath(),
+					Timeout: 100 * time.Millisecond,
+					Signals: []StopSignal{{Signal: "SIGNOPE"}},
+				})
+				steps, err = swap.Stop()
+			})
+
+			It("logs each step and kills the swap", func() {
+				Expect(err).To(BeNil())
+				Expect(steps).To(HaveLen(4))
+				Expect(steps[0]).To(Equal("ran stop command " + swapFilePath()))
+				Expect(steps[1]).To(Equal("still running 100ms after stop command"))
+				Expect(steps[2]).To(HavePrefix("error sending SIGNOPE: unknown stop signal SIGNOPE"))
+				Expect(steps[3]).To(HavePrefix("killed after"))
+			})
+		})
+	})
+
 	Describe("#Kill", func() {
 		BeforeEach(func() {
 			path = waitFilePath()
@@ -102,6 +917,42 @@ var _ = Describe("Swap", func() {
 				Expect(err).To(BeNil())
 			})
 		})
+
+		When("the swap has grandchildren", func() {
+			var pidFile string
+
+			BeforeEach(func() {
+				if runtime.GOOS != "linux" {
+					Skip("the test script is a shell script that needs /proc")
+				}
+
+				path = treeScriptPath()
+				pidFile = filepath.Join(os.TempDir(), uuid.New().String())
+			})
+
+			JustBeforeEach(func() {
+				swap.Kill()
+				swap = NewSwap(path)
+				swap.WithArgs([]string{pidFile})
+				Expect(swap.Start()).To(Succeed())
+				Eventually(func() string {
+					b, _ := ioutil.ReadFile(pidFile)
+					return strings.TrimSpace(string(b))
+				}).ShouldNot(BeEmpty())
+				err = swap.Kill()
+			})
+
+			AfterEach(func() {
+				os.Remove(pidFile)
+			})
+
+			It("kills the whole process tree", func() {
+				Expect(err).To(BeNil())
+				b, _ := ioutil.ReadFile(pidFile)
+				pid, _ := strconv.Atoi(strings.TrimSpace(string(b)))
+				Expect(processRunning(pid)).To(BeFalse())
+			})
+		})
 	})
 })
 
@@ -110,6 +961,119 @@ func currentDir() string {
 	return currentDir
 }
 
+func streamsScriptPath() string {
+	return filepath.FromSlash(currentDir() + "/test/scripts/streams")
+}
+
+func treeScriptPath() string {
+	return filepath.FromSlash(currentDir() + "/test/scripts/tree")
+}
+
+func tickerScriptPath() string {
+	return filepath.FromSlash(currentDir() + "/test/scripts/ticker")
+}
+
+// processRunning returns true if the process with the given PID is running
+// and is not a zombie waiting to be reaped.
+func processRunning(pid int) bool {
+	b, err := ioutil.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return false
+	}
+
+	return !strings.Contains(string(b), ") Z ")
+}
+
+// cgroup2Root returns where the cgroup v2 hierarchy is mounted, or an empty
+// string if it isn't.
+func cgroup2Root() string {
+	b, err := ioutil.ReadFile("/proc/self/mounts")
+	if err != nil {
+		return ""
+	}
+
+	for _, line := range strings.Split(string(b), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 2 && fields[2] == "cgroup2" {
+			return fields[1]
+		}
+	}
+
+	return ""
+}
+
+// processCgroup returns the cgroup v2 path of the process with the given PID.
+func processCgroup(pid int) string {
+	b, err := ioutil.ReadFile("/proc/" + strconv.Itoa(pid) + "/cgroup")
+	if err != nil {
+		return ""
+	}
+
+	for _, line := range strings.Split(string(b), "\n") {
+		if strings.HasPrefix(line, "0::") {
+			return strings.TrimPrefix(line, "0::")
+		}
+	}
+
+	return ""
+}
+
+// processUID returns the real user ID of the process with the given PID.
+func processUID(pid int) int {
+	b, err := ioutil.ReadFile("/proc/" + strconv.Itoa(pid) + "/status")
+	if err != nil {
+		return -1
+	}
+
+	for _, line := range strings.Split(string(b), "\n") {
+		if fields := strings.Fields(line); len(fields) > 1 && fields[0] == "Uid:" {
+			uid, _ := strconv.Atoi(fields[1])
+
+			return uid
+		}
+	}
+
+	return -1
+}
+
+// processNice returns the niceness of the process with the given PID.
+func processNice(pid int) int {
+	b, err := ioutil.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return 0
+	}
+	// The niceness is the 19th field, the 17th after the command name.
+	fields := strings.Fields(string(b[bytes.LastIndexByte(b, ')')+1:]))
+	nice, _ := strconv.Atoi(fields[16])
+
+	return nice
+}
+
+// processStartTime returns the start time of the process with the given PID,
+// in clock ticks since boot.
+func processStartTime(pid int) uint64 {
+	b, err := ioutil.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return 0
+	}
+	// The start time is the 22nd field, the 20th after the command name.
+	fields := strings.Fields(string(b[bytes.LastIndexByte(b, ')')+1:]))
+	startTime, _ := strconv.ParseUint(fields[19], 10, 64)
+
+	return startTime
+}
+
+// processStopped returns true if the process with the given PID is stopped
+// by a signal.
+func processStopped(pid int) bool {
+	b, err := ioutil.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return false
+	}
+
+	return strings.Contains(string(b), ") T ")
+}
+
 func swapFilePath() string {
 	var swap string
 
@@ -0,0 +1,64 @@
+//go:build !windows
+// +build !windows
+
+package procswap
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// setProcessGroup starts the command in its own process group so the swap
+// and everything it starts can be signaled at once.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// signalProcessGroup sends sig to every process in the process group led by
+// pid. A group that no longer exists is not an error.
+func signalProcessGroup(pid int, sig syscall.Signal) error {
+	if err := syscall.Kill(-pid, sig); err != nil && err != syscall.ESRCH {
+		return err
+	}
+
+	return nil
+}
+
+// signalProcess sends sig to the process with the given PID. A process that
+// no longer exists is not an error.
+func signalProcess(pid int, sig syscall.Signal) error {
+	if err := syscall.Kill(pid, sig); err != nil && err != syscall.ESRCH {
+		return err
+	}
+
+	return nil
+}
+
+// freezeProcessGroup stops, or continues, every process in the process group
+// led by pid.
+func freezeProcessGroup(pid int, freeze bool) error {
+	if freeze {
+		return signalProcessGroup(pid, syscall.SIGSTOP)
+	}
+
+	return signalProcessGroup(pid, syscall.SIGCONT)
+}
+
+// freezeProcess stops, or continues, the process with the given PID.
+func freezeProcess(pid int, freeze bool) error {
+	if freeze {
+		return signalProcess(pid, syscall.SIGSTOP)
+	}
+
+	return signalProcess(pid, syscall.SIGCONT)
+}
+
+// waitForProcess waits for a process procswap didn't start, and so can't
+// wait on, to exit.
+func waitForProcess(p *os.Process) {
+	for syscall.Kill(p.Pid, 0) != syscall.ESRCH && !processZombie(p.Pid) {
+		time.Sleep(treePollInterval)
+	}
+}
@@ -0,0 +1,75 @@
+package procswap
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// processSuspendResume is the access right needed to suspend and resume a process.
+const processSuspendResume = 0x0800
+
+var (
+	ntdll                = syscall.NewLazyDLL("ntdll.dll")
+	procNtSuspendProcess = ntdll.NewProc("NtSuspendProcess")
+	procNtResumeProcess  = ntdll.NewProc("NtResumeProcess")
+)
+
+// setProcessGroup starts the command in a new process group.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+}
+
+// signalProcessGroup does nothing on Windows, which can't signal a process
+// group. Every process in the tree is signaled on its own instead.
+func signalProcessGroup(pid int, sig syscall.Signal) error {
+	return nil
+}
+
+// signalProcess sends sig to the process with the given PID. Windows can only
+// kill processes. A process that no longer exists is not an error.
+func signalProcess(pid int, sig syscall.Signal) error {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		// FindProcess fails when the process has already exited.
+		return nil
+	}
+	defer p.Release()
+
+	return p.Signal(sig)
+}
+
+// freezeProcessGroup does nothing on Windows, which has no process groups to
+// suspend. Every process in the tree is suspended on its own instead.
+func freezeProcessGroup(pid int, freeze bool) error {
+	return nil
+}
+
+// freezeProcess suspends, or resumes, every thread of the process with the
+// given PID. A process that no longer exists is not an error.
+func freezeProcess(pid int, freeze bool) error {
+	h, err := syscall.OpenProcess(processSuspendResume, false, uint32(pid))
+	if err != nil {
+		// OpenProcess fails when the process has already exited.
+		return nil
+	}
+	defer syscall.CloseHandle(h)
+
+	proc := procNtResumeProcess
+	if freeze {
+		proc = procNtSuspendProcess
+	}
+
+	if status, _, _ := proc.Call(uintptr(h)); status != 0 {
+		return fmt.Errorf("%s failed with status 0x%x", proc.Name, status)
+	}
+
+	return nil
+}
+
+// waitForProcess waits for a process procswap didn't start to exit. Windows
+// can wait on any process it has a handle to.
+func waitForProcess(p *os.Process) {
+	_, _ = p.Wait()
+}
@@ -0,0 +1,187 @@
+package procswap
+
+import (
+	"errors"
+	"fmt"
+)
+
+// defaultThrottleNice is the niceness a throttled swap runs at when no
+// throttle is set.
+const defaultThrottleNice = 19
+
+// Throttle is how a swap in throttle mode is slowed down while priorities
+// run. The zero value runs the swap at the lowest priority.
+type Throttle struct {
+	// Nice is the niceness, from 1 to 19, the swap's processes run at. On
+	// Windows a nice of 10 or more runs them at idle priority and anything
+	// less below normal priority.
+	Nice int
+	// CPUs are the CPUs the swap's processes are restricted to.
+	CPUs []int
+	// CPUWeight is the cgroup v2 CPU weight, from 1 to 10000, of the swap's
+	// cgroup. It only works on Linux when the swap runs in a cgroup of its own.
+	CPUWeight int
+}
+
+// isZero returns true if no part of the throttle is set.
+func (t Throttle) isZero() bool {
+	return t.Nice == 0 && len(t.CPUs) == 0 && t.CPUWeight == 0
+}
+
+// validate returns an error if any value is out of range.
+func (t Throttle) validate() error {
+	if t.Nice < 0 || t.Nice > 19 {
+		return errors.New("throttle nice must be between 1 and 19")
+	}
+
+	for _, cpu := range t.CPUs {
+		if cpu < 0 {
+			return errors.New("throttle cpus can't be negative")
+		}
+	}
+
+	if t.CPUWeight < 0 || t.CPUWeight > 10000 {
+		return errors.New("throttle cpu weight must be between 1 and 10000")
+	}
+
+	return nil
+}
+
+// orDefault returns the throttle, or the default throttle if none is set.
+func (t Throttle) orDefault() Throttle {
+	if t.isZero() {
+		return Throttle{Nice: defaultThrottleNice}
+	}
+
+	return t
+}
+
+// Throttle lowers the priority of the swap's whole process tree, restricts
+// it to some CPUs or lowers its cgroup's CPU weight, remembering the
+// original settings so Unthrottle can restore them.
+func (s *swap) Throttle() error {
+	if err := s.checkRunning("throttle"); err != nil {
+		return err
+	}
+
+	pids, err := s.treePIDs()
+	if err != nil {
+		return err
+	}
+
+	throttle := s.throttle.orDefault()
+	original := map[int]processSettings{}
+
+	for _, pid := range pids {
+		settings, err := getProcessSettings(pid)
+		if err != nil {
+			// A child that exited in the meantime has nothing to throttle.
+			if pid != s.cmd.Process.Pid {
+				continue
+			}
+
+			return fmt.Errorf("error reading scheduling settings of %s: %w", s.path, err)
+		}
+
+		if err := throttleProcess(pid, settings, throttle); err != nil {
+			s.restoreProcesses(original)
+
+			return fmt.Errorf("error throttling process %d: %w", pid, err)
+		}
+
+		original[pid] = settings
+	}
+
+	if throttle.CPUWeight != 0 {
+		weight, err := throttleCgroup(s.cmd.Process.Pid, throttle.CPUWeight)
+		if err != nil {
+			s.restoreProcesses(original)
+
+			return fmt.Errorf("error throttling cgroup: %w", err)
+		}
+
+		s.cgroupWeight = weight
+	}
+
+	s.throttled = original
+
+	return nil
+}
+
+// Unthrottle restores the original settings of the swap's process tree after
+// it was throttled. Processes the swap started while it was throttled get
+// the swap's own original settings.
+func (s *swap) Unthrottle() error {
+	if s.throttled == nil {
+		return errors.New("swap is not throttled")
+	}
+
+	if err := s.checkRunning("unthrottle"); err != nil {
+		return err
+	}
+
+	pids, err := s.treePIDs()
+	if err != nil {
+		return err
+	}
+
+	original := map[int]processSettings{}
+
+	for _, pid := range pids {
+		settings, ok := s.throttled[pid]
+		if !ok {
+			settings = s.throttled[s.cmd.Process.Pid]
+		}
+
+		original[pid] = settings
+	}
+
+	if err := s.restoreProcesses(original); err != nil {
+		return err
+	}
+
+	if s.throttle.orDefault().CPUWeight != 0 {
+		if err := restoreCgroup(s.cmd.Process.Pid, s.cgroupWeight); err != nil {
+			return fmt.Errorf("error restoring cgroup: %w", err)
+		}
+	}
+
+	s.throttled = nil
+
+	return nil
+}
+
+// Throttled returns true if the swap is throttled.
+func (s *swap) Throttled() bool {
+	return s.throttled != nil
+}
+
+// restoreProcesses restores the settings of each process. Only failing to
+// restore the swap itself is an error, its children may have exited.
+func (s *swap) restoreProcesses(settings map[int]processSettings) error {
+	var swapErr error
+
+	for pid, original := range settings {
+		if err := restoreProcess(pid, original); err != nil && pid == s.cmd.Process.Pid {
+			swapErr = fmt.Errorf("error restoring scheduling settings of %s: %w", s.path, err)
+		}
+	}
+
+	return swapErr
+}
+
+// treePIDs returns the PID of the swap followed by the PIDs of every process
+// in its tree.
+func (s *swap) treePIDs() ([]int, error) {
+	tree, err := s.processTree()
+	if err != nil {
+		return nil, fmt.Errorf("error listing child processes for %s: %w", s.path, err)
+	}
+
+	pids := []int{s.cmd.Process.Pid}
+	for _, p := range tree {
+		pids = append(pids, p.Pid())
+	}
+
+	return pids, nil
+}
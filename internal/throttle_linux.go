@@ -0,0 +1,150 @@
+package procswap
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// affinityMaskSize is the size in bytes of a CPU affinity mask, which has
+// room for 1024 CPUs.
+const affinityMaskSize = 128
+
+// processSettings are the scheduling settings of a process that throttling changes.
+type processSettings struct {
+	nice     int
+	affinity []byte
+}
+
+// getProcessSettings reads the niceness and CPU affinity of a process.
+func getProcessSettings(pid int) (processSettings, error) {
+	prio, err := syscall.Getpriority(syscall.PRIO_PROCESS, pid)
+	if err != nil {
+		return processSettings{}, err
+	}
+
+	mask := make([]byte, affinityMaskSize)
+
+	n, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_GETAFFINITY, uintptr(pid), uintptr(len(mask)), uintptr(unsafe.Pointer(&mask[0])))
+	if errno != 0 {
+		return processSettings{}, errno
+	}
+	// The kernel returns the priority as 20 - nice so it is never negative.
+	return processSettings{nice: 20 - prio, affinity: mask[:n]}, nil
+}
+
+// throttleProcess raises the niceness of a process, unless it is already
+// nicer, and restricts it to the throttle's CPUs.
+func throttleProcess(pid int, original processSettings, t Throttle) error {
+	if t.Nice > original.nice {
+		if err := syscall.Setpriority(syscall.PRIO_PROCESS, pid, t.Nice); err != nil {
+			return err
+		}
+	}
+
+	if len(t.CPUs) == 0 {
+		return nil
+	}
+
+	mask := make([]byte, affinityMaskSize)
+
+	for _, cpu := range t.CPUs {
+		if cpu >= affinityMaskSize*8 {
+			return fmt.Errorf("cpu %d is out of range", cpu)
+		}
+
+		mask[cpu/8] |= 1 << (cpu % 8)
+	}
+
+	return setAffinity(pid, mask)
+}
+
+// restoreProcess sets the niceness and CPU affinity of a process back to the
+// original settings. Lowering the niceness needs CAP_SYS_NICE.
+func restoreProcess(pid int, original processSettings) error {
+	if err := syscall.Setpriority(syscall.PRIO_PROCESS, pid, original.nice); err != nil {
+		return err
+	}
+
+	return setAffinity(pid, original.affinity)
+}
+
+// setAffinity restricts a process to the CPUs set in mask.
+func setAffinity(pid int, mask []byte) error {
+	_, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_SETAFFINITY, uintptr(pid), uintptr(len(mask)), uintptr(unsafe.Pointer(&mask[0])))
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
+}
+
+// throttleCgroup sets the CPU weight of the cgroup the process runs in,
+// returning the original weight. The cgroup can't be procswap's own, or
+// one procswap runs in, as that would throttle procswap too.
+func throttleCgroup(pid, weight int) (int, error) {
+	path, err := cgroupPath(strconv.Itoa(pid))
+	if err != nil {
+		return 0, err
+	}
+
+	own, err := cgroupPath("self")
+	if err != nil {
+		return 0, err
+	}
+
+	if own == path || strings.HasPrefix(own, strings.TrimSuffix(path, "/")+"/") {
+		return 0, errors.New("swap does not run in a cgroup of its own")
+	}
+
+	file := filepath.Join(cgroupRoot(), path, "cpu.weight")
+
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return 0, err
+	}
+
+	original, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil {
+		return 0, fmt.Errorf("invalid cpu weight in %s: %w", file, err)
+	}
+
+	if err := ioutil.WriteFile(file, []byte(strconv.Itoa(weight)), 0o644); err != nil {
+		return 0, err
+	}
+
+	return original, nil
+}
+
+// restoreCgroup sets the CPU weight of the cgroup the process runs in back
+// to the original weight.
+func restoreCgroup(pid, weight int) error {
+	path, err := cgroupPath(strconv.Itoa(pid))
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(cgroupRoot(), path, "cpu.weight"), []byte(strconv.Itoa(weight)), 0o644)
+}
+
+// cgroupPath reads the cgroup v2 path of a process, given its PID or "self",
+// from /proc.
+func cgroupPath(pid string) (string, error) {
+	b, err := ioutil.ReadFile("/proc/" + pid + "/cgroup")
+	if err != nil {
+		return "", err
+	}
+
+	for _, line := range strings.Split(string(b), "\n") {
+		if strings.HasPrefix(line, "0::") {
+			return strings.TrimPrefix(line, "0::"), nil
+		}
+	}
+
+	return "", errors.New("process is not in a cgroup v2 hierarchy")
+}
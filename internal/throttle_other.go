@@ -0,0 +1,52 @@
+//go:build !linux && !windows
+// +build !linux,!windows
+
+package procswap
+
+import (
+	"errors"
+	"runtime"
+	"syscall"
+)
+
+// processSettings are the scheduling settings of a process that throttling changes.
+type processSettings struct {
+	nice int
+}
+
+// getProcessSettings reads the niceness of a process.
+func getProcessSettings(pid int) (processSettings, error) {
+	nice, err := syscall.Getpriority(syscall.PRIO_PROCESS, pid)
+	if err != nil {
+		return processSettings{}, err
+	}
+
+	return processSettings{nice: nice}, nil
+}
+
+// throttleProcess raises the niceness of a process, unless it is already
+// nicer. CPU affinity can't be set outside of Linux and Windows.
+func throttleProcess(pid int, original processSettings, t Throttle) error {
+	if len(t.CPUs) > 0 {
+		return errors.New("restricting the cpus of a process is not supported on " + runtime.GOOS)
+	}
+
+	if t.Nice > original.nice {
+		return syscall.Setpriority(syscall.PRIO_PROCESS, pid, t.Nice)
+	}
+
+	return nil
+}
+
+// restoreProcess sets the niceness of a process back to the original niceness.
+func restoreProcess(pid int, original processSettings) error {
+	return syscall.Setpriority(syscall.PRIO_PROCESS, pid, original.nice)
+}
+
+func throttleCgroup(pid, weight int) (int, error) {
+	return 0, errors.New("cgroups are not supported on " + runtime.GOOS)
+}
+
+func restoreCgroup(pid, weight int) error {
+	return errors.New("cgroups are not supported on " + runtime.GOOS)
+}
@@ -0,0 +1,118 @@
+package procswap
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	// processThrottleAccess are the access rights needed to read and set the
+	// priority class and affinity of a process.
+	processThrottleAccess = 0x0200 | 0x0400
+
+	idlePriorityClass        = 0x0040
+	belowNormalPriorityClass = 0x4000
+
+	// affinityMaskBits is the number of CPUs a process affinity mask has room for.
+	affinityMaskBits = int(unsafe.Sizeof(uintptr(0)) * 8)
+)
+
+var (
+	kernel32                   = syscall.NewLazyDLL("kernel32.dll")
+	procGetPriorityClass       = kernel32.NewProc("GetPriorityClass")
+	procSetPriorityClass       = kernel32.NewProc("SetPriorityClass")
+	procGetProcessAffinityMask = kernel32.NewProc("GetProcessAffinityMask")
+	procSetProcessAffinityMask = kernel32.NewProc("SetProcessAffinityMask")
+)
+
+// processSettings are the scheduling settings of a process that throttling changes.
+type processSettings struct {
+	priorityClass uintptr
+	affinity      uintptr
+}
+
+// getProcessSettings reads the priority class and CPU affinity of a process.
+func getProcessSettings(pid int) (processSettings, error) {
+	h, err := syscall.OpenProcess(processThrottleAccess, false, uint32(pid))
+	if err != nil {
+		return processSettings{}, err
+	}
+	defer syscall.CloseHandle(h)
+
+	class, _, err := procGetPriorityClass.Call(uintptr(h))
+	if class == 0 {
+		return processSettings{}, err
+	}
+
+	var affinity, system uintptr
+	if ok, _, err := procGetProcessAffinityMask.Call(uintptr(h), uintptr(unsafe.Pointer(&affinity)), uintptr(unsafe.Pointer(&system))); ok == 0 {
+		return processSettings{}, err
+	}
+
+	return processSettings{priorityClass: class, affinity: affinity}, nil
+}
+
+// throttleProcess lowers the priority class of a process, unless it is
+// already idle, and restricts it to the throttle's CPUs.
+func throttleProcess(pid int, original processSettings, t Throttle) error {
+	class := uintptr(0)
+
+	switch {
+	case t.Nice >= 10 && original.priorityClass != idlePriorityClass:
+		class = idlePriorityClass
+	case t.Nice > 0 && original.priorityClass != idlePriorityClass && original.priorityClass != belowNormalPriorityClass:
+		class = belowNormalPriorityClass
+	}
+
+	affinity := uintptr(0)
+
+	for _, cpu := range t.CPUs {
+		if cpu >= affinityMaskBits {
+			return fmt.Errorf("cpu %d is out of range", cpu)
+		}
+
+		affinity |= 1 << uint(cpu)
+	}
+
+	return setProcessSettings(pid, processSettings{priorityClass: class, affinity: affinity})
+}
+
+// restoreProcess sets the priority class and CPU affinity of a process back
+// to the original settings.
+func restoreProcess(pid int, original processSettings) error {
+	return setProcessSettings(pid, original)
+}
+
+// setProcessSettings sets the priority class and CPU affinity of a process,
+// leaving either alone when it is zero.
+func setProcessSettings(pid int, settings processSettings) error {
+	h, err := syscall.OpenProcess(processThrottleAccess, false, uint32(pid))
+	if err != nil {
+		return err
+	}
+	defer syscall.CloseHandle(h)
+
+	if settings.priorityClass != 0 {
+		if ok, _, err := procSetPriorityClass.Call(uintptr(h), settings.priorityClass); ok == 0 {
+			return err
+		}
+	}
+
+	if settings.affinity != 0 {
+		if ok, _, err := procSetProcessAffinityMask.Call(uintptr(h), settings.affinity); ok == 0 {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func throttleCgroup(pid, weight int) (int, error) {
+	return 0, errors.New("cgroups are not supported on windows")
+}
+
+func restoreCgroup(pid, weight int) error {
+	return errors.New("cgroups are not supported on windows")
+}
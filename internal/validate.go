@@ -0,0 +1,144 @@
+package procswap
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+
+	"github.com/karrick/godirwalk"
+	"github.com/logrusorgru/aurora"
+	"github.com/urfave/cli/v2"
+)
+
+// validate checks the config for problems without running any processes. It
+// resolves every group's priorities, makes sure no swap is also a priority or
+// matched by a priority rule and that every swap and priority script can be
+// executed, by the user it runs as when a swap has one. Every problem found
+// is logged and an error is returned if there were any.
+func validate(c *cli.Context) error {
+	cfg, err := configFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	problems := []string{}
+
+	for _, gc := range cfg.groups() {
+		problems = append(problems, validateGroup(gc)...)
+	}
+
+	if len(problems) > 0 {
+		for _, problem := range problems {
+			logError(fmt.Sprintf("%s %s", aurora.Cyan("validate"), problem))
+		}
+
+		return fmt.Errorf("found %d problems in the config", len(problems))
+	}
+
+	groups := strconv.Itoa(len(cfg.groups()))
+	logInfo(fmt.Sprintf("%s config is valid, checked %s groups", aurora.Cyan("validate"), aurora.Bold(groups)))
+
+	return nil
+}
+
+// validateGroup returns every problem found in a group.
+func validateGroup(gc groupConfig) []string {
+	problems := []string{}
+	// Resolve the priorities.
+	pe := []*godirwalk.Dirent{}
+
+	for _, p := range gc.Priorities {
+		e, err := ProcessList(p.Path, gc.Ignore, p.discovery())
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("priority %s%s: %s", p.Path, gc.logSuffix(), err.Error()))
+
+			continue
+		}
+
+		pe = append(pe, e...)
+	}
+
+	if err := intersect(pe, gc.swapPaths()); err != nil {
+		problems = append(problems, err.Error()+gc.logSuffix())
+	}
+
+	// The rules were already checked when the config was validated.
+	rules, _ := gc.rules()
+	if err := intersectRules(rules, gc.swapPaths()); err != nil {
+		problems = append(problems, err.Error()+gc.logSuffix())
+	}
+
+	for _, sc := range gc.Swaps {
+		if err := checkExecutable(sc.Path); err != nil {
+			problems = append(problems, fmt.Sprintf("swap %s%s: %s", sc.Path, gc.logSuffix(), err.Error()))
+		} else if !sc.runAs().isZero() {
+			if err := checkRunAs(sc.runAs(), sc.Path); err != nil {
+				problems = append(problems, fmt.Sprintf("swap %s%s: %s", sc.Path, gc.logSuffix(), err.Error()))
+			}
+		}
+
+		if sc.Dir != "" {
+			if err := checkDir(sc.Dir); err != nil {
+				problems = append(problems, fmt.Sprintf("swap %s%s working directory: %s", sc.Path, gc.logSuffix(), err.Error()))
+			}
+		}
+	}
+
+	if gc.PriorityScript != "" {
+		if err := checkExecutable(gc.PriorityScript); err != nil {
+			problems = append(problems, fmt.Sprintf("priority script %s%s: %s", gc.PriorityScript, gc.logSuffix(), err.Error()))
+		}
+	}
+
+	return problems
+}
+
+// checkExecutable returns an error if path does not exist or can not be
+// executed. A path that is just a name is looked up in the PATH.
+func checkExecutable(path string) error {
+	if filepath.Base(path) == path {
+		_, err := exec.LookPath(path)
+
+		return err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return errors.New("does not exist")
+		}
+
+		return err
+	}
+
+	if info.IsDir() {
+		return errors.New("is a directory")
+	}
+
+	if !isExecutable(path, filepath.Base(path)) {
+		return errors.New("is not executable")
+	}
+
+	return nil
+}
+
+// checkDir returns an error if path does not exist or is not a directory.
+func checkDir(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return errors.New("does not exist")
+		}
+
+		return err
+	}
+
+	if !info.IsDir() {
+		return errors.New("is not a directory")
+	}
+
+	return nil
+}
@@ -0,0 +1,184 @@
+package procswap_test
+
+import (
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"strconv"
+
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+
+	"github.com/urfave/cli/v2"
+
+	. "github.com/billiford/procswap/internal"
+)
+
+var _ = Describe("Validate", func() {
+	const (
+		fmtInfoLog  = `\d{4}\/\d{2}\/\d{2} - \d{2}:\d{2}:\d{2} \|.*INFO.*\| `
+		fmtErrorLog = `\d{4}\/\d{2}\/\d{2} - \d{2}:\d{2}:\d{2} \|.*ERROR.*\| `
+	)
+
+	var (
+		err          error
+		app          *cli.App
+		args         []string
+		buffer       *Buffer
+		rescue, r, w *os.File
+	)
+
+	BeforeEach(func() {
+		app = NewApp()
+		args = []string{procswapFilename(), "validate",
+			"-p", priorityFileDir(),
+			"-s", swapFilePath(),
+			"-ps", priorityScriptPath(),
+		}
+
+		// Output checks.
+		rescue = os.Stdout
+		r, w, _ = os.Pipe()
+		os.Stdout = w
+		buffer = BufferReader(r)
+	})
+
+	AfterEach(func() {
+		w.Close()
+		os.Stdout = rescue
+	})
+
+	JustBeforeEach(func() {
+		err = app.Run(args)
+	})
+
+	When("the config can not be loaded", func() {
+		BeforeEach(func() {
+			args = []string{procswapFilename(), "validate", "-p", priorityFileDir()}
+		})
+
+		It("returns an error", func() {
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(Equal("no swaps set, pass --swap or set swaps in the config file"))
+		})
+	})
+
+	When("there are problems", func() {
+		var missing string
+
+		BeforeEach(func() {
+			missing = filepath.FromSlash(currentDir() + "/" + uuid.New().String())
+			args = []string{procswapFilename(), "validate",
+				"-p", priorityFileDir(),
+				"-p", missing,
+				"-s", swapFilePath(),
+				"-s", priorityFilePath(),
+				"-s", missing,
+				"-ps", missing,
+			}
+		})
+
+		It("reports every problem", func() {
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(Equal("found 4 problems in the config"))
+			Eventually(buffer).Should(Say(fmtErrorLog + `.*validate.* priority ` + missing + `: .*`))
+			Eventually(buffer).Should(Say(fmtErrorLog + `.*validate.* ` + priorityFile() + ` found in both priorities and swaps`))
+			Eventually(buffer).Should(Say(fmtErrorLog + `.*validate.* swap ` + missing + `: does not exist`))
+			Eventually(buffer).Should(Say(fmtErrorLog + `.*validate.* priority script ` + missing + `: does not exist`))
+		})
+	})
+
+	When("a swap is not executable", func() {
+		BeforeEach(func() {
+			if runtime.GOOS == "windows" {
+				Skip("files are executable by their extension on windows")
+			}
+
+			args = []string{procswapFilename(), "validate",
+				"-p", priorityFileDir(),
+				"-s", filepath.FromSlash(currentDir() + "/test/file"),
+			}
+		})
+
+		It("reports the problem", func() {
+			Expect(err).ToNot(BeNil())
+			Eventually(buffer).Should(Say(fmtErrorLog + `.*validate.* swap .*test/file: is not executable`))
+		})
+	})
+
+	When("a swap runs as a user that does not exist", func() {
+		var name string
+
+		BeforeEach(func() {
+			if runtime.GOOS == "windows" {
+				Skip("swaps can't run as another user on windows")
+			}
+
+			name = "procswap-" + uuid.New().String()[:8]
+			args = append(args, "--swap-user", filepath.Base(swapFilePath())+"="+name)
+		})
+
+		It("reports the problem", func() {
+			Expect(err).ToNot(BeNil())
+			Eventually(buffer).Should(Say(fmtErrorLog + `.*validate.* swap .*: user ` + name + ` does not exist`))
+		})
+	})
+
+	When("a swap runs as a user that can't execute it", func() {
+		var dir string
+
+		BeforeEach(func() {
+			if runtime.GOOS == "windows" {
+				Skip("swaps can't run as another user on windows")
+			}
+
+			if u, err := user.Lookup("nobody"); err != nil || u.Uid == strconv.Itoa(os.Getuid()) {
+				Skip("there is no user nobody to run the swap as")
+			}
+			// Only the current user can search the temporary directory.
+			dir, err = ioutil.TempDir("", "procswap")
+			Expect(err).To(BeNil())
+			Expect(ioutil.WriteFile(filepath.Join(dir, "swap"), []byte("#!/bin/sh\n"), 0o755)).To(Succeed())
+
+			args = []string{procswapFilename(), "validate",
+				"-p", priorityFileDir(),
+				"-s", filepath.Join(dir, "swap"),
+				"--swap-user", "swap=nobody",
+			}
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+
+		It("reports the problem", func() {
+			Expect(err).ToNot(BeNil())
+			Eventually(buffer).Should(Say(fmtErrorLog + `.*validate.* swap .*swap: user nobody can't search directory ` + dir))
+		})
+	})
+
+	When("a priority rule matches a swap", func() {
+		BeforeEach(func() {
+			args = []string{procswapFilename(), "validate",
+				"--priority-glob", "SWAP*",
+				"-s", swapFilePath(),
+			}
+		})
+
+		It("reports the problem", func() {
+			Expect(err).ToNot(BeNil())
+			Eventually(buffer).Should(Say(fmtErrorLog + `.*validate.* ` + filepath.Base(swapFilePath()) + ` \(SWAP\*\) matched by priority rules`))
+		})
+	})
+
+	When("it succeeds", func() {
+		It("succeeds", func() {
+			Expect(err).To(BeNil())
+			Eventually(buffer).Should(Say(fmtInfoLog + `.*validate.* config is valid, checked .*1.* groups`))
+		})
+	})
+})